@@ -0,0 +1,189 @@
+package agents
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/JoshPattman/gym"
+	"github.com/JoshPattman/gym/nn"
+)
+
+var _ gym.RolloutPolicy = &ActorCritic{}
+var _ gym.Policy = &ActorCritic{}
+
+// ActorCritic is a stochastic policy with a separate value network, as trained by Reinforce and PPO.
+// A categorical policy's actor outputs the logits of a categorical distribution over the env's categorical actions.
+// A continuous policy's actor outputs the mean of a Gaussian distribution, whose log standard deviation for each action is a separate parameter.
+//
+// SampleAction returns actions in the form the trainers learn from, which is not always a valid env action: categorical actions are returned as a single index,
+// and Gaussian actions are not clipped. Act always returns a valid env action, and acts deterministically, so it should be used for evaluation.
+type ActorCritic struct {
+	Actor  *nn.MLP
+	Critic *nn.MLP
+	// The log standard deviation of each action. Only used by continuous policies.
+	LogStd      []float64
+	Categorical bool
+	// Observations are normalised before they are given to the actor and critic. The trainers update its statistics after every update.
+	Normaliser *nn.Normaliser
+
+	env        gym.Env
+	rng        *rand.Rand
+	action     []float64
+	normalised []float64
+}
+
+// NewActorCritic creates a continuous policy for the env, with tanh hidden layers of the given sizes in both the actor and the critic.
+func NewActorCritic(env gym.Env, hidden []int, seed int64) *ActorCritic {
+	return newActorCritic(env, hidden, env.ActionLength(), false, seed)
+}
+
+// NewCategoricalActorCritic creates a categorical policy for the env, with tanh hidden layers of the given sizes in both the actor and the critic.
+func NewCategoricalActorCritic(env gym.Env, hidden []int, seed int64) *ActorCritic {
+	return newActorCritic(env, hidden, env.NumCategoricalActions(), true, seed)
+}
+
+func newActorCritic(env gym.Env, hidden []int, numOutputs int, categorical bool, seed int64) *ActorCritic {
+	rng := rand.New(rand.NewSource(seed))
+	actor := nn.NewMLP(append(append([]int{env.ObservationLength()}, hidden...), numOutputs), nn.Tanh, nn.Linear)
+	actor.Init(rng)
+	// Small initial outputs keep the first policy close to uniform.
+	scaleOutputLayer(actor, 0.01)
+	critic := nn.NewMLP(append(append([]int{env.ObservationLength()}, hidden...), 1), nn.Tanh, nn.Linear)
+	critic.Init(rng)
+	p := &ActorCritic{
+		Actor:       actor,
+		Critic:      critic,
+		Categorical: categorical,
+		Normaliser:  nn.NewNormaliser(env.ObservationLength()),
+		env:         env,
+		rng:         rng,
+		action:      make([]float64, numOutputs),
+		normalised:  make([]float64, env.ObservationLength()),
+	}
+	if !categorical {
+		p.LogStd = make([]float64, numOutputs)
+		for i := range p.LogStd {
+			p.LogStd[i] = -0.5
+		}
+	}
+	return p
+}
+
+// Multiplies the weights of the last layer of the network by scale.
+func scaleOutputLayer(net *nn.MLP, scale float64) {
+	in, out := net.Sizes[len(net.Sizes)-2], net.NumOutputs()
+	start := net.NumParams() - in*out - out
+	for i := start; i < start+in*out; i++ {
+		net.Params[i] *= scale
+	}
+}
+
+// Seed reseeds the random number generator used to sample actions.
+func (p *ActorCritic) Seed(seed int64) {
+	p.rng = rand.New(rand.NewSource(seed))
+}
+
+// Gets the observation as given to the networks. It is reused, so it is only valid until the next call.
+func (p *ActorCritic) input(observation []float64) []float64 {
+	if p.Normaliser == nil {
+		return observation
+	}
+	p.Normaliser.Normalise(observation, p.normalised)
+	return p.normalised
+}
+
+// Runs the actor on the observation.
+func (p *ActorCritic) actor(observation []float64) []float64 {
+	return p.Actor.Forward(p.input(observation))
+}
+
+// SampleAction implements gym.RolloutPolicy.
+func (p *ActorCritic) SampleAction(observation []float64) ([]float64, float64, float64) {
+	value := p.Value(observation)
+	out := p.actor(observation)
+	if p.Categorical {
+		a := nn.SampleCategorical(out, p.rng)
+		return []float64{float64(a)}, nn.CategoricalLogProb(out, a), value
+	}
+	action := make([]float64, len(out))
+	nn.SampleGaussian(out, p.LogStd, p.rng, action)
+	return action, nn.GaussianLogProb(out, p.LogStd, action), value
+}
+
+// Value implements gym.RolloutPolicy.
+func (p *ActorCritic) Value(observation []float64) float64 {
+	return p.Critic.Forward(p.input(observation))[0]
+}
+
+// Act implements gym.Policy. It picks the most likely action. The returned action is reused, so it is only valid until the next call to Act.
+func (p *ActorCritic) Act(observation []float64) []float64 {
+	out := p.actor(observation)
+	if p.Categorical {
+		return p.env.ConvertCategoricalAction(argmax(out))
+	}
+	for i, m := range out {
+		p.action[i] = math.Max(-1, math.Min(1, m))
+	}
+	return p.action
+}
+
+// Gets the log probability of the action, in the form returned by SampleAction, and the entropy of the distribution.
+// out is the output of the actor.
+func (p *ActorCritic) logProb(out, action []float64) (float64, float64) {
+	if p.Categorical {
+		return nn.CategoricalLogProb(out, int(action[0])), nn.CategoricalEntropy(out)
+	}
+	return nn.GaussianLogProb(out, p.LogStd, action), nn.GaussianEntropy(p.LogStd)
+}
+
+// Backpropagates scale*logProb(action) + entropyScale*entropy through the actor, adding to the gradients of its params and LogStd.
+// The actor must have just been run, and out is its output.
+func (p *ActorCritic) backward(out, action []float64, scale, entropyScale float64, actorGrads, logStdGrads []float64) {
+	outGrad := make([]float64, len(out))
+	if p.Categorical {
+		nn.CategoricalGrad(out, int(action[0]), scale, entropyScale, outGrad)
+	} else {
+		nn.GaussianGrad(out, p.LogStd, action, scale, entropyScale, outGrad, logStdGrads)
+	}
+	p.Actor.Backward(outGrad, actorGrads)
+}
+
+// Adds every observation to the statistics of the normaliser, if there is one.
+func (p *ActorCritic) updateNormaliser(observations [][]float64) {
+	if p.Normaliser == nil {
+		return
+	}
+	for _, obs := range observations {
+		p.Normaliser.Update(obs)
+	}
+}
+
+// trainingEnv wraps an env so that it accepts actions in the form returned by ActorCritic.SampleAction.
+type trainingEnv struct {
+	gym.Env
+	categorical bool
+	action      []float64
+}
+
+func newTrainingEnv(env gym.Env, categorical bool) *trainingEnv {
+	return &trainingEnv{Env: env, categorical: categorical}
+}
+
+// Step converts categorical indices to env actions, and clips continuous actions to between -1 and 1.
+func (e *trainingEnv) Step(action []float64) gym.StepData {
+	if e.categorical {
+		return e.Env.Step(e.Env.ConvertCategoricalAction(int(action[0])))
+	}
+	e.action = append(e.action[:0], action...)
+	for i, a := range e.action {
+		e.action[i] = math.Max(-1, math.Min(1, a))
+	}
+	return e.Env.Step(e.action)
+}
+
+func (e *trainingEnv) ActionLength() int {
+	if e.categorical {
+		return 1
+	}
+	return e.Env.ActionLength()
+}
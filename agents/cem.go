@@ -0,0 +1,150 @@
+package agents
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Optimiser is a population based, gradient free optimiser, which searches for the parameter vector with the highest fitness.
+// Every optimiser is deterministic given its seed, and has only exported fields, so it can be checkpointed with encoding/gob between generations.
+type Optimiser interface {
+	// Ask samples the population of parameter vectors to evaluate this generation. The slices are reused, so they are only valid until the next call to Ask.
+	Ask() [][]float64
+	// Tell gives the fitness of each member of the population from the last call to Ask, in the same order, and moves on to the next generation.
+	// Higher fitness is better.
+	Tell(fitness []float64)
+	// Params gets the centre of the search distribution, which is the current best estimate of the optimal parameters.
+	Params() []float64
+}
+
+// Creates the random number generator for a generation, so that each generation is reproducible from the seed alone.
+func generationRand(seed int64, generation int) *rand.Rand {
+	return rand.New(rand.NewSource(seed ^ int64(generation+1)*-7046029254386353131))
+}
+
+// Gets the indices of fitness sorted from best to worst.
+func rankDescending(fitness []float64) []int {
+	order := make([]int, len(fitness))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fitness[order[a]] > fitness[order[b]] })
+	return order
+}
+
+// CEMSettings contains all the settings for the cross-entropy method.
+type CEMSettings struct {
+	PopulationSize int
+	// The fraction of the population, with the highest fitness, that the distribution is refit to.
+	EliteFraction float64
+	// The initial standard deviation of every parameter.
+	InitialStd float64
+	// This variance is added to every parameter after each refit, to stop the distribution collapsing too early.
+	// It is multiplied by NoiseDecay every generation.
+	ExtraNoise float64
+	NoiseDecay float64
+}
+
+var DefaultCEMSettings = CEMSettings{
+	PopulationSize: 50,
+	EliteFraction:  0.2,
+	InitialStd:     0.5,
+	ExtraNoise:     0.01,
+	NoiseDecay:     0.95,
+}
+
+var _ Optimiser = &CEM{}
+
+// CEM is the cross-entropy method. It samples from an independent normal distribution over every parameter, and refits it to the best members of each population.
+type CEM struct {
+	Settings CEMSettings
+	Mean     []float64
+	Std      []float64
+	// The variance currently added after each refit.
+	Noise      float64
+	Seed       int64
+	Generation int
+
+	population [][]float64
+}
+
+// NewCEM creates a cross-entropy method optimiser, with its distribution centred on the initial parameters.
+func NewCEM(initial []float64, settings CEMSettings, seed int64) *CEM {
+	if settings.PopulationSize < 2 {
+		panic("population size must be at least 2")
+	}
+	std := make([]float64, len(initial))
+	for i := range std {
+		std[i] = settings.InitialStd
+	}
+	return &CEM{
+		Settings: settings,
+		Mean:     append([]float64{}, initial...),
+		Std:      std,
+		Noise:    settings.ExtraNoise,
+		Seed:     seed,
+	}
+}
+
+// Ask implements Optimiser.
+func (c *CEM) Ask() [][]float64 {
+	c.population = makePopulation(c.population, c.Settings.PopulationSize, len(c.Mean))
+	rng := generationRand(c.Seed, c.Generation)
+	for _, x := range c.population {
+		for i := range x {
+			x[i] = c.Mean[i] + c.Std[i]*rng.NormFloat64()
+		}
+	}
+	return c.population
+}
+
+// Tell implements Optimiser.
+func (c *CEM) Tell(fitness []float64) {
+	checkFitness(c.population, fitness)
+	numElite := max(1, int(math.Round(c.Settings.EliteFraction*float64(len(fitness)))))
+	elite := rankDescending(fitness)[:numElite]
+	for i := range c.Mean {
+		mean := 0.0
+		for _, e := range elite {
+			mean += c.population[e][i]
+		}
+		mean /= float64(numElite)
+		variance := 0.0
+		for _, e := range elite {
+			d := c.population[e][i] - mean
+			variance += d * d
+		}
+		variance /= float64(numElite)
+		c.Mean[i] = mean
+		c.Std[i] = math.Sqrt(variance + c.Noise)
+	}
+	c.Noise *= c.Settings.NoiseDecay
+	c.Generation++
+}
+
+// Params implements Optimiser.
+func (c *CEM) Params() []float64 {
+	return c.Mean
+}
+
+// Reuses the population if it is the right shape, and otherwise allocates a new one.
+func makePopulation(population [][]float64, size, numParams int) [][]float64 {
+	if len(population) == size && (size == 0 || len(population[0]) == numParams) {
+		return population
+	}
+	population = make([][]float64, size)
+	for i := range population {
+		population[i] = make([]float64, numParams)
+	}
+	return population
+}
+
+func checkFitness(population [][]float64, fitness []float64) {
+	if population == nil {
+		panic("must call Ask before Tell")
+	}
+	if len(fitness) != len(population) {
+		panic("must give one fitness for each member of the population")
+	}
+}
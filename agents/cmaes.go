@@ -0,0 +1,265 @@
+package agents
+
+import (
+	"math"
+)
+
+// CMAESSettings contains all the settings for CMA-ES. The learning rates are derived from the number of parameters, as recommended by Hansen (2016).
+type CMAESSettings struct {
+	// The number of parameter vectors in each population. If 0, 4+3ln(n) is used, where n is the number of parameters.
+	PopulationSize int
+	// The initial step size.
+	InitialSigma float64
+}
+
+var DefaultCMAESSettings = CMAESSettings{
+	PopulationSize: 0,
+	InitialSigma:   0.5,
+}
+
+var _ Optimiser = &CMAES{}
+
+// CMAES is the covariance matrix adaptation evolution strategy. It adapts a full covariance matrix, so it learns correlations between parameters,
+// but its cost grows with the square of the number of parameters, so it is best suited to small networks.
+type CMAES struct {
+	Settings CMAESSettings
+	Mean     []float64
+	Sigma    float64
+	// The covariance matrix, stored by row.
+	C []float64
+	// The evolution paths of the covariance and the step size.
+	PathC     []float64
+	PathSigma []float64
+	// The eigendecomposition of C = B diag(D^2) B^T. B is stored by row, and its columns are the eigenvectors.
+	B []float64
+	D []float64
+	// The generation at which B and D were last computed.
+	EigenGeneration int
+	Seed            int64
+	Generation      int
+
+	population [][]float64
+}
+
+// NewCMAES creates a CMA-ES optimiser, with its distribution centred on the initial parameters.
+func NewCMAES(initial []float64, settings CMAESSettings, seed int64) *CMAES {
+	n := len(initial)
+	if n == 0 {
+		panic("must have at least one parameter")
+	}
+	if settings.PopulationSize == 0 {
+		settings.PopulationSize = 4 + int(3*math.Log(float64(n)))
+	}
+	if settings.PopulationSize < 2 {
+		panic("population size must be at least 2")
+	}
+	c := &CMAES{
+		Settings:  settings,
+		Mean:      append([]float64{}, initial...),
+		Sigma:     settings.InitialSigma,
+		C:         make([]float64, n*n),
+		PathC:     make([]float64, n),
+		PathSigma: make([]float64, n),
+		B:         make([]float64, n*n),
+		D:         make([]float64, n),
+		Seed:      seed,
+	}
+	for i := 0; i < n; i++ {
+		c.C[i*n+i] = 1
+		c.B[i*n+i] = 1
+		c.D[i] = 1
+	}
+	return c
+}
+
+// The constants of the update, which only depend on the number of parameters and the population size.
+type cmaesConstants struct {
+	weights             []float64
+	muEff               float64
+	cc, cs, c1, cmu     float64
+	damps, expectedNorm float64
+}
+
+func (c *CMAES) constants() cmaesConstants {
+	n := float64(len(c.Mean))
+	mu := c.Settings.PopulationSize / 2
+	k := cmaesConstants{weights: make([]float64, mu)}
+	sum := 0.0
+	for i := range k.weights {
+		k.weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sum += k.weights[i]
+	}
+	sumSq := 0.0
+	for i := range k.weights {
+		k.weights[i] /= sum
+		sumSq += k.weights[i] * k.weights[i]
+	}
+	k.muEff = 1 / sumSq
+	k.cc = (4 + k.muEff/n) / (n + 4 + 2*k.muEff/n)
+	k.cs = (k.muEff + 2) / (n + k.muEff + 5)
+	k.c1 = 2 / ((n+1.3)*(n+1.3) + k.muEff)
+	k.cmu = math.Min(1-k.c1, 2*(k.muEff-2+1/k.muEff)/((n+2)*(n+2)+k.muEff))
+	k.damps = 1 + 2*math.Max(0, math.Sqrt((k.muEff-1)/(n+1))-1) + k.cs
+	k.expectedNorm = math.Sqrt(n) * (1 - 1/(4*n) + 1/(21*n*n))
+	return k
+}
+
+// Ask implements Optimiser.
+func (c *CMAES) Ask() [][]float64 {
+	n := len(c.Mean)
+	c.population = makePopulation(c.population, c.Settings.PopulationSize, n)
+	rng := generationRand(c.Seed, c.Generation)
+	z := make([]float64, n)
+	for _, x := range c.population {
+		for i := range z {
+			z[i] = c.D[i] * rng.NormFloat64()
+		}
+		for i := range x {
+			y := 0.0
+			for j := range z {
+				y += c.B[i*n+j] * z[j]
+			}
+			x[i] = c.Mean[i] + c.Sigma*y
+		}
+	}
+	return c.population
+}
+
+// Tell implements Optimiser.
+func (c *CMAES) Tell(fitness []float64) {
+	checkFitness(c.population, fitness)
+	n := len(c.Mean)
+	k := c.constants()
+	order := rankDescending(fitness)[:len(k.weights)]
+
+	// The steps from the old mean to each selected member, in units of sigma.
+	steps := make([][]float64, len(order))
+	meanStep := make([]float64, n)
+	for r, idx := range order {
+		steps[r] = make([]float64, n)
+		for i := range meanStep {
+			steps[r][i] = (c.population[idx][i] - c.Mean[i]) / c.Sigma
+			meanStep[i] += k.weights[r] * steps[r][i]
+		}
+	}
+	for i := range c.Mean {
+		c.Mean[i] += c.Sigma * meanStep[i]
+	}
+
+	// C^(-1/2) meanStep = B diag(1/D) B^T meanStep.
+	tmp := make([]float64, n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			tmp[j] += c.B[i*n+j] * meanStep[i]
+		}
+		tmp[j] /= c.D[j]
+	}
+	whitened := make([]float64, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			whitened[i] += c.B[i*n+j] * tmp[j]
+		}
+	}
+
+	sigmaScale := math.Sqrt(k.cs * (2 - k.cs) * k.muEff)
+	normSigma := 0.0
+	for i := range c.PathSigma {
+		c.PathSigma[i] = (1-k.cs)*c.PathSigma[i] + sigmaScale*whitened[i]
+		normSigma += c.PathSigma[i] * c.PathSigma[i]
+	}
+	normSigma = math.Sqrt(normSigma)
+
+	// The covariance path is stalled while the step size path is long, which stops C growing too fast when sigma is too small.
+	hsig := 0.0
+	if normSigma/math.Sqrt(1-math.Pow(1-k.cs, 2*float64(c.Generation+1)))/k.expectedNorm < 1.4+2/(float64(n)+1) {
+		hsig = 1
+	}
+	cScale := hsig * math.Sqrt(k.cc*(2-k.cc)*k.muEff)
+	for i := range c.PathC {
+		c.PathC[i] = (1-k.cc)*c.PathC[i] + cScale*meanStep[i]
+	}
+
+	decay := 1 - k.c1 - k.cmu + (1-hsig)*k.c1*k.cc*(2-k.cc)
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			v := decay*c.C[i*n+j] + k.c1*c.PathC[i]*c.PathC[j]
+			for r, s := range steps {
+				v += k.cmu * k.weights[r] * s[i] * s[j]
+			}
+			c.C[i*n+j] = v
+			c.C[j*n+i] = v
+		}
+	}
+
+	c.Sigma *= math.Exp(k.cs / k.damps * (normSigma/k.expectedNorm - 1))
+	c.Generation++
+
+	// The eigendecomposition is expensive, so it is only updated once C has changed enough.
+	if float64(c.Generation-c.EigenGeneration) > float64(c.Settings.PopulationSize)/(k.c1+k.cmu)/float64(n)/10 {
+		c.EigenGeneration = c.Generation
+		c.decompose()
+	}
+}
+
+// Params implements Optimiser.
+func (c *CMAES) Params() []float64 {
+	return c.Mean
+}
+
+// Computes B and D from C, using the cyclic Jacobi eigenvalue algorithm.
+func (c *CMAES) decompose() {
+	n := len(c.Mean)
+	a := append([]float64{}, c.C...)
+	v := c.B
+	for i := range v {
+		v[i] = 0
+	}
+	for i := 0; i < n; i++ {
+		v[i*n+i] = 1
+	}
+	for sweep := 0; sweep < 50; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += a[i*n+j] * a[i*n+j]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a[p*n+q]
+				if apq == 0 {
+					continue
+				}
+				theta := (a[q*n+q] - a[p*n+p]) / (2 * apq)
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				cos := 1 / math.Sqrt(t*t+1)
+				sin := t * cos
+				for r := 0; r < n; r++ {
+					arp, arq := a[r*n+p], a[r*n+q]
+					a[r*n+p] = cos*arp - sin*arq
+					a[r*n+q] = sin*arp + cos*arq
+				}
+				for r := 0; r < n; r++ {
+					apr, aqr := a[p*n+r], a[q*n+r]
+					a[p*n+r] = cos*apr - sin*aqr
+					a[q*n+r] = sin*apr + cos*aqr
+				}
+				for r := 0; r < n; r++ {
+					vrp, vrq := v[r*n+p], v[r*n+q]
+					v[r*n+p] = cos*vrp - sin*vrq
+					v[r*n+q] = sin*vrp + cos*vrq
+				}
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		// Roundoff can make tiny eigenvalues slightly negative.
+		c.D[i] = math.Sqrt(math.Max(a[i*n+i], 1e-20))
+	}
+}
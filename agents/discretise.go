@@ -0,0 +1,65 @@
+// Package agents provides simple reinforcement learning agents, written in pure Go, that learn from any gym.Env.
+package agents
+
+import (
+	"math"
+
+	"github.com/JoshPattman/gym"
+)
+
+// Discretiser splits each dimension of an observation into equal width bins, and maps every observation to a single state index.
+// Values outside of the range of a dimension are put in its first or last bin.
+type Discretiser struct {
+	Low  []float64
+	High []float64
+	Bins []int
+}
+
+// NewDiscretiser creates a discretiser with the given range and number of bins for each dimension.
+func NewDiscretiser(low, high []float64, bins []int) *Discretiser {
+	if len(low) != len(high) || len(low) != len(bins) {
+		panic("low, high and bins must have the same length")
+	}
+	return &Discretiser{Low: low, High: high, Bins: bins}
+}
+
+// NewUniformDiscretiser creates a discretiser that splits every dimension of the observation between -1 and 1 into the same number of bins.
+func NewUniformDiscretiser(observationLength, bins int) *Discretiser {
+	low := make([]float64, observationLength)
+	high := make([]float64, observationLength)
+	numBins := make([]int, observationLength)
+	for i := range low {
+		low[i], high[i], numBins[i] = -1, 1, bins
+	}
+	return NewDiscretiser(low, high, numBins)
+}
+
+// NumStates gets the number of distinct state indices.
+func (d *Discretiser) NumStates() int {
+	n := 1
+	for _, b := range d.Bins {
+		n *= b
+	}
+	return n
+}
+
+// State gets the state index of an observation.
+func (d *Discretiser) State(observation []float64) int {
+	if len(observation) != len(d.Bins) {
+		panic("observation has wrong length for discretiser")
+	}
+	state := 0
+	for i, v := range observation {
+		bin := int(math.Floor((v - d.Low[i]) / (d.High[i] - d.Low[i]) * float64(d.Bins[i])))
+		bin = max(0, min(d.Bins[i]-1, bin))
+		state = state*d.Bins[i] + bin
+	}
+	return state
+}
+
+// CartPoleRanges gets the low and high bounds of the part of the CartPole observation space that matters when balancing the pole.
+// The pole angle is scaled down heavily in the observation, so uniform bins over -1 to 1 would put every angle in the same bin.
+func CartPoleRanges(settings gym.CartPoleSettings) (low, high []float64) {
+	maxAngle := settings.FailAngle / 180
+	return []float64{-1, -1, -maxAngle, -0.5}, []float64{1, 1, maxAngle, 0.5}
+}
@@ -0,0 +1,112 @@
+package agents
+
+import (
+	"github.com/JoshPattman/gym/nn"
+)
+
+// ESSettings contains all the settings for evolution strategies.
+type ESSettings struct {
+	// The number of parameter vectors in each population. Must be even, as they are sampled in antithetic pairs.
+	PopulationSize int
+	// The standard deviation of the noise added to the parameters.
+	Sigma float64
+	// The Adam settings used to follow the estimated gradient.
+	Adam nn.AdamSettings
+	// Each step, the parameters are also pulled towards zero by this times their value.
+	WeightDecay float64
+}
+
+var DefaultESSettings = ESSettings{
+	PopulationSize: 50,
+	Sigma:          0.1,
+	Adam: nn.AdamSettings{
+		LearningRate: 0.03,
+		Beta1:        0.9,
+		Beta2:        0.999,
+		Epsilon:      1e-8,
+	},
+	WeightDecay: 0.005,
+}
+
+var _ Optimiser = &ES{}
+
+// ES is the evolution strategy described by Salimans et al. (2017).
+// It estimates the gradient of the fitness from antithetic pairs of perturbations, using centred ranks of the fitness so that it is not affected by its scale.
+type ES struct {
+	Settings   ESSettings
+	Mean       []float64
+	Adam       *nn.Adam
+	Seed       int64
+	Generation int
+
+	noise      [][]float64
+	population [][]float64
+}
+
+// NewES creates an evolution strategy optimiser, starting at the initial parameters.
+func NewES(initial []float64, settings ESSettings, seed int64) *ES {
+	if settings.PopulationSize < 2 || settings.PopulationSize%2 != 0 {
+		panic("population size must be even and at least 2")
+	}
+	return &ES{
+		Settings: settings,
+		Mean:     append([]float64{}, initial...),
+		Adam:     nn.NewAdam(len(initial), settings.Adam),
+		Seed:     seed,
+	}
+}
+
+// Ask implements Optimiser. Members 2i and 2i+1 of the population are the mean plus and minus the same noise.
+func (e *ES) Ask() [][]float64 {
+	n := len(e.Mean)
+	e.population = makePopulation(e.population, e.Settings.PopulationSize, n)
+	e.noise = makePopulation(e.noise, e.Settings.PopulationSize/2, n)
+	rng := generationRand(e.Seed, e.Generation)
+	for p, eps := range e.noise {
+		plus, minus := e.population[2*p], e.population[2*p+1]
+		for i := range eps {
+			eps[i] = rng.NormFloat64()
+			plus[i] = e.Mean[i] + e.Settings.Sigma*eps[i]
+			minus[i] = e.Mean[i] - e.Settings.Sigma*eps[i]
+		}
+	}
+	return e.population
+}
+
+// Tell implements Optimiser.
+func (e *ES) Tell(fitness []float64) {
+	checkFitness(e.population, fitness)
+	ranks := centredRanks(fitness)
+	grads := make([]float64, len(e.Mean))
+	scale := 1 / (float64(len(fitness)) * e.Settings.Sigma)
+	for p, eps := range e.noise {
+		w := (ranks[2*p] - ranks[2*p+1]) * scale
+		for i := range grads {
+			grads[i] += w * eps[i]
+		}
+	}
+	// Adam minimises, so the fitness gradient is negated.
+	for i := range grads {
+		grads[i] = -grads[i] + e.Settings.WeightDecay*e.Mean[i]
+	}
+	e.Adam.Step(e.Mean, grads)
+	e.Generation++
+}
+
+// Params implements Optimiser.
+func (e *ES) Params() []float64 {
+	return e.Mean
+}
+
+// Gets the rank of each fitness, scaled to be between -0.5 for the worst and 0.5 for the best.
+func centredRanks(fitness []float64) []float64 {
+	ranks := make([]float64, len(fitness))
+	order := rankDescending(fitness)
+	if len(fitness) == 1 {
+		return ranks
+	}
+	for r, i := range order {
+		ranks[i] = 0.5 - float64(r)/float64(len(fitness)-1)
+	}
+	return ranks
+}
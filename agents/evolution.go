@@ -0,0 +1,208 @@
+package agents
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/JoshPattman/gym"
+	"github.com/JoshPattman/gym/nn"
+)
+
+func init() {
+	gob.Register(&CEM{})
+	gob.Register(&CMAES{})
+	gob.Register(&ES{})
+}
+
+// EvolutionSettings contains all the settings for training a policy with an Optimiser.
+type EvolutionSettings struct {
+	// The number of episodes each candidate is evaluated for. Its fitness is the mean total reward.
+	EpisodesPerCandidate int
+	// Episodes are truncated after this many steps. 0 means episodes only end when the env terminates.
+	MaxEpisodeSteps int
+	// Seeds the envs. Every candidate in a generation is evaluated on the same seeds, which are different for each generation.
+	Seed int64
+}
+
+var DefaultEvolutionSettings = EvolutionSettings{
+	EpisodesPerCandidate: 1,
+	MaxEpisodeSteps:      1000,
+	Seed:                 0,
+}
+
+// GenerationResult summarises the fitness of one generation.
+type GenerationResult struct {
+	Generation  int
+	MeanFitness float64
+	MinFitness  float64
+	MaxFitness  float64
+}
+
+// Evolution trains the parameters of an MLPPolicy with a gradient free Optimiser, evaluating candidates in parallel with one goroutine per env.
+// If the envs are Seedable, training is deterministic, no matter how many envs there are.
+type Evolution struct {
+	Optimiser Optimiser
+	// The policy, which has the optimiser's current best parameters after every generation.
+	Policy     *MLPPolicy
+	Settings   EvolutionSettings
+	Generation int
+
+	envs    []gym.Env
+	workers []*MLPPolicy
+}
+
+// NewEvolution creates a trainer for the policy. The envs must all be separate copies of the same env, as they are stepped at the same time.
+// The optimiser should have been created with the policy's parameters, for example NewES(policy.Net.Params, DefaultESSettings, seed).
+func NewEvolution(optimiser Optimiser, policy *MLPPolicy, settings EvolutionSettings, envs ...gym.Env) *Evolution {
+	if len(envs) == 0 {
+		panic("evolution needs at least one env")
+	}
+	if len(optimiser.Params()) != policy.Net.NumParams() {
+		panic("optimiser has wrong number of params for policy")
+	}
+	if settings.EpisodesPerCandidate < 1 {
+		panic("each candidate must be evaluated for at least one episode")
+	}
+	workers := make([]*MLPPolicy, len(envs))
+	for i, env := range envs {
+		workers[i] = policy.Clone(env)
+	}
+	policy.Net.SetParams(optimiser.Params())
+	return &Evolution{
+		Optimiser: optimiser,
+		Policy:    policy,
+		Settings:  settings,
+		envs:      envs,
+		workers:   workers,
+	}
+}
+
+// Step evaluates one generation of candidates, and updates the optimiser with their fitness.
+func (e *Evolution) Step() GenerationResult {
+	population := e.Optimiser.Ask()
+	fitness := make([]float64, len(population))
+	candidates := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := range e.envs {
+		wg.Add(1)
+		go func(env gym.Env, policy *MLPPolicy) {
+			defer wg.Done()
+			for i := range candidates {
+				policy.Net.SetParams(population[i])
+				fitness[i] = e.evaluate(env, policy)
+			}
+		}(e.envs[w], e.workers[w])
+	}
+	for i := range population {
+		candidates <- i
+	}
+	close(candidates)
+	wg.Wait()
+
+	e.Optimiser.Tell(fitness)
+	e.Policy.Net.SetParams(e.Optimiser.Params())
+
+	result := GenerationResult{Generation: e.Generation, MinFitness: math.Inf(1), MaxFitness: math.Inf(-1)}
+	for _, f := range fitness {
+		result.MeanFitness += f
+		result.MinFitness = math.Min(result.MinFitness, f)
+		result.MaxFitness = math.Max(result.MaxFitness, f)
+	}
+	result.MeanFitness /= float64(len(fitness))
+	e.Generation++
+	return result
+}
+
+// Gets the mean total reward of the policy over EpisodesPerCandidate episodes.
+func (e *Evolution) evaluate(env gym.Env, policy *MLPPolicy) float64 {
+	seedable, canSeed := env.(gym.Seedable)
+	total := 0.0
+	for ep := 0; ep < e.Settings.EpisodesPerCandidate; ep++ {
+		if canSeed {
+			seedable.Seed(e.Settings.Seed + int64(e.Generation*e.Settings.EpisodesPerCandidate+ep))
+		}
+		obs := env.Reset().Observation
+		for step := 0; e.Settings.MaxEpisodeSteps <= 0 || step < e.Settings.MaxEpisodeSteps; step++ {
+			stepData := env.Step(policy.Act(obs))
+			total += stepData.Reward
+			obs = stepData.Observation
+			if stepData.Terminated {
+				break
+			}
+		}
+	}
+	return total / float64(e.Settings.EpisodesPerCandidate)
+}
+
+// The version of the saved checkpoint format. This should be increased whenever savedEvolution changes.
+const savedEvolutionVersion = 1
+
+// savedEvolution is the on-disk form of an evolution trainer. It is encoded with encoding/gob.
+type savedEvolution struct {
+	Version     int
+	Settings    EvolutionSettings
+	Generation  int
+	Optimiser   Optimiser
+	Net         *nn.MLP
+	Categorical bool
+}
+
+// Save writes a checkpoint of the trainer to w, which can be loaded with LoadEvolution to carry on training exactly where it left off.
+func (e *Evolution) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(&savedEvolution{
+		Version:     savedEvolutionVersion,
+		Settings:    e.Settings,
+		Generation:  e.Generation,
+		Optimiser:   e.Optimiser,
+		Net:         e.Policy.Net,
+		Categorical: e.Policy.Categorical,
+	})
+}
+
+// SaveFile writes a checkpoint of the trainer to a file, overwriting it if it exists.
+func (e *Evolution) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := e.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadEvolution reads a checkpoint written by Save, and creates a trainer that uses the given envs.
+func LoadEvolution(r io.Reader, envs ...gym.Env) (*Evolution, error) {
+	s := &savedEvolution{}
+	if err := gob.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Version != savedEvolutionVersion {
+		return nil, fmt.Errorf("unsupported evolution checkpoint version %d", s.Version)
+	}
+	if len(envs) == 0 {
+		return nil, fmt.Errorf("evolution needs at least one env")
+	}
+	if s.Net == nil || s.Optimiser == nil || len(s.Optimiser.Params()) != s.Net.NumParams() {
+		return nil, fmt.Errorf("corrupt evolution checkpoint: optimiser does not match network")
+	}
+	policy := &MLPPolicy{Net: s.Net.Clone(), Categorical: s.Categorical, env: envs[0], action: make([]float64, s.Net.NumOutputs())}
+	e := NewEvolution(s.Optimiser, policy, s.Settings, envs...)
+	e.Generation = s.Generation
+	return e, nil
+}
+
+// LoadEvolutionFile reads a checkpoint file written by SaveFile.
+func LoadEvolutionFile(path string, envs ...gym.Env) (*Evolution, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadEvolution(f, envs...)
+}
@@ -0,0 +1,63 @@
+package agents
+
+import (
+	"math/rand"
+
+	"github.com/JoshPattman/gym"
+	"github.com/JoshPattman/gym/nn"
+)
+
+var _ gym.Policy = &MLPPolicy{}
+var _ gym.CategoricalPolicy = &MLPPolicy{}
+
+// MLPPolicy is a deterministic policy whose actions are the output of a small neural network.
+// A continuous policy outputs one tanh value per action. A categorical policy outputs one value per categorical action, and picks the highest.
+type MLPPolicy struct {
+	Net         *nn.MLP
+	Categorical bool
+	env         gym.Env
+	action      []float64
+}
+
+// NewMLPPolicy creates a continuous policy for the env, with tanh hidden layers of the given sizes, and randomly initialised weights.
+func NewMLPPolicy(env gym.Env, hidden []int, seed int64) *MLPPolicy {
+	sizes := append(append([]int{env.ObservationLength()}, hidden...), env.ActionLength())
+	net := nn.NewMLP(sizes, nn.Tanh, nn.Tanh)
+	net.Init(rand.New(rand.NewSource(seed)))
+	return &MLPPolicy{Net: net, env: env, action: make([]float64, env.ActionLength())}
+}
+
+// NewCategoricalMLPPolicy creates a categorical policy for the env, with tanh hidden layers of the given sizes, and randomly initialised weights.
+func NewCategoricalMLPPolicy(env gym.Env, hidden []int, seed int64) *MLPPolicy {
+	sizes := append(append([]int{env.ObservationLength()}, hidden...), env.NumCategoricalActions())
+	net := nn.NewMLP(sizes, nn.Tanh, nn.Linear)
+	net.Init(rand.New(rand.NewSource(seed)))
+	return &MLPPolicy{Net: net, Categorical: true, env: env}
+}
+
+// Act implements gym.Policy. The returned action is reused, so it is only valid until the next call to Act.
+func (p *MLPPolicy) Act(observation []float64) []float64 {
+	if p.Categorical {
+		return p.env.ConvertCategoricalAction(p.ActCategorical(observation))
+	}
+	copy(p.action, p.Net.Forward(observation))
+	return p.action
+}
+
+// ActCategorical implements gym.CategoricalPolicy. It panics if the policy is not categorical.
+func (p *MLPPolicy) ActCategorical(observation []float64) int {
+	if !p.Categorical {
+		panic("policy is not categorical")
+	}
+	return argmax(p.Net.Forward(observation))
+}
+
+// Clone creates a copy of the policy which acts in a different env, so that copies can be run in parallel.
+func (p *MLPPolicy) Clone(env gym.Env) *MLPPolicy {
+	return &MLPPolicy{
+		Net:         p.Net.Clone(),
+		Categorical: p.Categorical,
+		env:         env,
+		action:      make([]float64, len(p.action)),
+	}
+}
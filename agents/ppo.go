@@ -0,0 +1,161 @@
+package agents
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/JoshPattman/gym"
+	"github.com/JoshPattman/gym/nn"
+)
+
+// PPOSettings contains all the settings for PPO.
+type PPOSettings struct {
+	// The number of steps collected from each env for each update.
+	StepsPerEnv int
+	// The number of passes over the collected steps in each update.
+	Epochs int
+	// The number of steps in each gradient step.
+	MinibatchSize int
+	// How far the probability ratio between the new and old policy can move before its gradient is cut off.
+	ClipRange float64
+	// The discount factor of future rewards, and the GAE lambda.
+	Discount float64
+	Lambda   float64
+	// Episodes are truncated after this many steps, and the return is bootstrapped from the critic. 0 means episodes only end when the env terminates.
+	MaxEpisodeSteps    int
+	ActorLearningRate  float64
+	CriticLearningRate float64
+	// The weight of the entropy bonus, which encourages exploration.
+	EntropyCoefficient float64
+	// The gradients of the actor and the critic are each clipped to this L2 norm. 0 means no clipping.
+	MaxGradNorm float64
+}
+
+var DefaultPPOSettings = PPOSettings{
+	StepsPerEnv:        256,
+	Epochs:             4,
+	MinibatchSize:      64,
+	ClipRange:          0.2,
+	Discount:           0.99,
+	Lambda:             0.95,
+	MaxEpisodeSteps:    1000,
+	ActorLearningRate:  0.001,
+	CriticLearningRate: 0.001,
+	EntropyCoefficient: 0,
+	MaxGradNorm:        0.5,
+}
+
+// PPO trains an ActorCritic with proximal policy optimisation (Schulman et al. 2017), collecting experience from several envs in lockstep.
+type PPO struct {
+	Policy   *ActorCritic
+	Settings PPOSettings
+	// The rollout used to collect experience. Its envs accept actions in the form returned by ActorCritic.SampleAction.
+	Rollout *gym.Rollout
+
+	actorAdam  *nn.Adam
+	criticAdam *nn.Adam
+	logStdAdam *nn.Adam
+	rng        *rand.Rand
+}
+
+// NewPPO creates a PPO trainer for the policy. The envs must all be separate copies of the same env. The seed is used to shuffle minibatches.
+func NewPPO(policy *ActorCritic, settings PPOSettings, seed int64, envs ...gym.Env) *PPO {
+	if settings.MinibatchSize < 1 || settings.Epochs < 1 {
+		panic("minibatch size and epochs must be at least 1")
+	}
+	if settings.StepsPerEnv < 1 || len(envs) == 0 {
+		panic("steps per env must be at least 1, and there must be at least one env")
+	}
+	wrapped := make([]gym.Env, len(envs))
+	for i, env := range envs {
+		wrapped[i] = newTrainingEnv(env, policy.Categorical)
+	}
+	rollout := gym.NewRollout(settings.StepsPerEnv, wrapped...)
+	rollout.MaxEpisodeSteps = settings.MaxEpisodeSteps
+	p := &PPO{
+		Policy:   policy,
+		Settings: settings,
+		Rollout:  rollout,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+	p.actorAdam, p.criticAdam, p.logStdAdam = newActorCriticAdams(policy, settings.ActorLearningRate, settings.CriticLearningRate)
+	return p
+}
+
+// Update collects StepsPerEnv steps from every env, and then takes several clipped gradient steps on the actor and the critic.
+func (p *PPO) Update() UpdateResult {
+	policy := p.Policy
+	r := p.Rollout
+	r.Collect(policy)
+	r.ComputeGAE(p.Settings.Discount, p.Settings.Lambda)
+
+	n := r.Len()
+	result := UpdateResult{Steps: n}
+	result.setEpisodes(r.EpisodeReturns, r.EpisodeLengths)
+
+	actorGrads := make([]float64, policy.Actor.NumParams())
+	criticGrads := make([]float64, policy.Critic.NumParams())
+	logStdGrads := make([]float64, len(policy.LogStd))
+	advantages := make([]float64, p.Settings.MinibatchSize)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	samples := 0
+	for epoch := 0; epoch < p.Settings.Epochs; epoch++ {
+		p.rng.Shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+		for start := 0; start < n; start += p.Settings.MinibatchSize {
+			batch := order[start:min(n, start+p.Settings.MinibatchSize)]
+			size := float64(len(batch))
+			// Advantages are normalised within each minibatch.
+			advantages = advantages[:len(batch)]
+			for k, idx := range batch {
+				advantages[k] = r.Advantages[idx]
+			}
+			if len(batch) > 1 {
+				normalise(advantages)
+			}
+
+			clear(actorGrads)
+			clear(criticGrads)
+			clear(logStdGrads)
+			for k, idx := range batch {
+				obs, action, advantage := r.Observations[idx], r.Actions[idx], advantages[k]
+				out := policy.actor(obs)
+				logProb, entropy := policy.logProb(out, action)
+				ratio := math.Exp(logProb - r.LogProbs[idx])
+				clipped := math.Max(1-p.Settings.ClipRange, math.Min(1+p.Settings.ClipRange, ratio))
+				result.PolicyLoss -= math.Min(ratio*advantage, clipped*advantage)
+				result.Entropy += entropy
+				// The gradient of the clipped objective is 0 when the clipped term is the smaller one, and ratio*advantage times the gradient of logProb otherwise.
+				scale := 0.0
+				if ratio*advantage <= clipped*advantage {
+					scale = ratio * advantage
+				}
+				// Adam minimises, so the objective is negated.
+				policy.backward(out, action, -scale/size, -p.Settings.EntropyCoefficient/size, actorGrads, logStdGrads)
+
+				value := policy.Critic.Forward(policy.input(obs))[0]
+				diff := value - r.Returns[idx]
+				result.ValueLoss += 0.5 * diff * diff
+				policy.Critic.Backward([]float64{diff / size}, criticGrads)
+				samples++
+			}
+			if p.Settings.MaxGradNorm > 0 {
+				nn.ClipGradNorm(actorGrads, p.Settings.MaxGradNorm)
+				nn.ClipGradNorm(criticGrads, p.Settings.MaxGradNorm)
+			}
+			p.actorAdam.Step(policy.Actor.Params, actorGrads)
+			p.criticAdam.Step(policy.Critic.Params, criticGrads)
+			if p.logStdAdam != nil {
+				p.logStdAdam.Step(policy.LogStd, logStdGrads)
+			}
+		}
+	}
+	// The normaliser is only updated once the update is finished, so that the log probabilities of the collected actions stay correct while it is optimised.
+	policy.updateNormaliser(r.Observations)
+	result.PolicyLoss /= float64(samples)
+	result.ValueLoss /= float64(samples)
+	result.Entropy /= float64(samples)
+	return result
+}
@@ -0,0 +1,91 @@
+package agents
+
+import (
+	"math"
+	"testing"
+
+	"github.com/JoshPattman/gym"
+)
+
+// The mean evaluation episode length, out of DefaultEvaluateSettings.MaxEpisodeSteps, at which CartPole counts as balanced.
+const cartPoleBalanceSteps = 500
+
+// TestPolicyGradients trains a categorical ActorCritic on CartPole with each policy gradient trainer, and checks that its greedy policy balances the pole within a budget of updates.
+func TestPolicyGradients(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow to train")
+	}
+	// Every env gets its own seed, so that training is repeatable.
+	seed := int64(0)
+	newEnv := func() gym.Env {
+		seed++
+		env := gym.NewCartPoleEnv(gym.DefaultCartPoleSettings)
+		env.Seed(seed)
+		return env
+	}
+	cases := []struct {
+		name string
+		// newUpdate creates the trainer for the policy, and returns a function that does one update.
+		newUpdate func(policy *ActorCritic) func()
+		updates   int
+	}{
+		{"PPO", func(policy *ActorCritic) func() {
+			ppo := NewPPO(policy, DefaultPPOSettings, 1, newEnv(), newEnv(), newEnv(), newEnv())
+			return func() { ppo.Update() }
+		}, 200},
+		{"Reinforce", func(policy *ActorCritic) func() {
+			reinforce := NewReinforce(policy, DefaultReinforceSettings)
+			env := newEnv()
+			return func() { reinforce.Update(env) }
+		}, 300},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			policy := NewCategoricalActorCritic(newEnv(), []int{32, 32}, 1)
+			update := c.newUpdate(policy)
+			evalEnv := newEnv()
+			best := 0.0
+			for u := 1; u <= c.updates && best < cartPoleBalanceSteps; u++ {
+				update()
+				if u%10 == 0 {
+					result, err := gym.Evaluate(evalEnv, policy, 5, 1)
+					if err != nil {
+						t.Fatal(err)
+					}
+					best = math.Max(best, result.MeanLength)
+				}
+			}
+			if best < cartPoleBalanceSteps {
+				t.Errorf("best mean episode length %.1f is below %d", best, cartPoleBalanceSteps)
+			}
+		})
+	}
+}
+
+// TestNewPPOPanics checks that NewPPO rejects settings and envs that it could never collect a batch from.
+func TestNewPPOPanics(t *testing.T) {
+	env := gym.NewCartPoleEnv(gym.DefaultCartPoleSettings)
+	cases := []struct {
+		name   string
+		modify func(s *PPOSettings)
+		envs   []gym.Env
+	}{
+		{"ZeroStepsPerEnv", func(s *PPOSettings) { s.StepsPerEnv = 0 }, []gym.Env{env}},
+		{"ZeroMinibatchSize", func(s *PPOSettings) { s.MinibatchSize = 0 }, []gym.Env{env}},
+		{"ZeroEpochs", func(s *PPOSettings) { s.Epochs = 0 }, []gym.Env{env}},
+		{"NoEnvs", func(s *PPOSettings) {}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settings := DefaultPPOSettings
+			c.modify(&settings)
+			policy := NewCategoricalActorCritic(env, []int{4}, 1)
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			NewPPO(policy, settings, 1, c.envs...)
+		})
+	}
+}
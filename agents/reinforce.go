@@ -0,0 +1,188 @@
+package agents
+
+import (
+	"math"
+
+	"github.com/JoshPattman/gym"
+	"github.com/JoshPattman/gym/nn"
+)
+
+// UpdateResult summarises one update of a policy gradient trainer.
+type UpdateResult struct {
+	// The number of env steps collected for the update.
+	Steps int
+	// The number of episodes that finished while collecting, and their mean total reward and length. Both means are 0 if no episodes finished.
+	Episodes   int
+	MeanReturn float64
+	MeanLength float64
+	// The mean losses and entropy over every sample used in the update.
+	PolicyLoss float64
+	ValueLoss  float64
+	Entropy    float64
+}
+
+// Fills in the episode statistics.
+func (r *UpdateResult) setEpisodes(returns []float64, lengths []int) {
+	r.Episodes = len(returns)
+	if r.Episodes == 0 {
+		return
+	}
+	for i := range returns {
+		r.MeanReturn += returns[i]
+		r.MeanLength += float64(lengths[i])
+	}
+	r.MeanReturn /= float64(r.Episodes)
+	r.MeanLength /= float64(r.Episodes)
+}
+
+// ReinforceSettings contains all the settings for Reinforce.
+type ReinforceSettings struct {
+	// The number of episodes collected for each update.
+	EpisodesPerUpdate int
+	// Episodes are truncated after this many steps, and the return is bootstrapped from the critic. 0 means episodes only end when the env terminates.
+	MaxEpisodeSteps int
+	// The discount factor of future rewards.
+	Discount           float64
+	ActorLearningRate  float64
+	CriticLearningRate float64
+	// The weight of the entropy bonus, which encourages exploration.
+	EntropyCoefficient float64
+	// If true, the advantages of each update are normalised to have a mean of 0 and a standard deviation of 1.
+	NormaliseAdvantages bool
+}
+
+var DefaultReinforceSettings = ReinforceSettings{
+	EpisodesPerUpdate:   8,
+	MaxEpisodeSteps:     1000,
+	Discount:            0.99,
+	ActorLearningRate:   0.003,
+	CriticLearningRate:  0.01,
+	EntropyCoefficient:  0,
+	NormaliseAdvantages: true,
+}
+
+// Reinforce trains an ActorCritic with the REINFORCE policy gradient (Williams 1992), using the critic as a baseline.
+type Reinforce struct {
+	Policy   *ActorCritic
+	Settings ReinforceSettings
+
+	actorAdam  *nn.Adam
+	criticAdam *nn.Adam
+	logStdAdam *nn.Adam
+}
+
+// NewReinforce creates a REINFORCE trainer for the policy.
+func NewReinforce(policy *ActorCritic, settings ReinforceSettings) *Reinforce {
+	if settings.EpisodesPerUpdate < 1 {
+		panic("must collect at least one episode per update")
+	}
+	r := &Reinforce{Policy: policy, Settings: settings}
+	r.actorAdam, r.criticAdam, r.logStdAdam = newActorCriticAdams(policy, settings.ActorLearningRate, settings.CriticLearningRate)
+	return r
+}
+
+// Update collects EpisodesPerUpdate episodes from the env, and takes one gradient step on the actor and the critic.
+func (r *Reinforce) Update(env gym.Env) UpdateResult {
+	p := r.Policy
+	wrapped := newTrainingEnv(env, p.Categorical)
+	var observations, actions [][]float64
+	var returns []float64
+	var episodeReturns []float64
+	var episodeLengths []int
+	for ep := 0; ep < r.Settings.EpisodesPerUpdate; ep++ {
+		obs := wrapped.Reset().Observation
+		var rewards, nextValues []float64
+		var ends []bool
+		for step := 0; ; step++ {
+			action, _, _ := p.SampleAction(obs)
+			stepData := wrapped.Step(action)
+			observations = append(observations, obs)
+			actions = append(actions, action)
+			rewards = append(rewards, stepData.Reward)
+			obs = stepData.Observation
+			truncated := !stepData.Terminated && r.Settings.MaxEpisodeSteps > 0 && step+1 >= r.Settings.MaxEpisodeSteps
+			ends = append(ends, stepData.Terminated || truncated)
+			// The next value is only used where the episode ends, and is 0 if the env terminated.
+			nextValue := 0.0
+			if truncated {
+				nextValue = p.Value(obs)
+			}
+			nextValues = append(nextValues, nextValue)
+			if stepData.Terminated || truncated {
+				break
+			}
+		}
+		returns = append(returns, gym.DiscountedReturns(rewards, nextValues, ends, r.Settings.Discount)...)
+		total := 0.0
+		for _, rw := range rewards {
+			total += rw
+		}
+		episodeReturns = append(episodeReturns, total)
+		episodeLengths = append(episodeLengths, len(rewards))
+	}
+
+	n := len(observations)
+	advantages := make([]float64, n)
+	for i, obs := range observations {
+		advantages[i] = returns[i] - p.Value(obs)
+	}
+	if r.Settings.NormaliseAdvantages {
+		normalise(advantages)
+	}
+
+	result := UpdateResult{Steps: n}
+	result.setEpisodes(episodeReturns, episodeLengths)
+	actorGrads := make([]float64, p.Actor.NumParams())
+	criticGrads := make([]float64, p.Critic.NumParams())
+	logStdGrads := make([]float64, len(p.LogStd))
+	for i, obs := range observations {
+		out := p.actor(obs)
+		logProb, entropy := p.logProb(out, actions[i])
+		result.PolicyLoss -= advantages[i] * logProb / float64(n)
+		result.Entropy += entropy / float64(n)
+		// Adam minimises, so the objective is negated.
+		p.backward(out, actions[i], -advantages[i]/float64(n), -r.Settings.EntropyCoefficient/float64(n), actorGrads, logStdGrads)
+
+		value := p.Critic.Forward(p.input(obs))[0]
+		result.ValueLoss += 0.5 * (value - returns[i]) * (value - returns[i]) / float64(n)
+		p.Critic.Backward([]float64{(value - returns[i]) / float64(n)}, criticGrads)
+	}
+	r.actorAdam.Step(p.Actor.Params, actorGrads)
+	r.criticAdam.Step(p.Critic.Params, criticGrads)
+	if r.logStdAdam != nil {
+		r.logStdAdam.Step(p.LogStd, logStdGrads)
+	}
+	p.updateNormaliser(observations)
+	return result
+}
+
+// Creates the optimisers for the actor, the critic and, for continuous policies, the log standard deviations.
+func newActorCriticAdams(p *ActorCritic, actorLearningRate, criticLearningRate float64) (actor, critic, logStd *nn.Adam) {
+	actorSettings := nn.DefaultAdamSettings
+	actorSettings.LearningRate = actorLearningRate
+	criticSettings := nn.DefaultAdamSettings
+	criticSettings.LearningRate = criticLearningRate
+	actor = nn.NewAdam(p.Actor.NumParams(), actorSettings)
+	critic = nn.NewAdam(p.Critic.NumParams(), criticSettings)
+	if !p.Categorical {
+		logStd = nn.NewAdam(len(p.LogStd), actorSettings)
+	}
+	return actor, critic, logStd
+}
+
+// Shifts and scales values to have a mean of 0 and a standard deviation of 1.
+func normalise(values []float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	std := math.Sqrt(variance/float64(len(values))) + 1e-8
+	for i := range values {
+		values[i] = (values[i] - mean) / std
+	}
+}
@@ -0,0 +1,183 @@
+package agents
+
+import (
+	"math/rand"
+
+	"github.com/JoshPattman/gym"
+)
+
+// TabularSettings contains all the settings for the tabular and linear agents.
+type TabularSettings struct {
+	// The step size of each update.
+	LearningRate float64
+	// The discount factor of future rewards.
+	Discount float64
+	// The chance of taking a random action while training.
+	Epsilon float64
+	// Epsilon is multiplied by this after every training episode, but never goes below MinEpsilon.
+	EpsilonDecay float64
+	MinEpsilon   float64
+	// The initial value of every action. Optimistic (high) values encourage exploration.
+	InitialValue float64
+}
+
+var DefaultTabularSettings = TabularSettings{
+	LearningRate: 0.1,
+	Discount:     0.99,
+	Epsilon:      1,
+	EpsilonDecay: 0.995,
+	MinEpsilon:   0.01,
+	InitialValue: 0,
+}
+
+// Learner is an agent that learns online, one episode at a time, and acts greedily on what it has learned.
+type Learner interface {
+	gym.CategoricalPolicy
+	// TrainEpisode runs one episode of at most maxSteps steps (or no limit if maxSteps is 0), learning from every step, and returns its total reward.
+	TrainEpisode(env gym.Env, maxSteps int) float64
+}
+
+// tabularAgent holds the Q table and action selection shared by QLearning and SARSA.
+type tabularAgent struct {
+	// The value of each action in each state. The value of action a in state s is Q[s*NumActions+a].
+	Q           []float64
+	NumActions  int
+	Discretiser *Discretiser
+	Settings    TabularSettings
+
+	rng *rand.Rand
+}
+
+func newTabularAgent(env gym.Env, discretiser *Discretiser, settings TabularSettings, seed int64) tabularAgent {
+	numActions := env.NumCategoricalActions()
+	q := make([]float64, discretiser.NumStates()*numActions)
+	for i := range q {
+		q[i] = settings.InitialValue
+	}
+	return tabularAgent{
+		Q:           q,
+		NumActions:  numActions,
+		Discretiser: discretiser,
+		Settings:    settings,
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Values gets the value of every action in the state of the observation. This is a view into Q, not a copy.
+func (a *tabularAgent) Values(observation []float64) []float64 {
+	s := a.Discretiser.State(observation)
+	return a.Q[s*a.NumActions : (s+1)*a.NumActions]
+}
+
+// ActCategorical picks the action with the highest value. It implements gym.CategoricalPolicy.
+func (a *tabularAgent) ActCategorical(observation []float64) int {
+	return argmax(a.Values(observation))
+}
+
+// Explore picks a random action with probability Epsilon, and otherwise the action with the highest value.
+func (a *tabularAgent) Explore(observation []float64) int {
+	if a.rng.Float64() < a.Settings.Epsilon {
+		return a.rng.Intn(a.NumActions)
+	}
+	return a.ActCategorical(observation)
+}
+
+// Decays Epsilon at the end of a training episode.
+func (a *tabularAgent) endEpisode() {
+	a.Settings.Epsilon = max(a.Settings.MinEpsilon, a.Settings.Epsilon*a.Settings.EpsilonDecay)
+}
+
+var _ Learner = &QLearning{}
+
+// QLearning is an off-policy tabular agent, which learns the value of acting greedily while exploring with epsilon greedy actions.
+type QLearning struct {
+	tabularAgent
+}
+
+// NewQLearning creates a Q-learning agent for the env, with every observation mapped to a state by the discretiser.
+func NewQLearning(env gym.Env, discretiser *Discretiser, settings TabularSettings, seed int64) *QLearning {
+	return &QLearning{newTabularAgent(env, discretiser, settings, seed)}
+}
+
+// Update learns from a single step of experience.
+func (a *QLearning) Update(observation []float64, action int, reward float64, nextObservation []float64, terminated bool) {
+	target := reward
+	if !terminated {
+		target += a.Settings.Discount * maxValue(a.Values(nextObservation))
+	}
+	values := a.Values(observation)
+	values[action] += a.Settings.LearningRate * (target - values[action])
+}
+
+// TrainEpisode runs one episode of at most maxSteps steps (or no limit if maxSteps is 0), learning from every step, and returns its total reward.
+func (a *QLearning) TrainEpisode(env gym.Env, maxSteps int) float64 {
+	obs := env.Reset().Observation
+	total := 0.0
+	for step := 0; maxSteps <= 0 || step < maxSteps; step++ {
+		action := a.Explore(obs)
+		stepData := env.Step(env.ConvertCategoricalAction(action))
+		a.Update(obs, action, stepData.Reward, stepData.Observation, stepData.Terminated)
+		total += stepData.Reward
+		obs = stepData.Observation
+		if stepData.Terminated {
+			break
+		}
+	}
+	a.endEpisode()
+	return total
+}
+
+var _ Learner = &SARSA{}
+
+// SARSA is an on-policy tabular agent, which learns the value of the epsilon greedy actions that it actually takes.
+type SARSA struct {
+	tabularAgent
+}
+
+// NewSARSA creates a SARSA agent for the env, with every observation mapped to a state by the discretiser.
+func NewSARSA(env gym.Env, discretiser *Discretiser, settings TabularSettings, seed int64) *SARSA {
+	return &SARSA{newTabularAgent(env, discretiser, settings, seed)}
+}
+
+// Update learns from a single step of experience, along with the action that will be taken next.
+func (a *SARSA) Update(observation []float64, action int, reward float64, nextObservation []float64, nextAction int, terminated bool) {
+	target := reward
+	if !terminated {
+		target += a.Settings.Discount * a.Values(nextObservation)[nextAction]
+	}
+	values := a.Values(observation)
+	values[action] += a.Settings.LearningRate * (target - values[action])
+}
+
+// TrainEpisode runs one episode of at most maxSteps steps (or no limit if maxSteps is 0), learning from every step, and returns its total reward.
+func (a *SARSA) TrainEpisode(env gym.Env, maxSteps int) float64 {
+	obs := env.Reset().Observation
+	action := a.Explore(obs)
+	total := 0.0
+	for step := 0; maxSteps <= 0 || step < maxSteps; step++ {
+		stepData := env.Step(env.ConvertCategoricalAction(action))
+		nextAction := a.Explore(stepData.Observation)
+		a.Update(obs, action, stepData.Reward, stepData.Observation, nextAction, stepData.Terminated)
+		total += stepData.Reward
+		obs, action = stepData.Observation, nextAction
+		if stepData.Terminated {
+			break
+		}
+	}
+	a.endEpisode()
+	return total
+}
+
+func argmax(values []float64) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+func maxValue(values []float64) float64 {
+	return values[argmax(values)]
+}
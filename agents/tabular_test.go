@@ -0,0 +1,58 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/JoshPattman/gym"
+)
+
+// TestLearning trains each agent on CartPole, and checks that its greedy policy does much better than a random policy.
+func TestLearning(t *testing.T) {
+	settings := gym.DefaultCartPoleSettings
+	low, high := CartPoleRanges(settings)
+	linearSettings := DefaultTabularSettings
+	linearSettings.LearningRate = 0.5
+	linearSettings.EpsilonDecay = 0.98
+
+	cases := []struct {
+		name     string
+		newAgent func(env gym.Env) Learner
+		episodes int
+		// Slow cases are skipped with -short.
+		slow bool
+	}{
+		{"QLearning", func(env gym.Env) Learner {
+			return NewQLearning(env, NewDiscretiser(low, high, []int{3, 3, 8, 8}), DefaultTabularSettings, 1)
+		}, 3000, true},
+		{"SARSA", func(env gym.Env) Learner {
+			return NewSARSA(env, NewDiscretiser(low, high, []int{3, 3, 8, 8}), DefaultTabularSettings, 1)
+		}, 3000, true},
+		{"LinearSARSA", func(env gym.Env) Learner {
+			return NewLinearSARSA(env, NewTileCoder(low, high, []int{4, 4, 8, 8}, 8), linearSettings, 1)
+		}, 300, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.slow && testing.Short() {
+				t.Skip("slow to train")
+			}
+			env := gym.NewCartPoleEnv(settings)
+			env.Seed(1)
+			random, err := gym.Evaluate(env, gym.NewRandomPolicy(env, 1), 5, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			agent := c.newAgent(env)
+			for i := 0; i < c.episodes; i++ {
+				agent.TrainEpisode(env, 1000)
+			}
+			result, err := gym.Evaluate(env, gym.FromCategorical(env, agent), 5, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.MeanReturn < random.MeanReturn+100 {
+				t.Errorf("mean return %.1f is not far enough above random (%.1f)", result.MeanReturn, random.MeanReturn)
+			}
+		})
+	}
+}
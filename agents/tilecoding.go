@@ -0,0 +1,217 @@
+package agents
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/JoshPattman/gym"
+)
+
+// TileCoder turns an observation into a sparse set of active features, using several grids (tilings) which are each offset by a fraction of a tile.
+// Each tiling has exactly one active tile, so there are always NumTilings active features.
+type TileCoder struct {
+	Low        []float64
+	High       []float64
+	NumTilings int
+	// The number of tiles along each dimension of every tiling.
+	Tiles []int
+	// If greater than 0, feature indices are hashed into this many features, which keeps memory bounded for high dimensional observations.
+	MaxFeatures int
+}
+
+// NewTileCoder creates a tile coder with the given range and number of tiles for each dimension.
+func NewTileCoder(low, high []float64, tiles []int, numTilings int) *TileCoder {
+	if len(low) != len(high) || len(low) != len(tiles) {
+		panic("low, high and tiles must have the same length")
+	}
+	if numTilings < 1 {
+		panic("must have at least one tiling")
+	}
+	return &TileCoder{Low: low, High: high, NumTilings: numTilings, Tiles: tiles}
+}
+
+// NewUniformTileCoder creates a tile coder that covers every dimension of the observation between -1 and 1 with the same number of tiles.
+func NewUniformTileCoder(observationLength, tiles, numTilings int) *TileCoder {
+	low := make([]float64, observationLength)
+	high := make([]float64, observationLength)
+	numTiles := make([]int, observationLength)
+	for i := range low {
+		low[i], high[i], numTiles[i] = -1, 1, tiles
+	}
+	return NewTileCoder(low, high, numTiles, numTilings)
+}
+
+// tilesPerTiling gets the number of tiles in a single tiling. Each dimension has one extra tile to make room for the offsets.
+func (c *TileCoder) tilesPerTiling() int {
+	n := 1
+	for _, t := range c.Tiles {
+		n *= t + 1
+	}
+	return n
+}
+
+// NumFeatures gets the total number of features.
+func (c *TileCoder) NumFeatures() int {
+	if c.MaxFeatures > 0 {
+		return c.MaxFeatures
+	}
+	return c.NumTilings * c.tilesPerTiling()
+}
+
+// Features writes the index of the active feature of each tiling into features, which must have length NumTilings.
+func (c *TileCoder) Features(observation []float64, features []int) {
+	if len(observation) != len(c.Tiles) {
+		panic("observation has wrong length for tile coder")
+	}
+	if len(features) != c.NumTilings {
+		panic("features must have length NumTilings")
+	}
+	perTiling := c.tilesPerTiling()
+	for tiling := range features {
+		// Each tiling is offset by a different fraction of a tile, with an asymmetric offset on each dimension.
+		tile := 0
+		for i, v := range observation {
+			offset := float64(tiling*(2*i+1)%c.NumTilings) / float64(c.NumTilings)
+			scaled := (v-c.Low[i])/(c.High[i]-c.Low[i])*float64(c.Tiles[i]) + offset
+			t := max(0, min(c.Tiles[i], int(math.Floor(scaled))))
+			tile = tile*(c.Tiles[i]+1) + t
+		}
+		index := tiling*perTiling + tile
+		if c.MaxFeatures > 0 {
+			index = int(uint64(index) * 0x9E3779B97F4A7C15 >> 1 % uint64(c.MaxFeatures))
+		}
+		features[tiling] = index
+	}
+}
+
+var _ Learner = &LinearSARSA{}
+
+// LinearSARSA is an on-policy agent which learns a linear approximation of the value of each action over tile coded features.
+// Unlike the tabular agents, nearby observations share what they learn, so it needs far fewer episodes on continuous observations.
+type LinearSARSA struct {
+	// The weight of each feature for each action. The weight of feature f for action a is Weights[a*NumFeatures+f].
+	Weights     []float64
+	NumActions  int
+	TileCoder   *TileCoder
+	Settings    TabularSettings
+	numFeatures int
+
+	features     []int
+	nextFeatures []int
+	values       []float64
+	rng          *rand.Rand
+}
+
+// NewLinearSARSA creates a linear SARSA agent for the env, using the tile coder to create its features.
+// The learning rate in the settings is divided between the tilings, so the same settings can be used for any number of tilings.
+func NewLinearSARSA(env gym.Env, tileCoder *TileCoder, settings TabularSettings, seed int64) *LinearSARSA {
+	numActions := env.NumCategoricalActions()
+	numFeatures := tileCoder.NumFeatures()
+	weights := make([]float64, numActions*numFeatures)
+	// Every action value is the sum of one weight per tiling, so this gives each action the initial value.
+	for i := range weights {
+		weights[i] = settings.InitialValue / float64(tileCoder.NumTilings)
+	}
+	return &LinearSARSA{
+		Weights:      weights,
+		NumActions:   numActions,
+		TileCoder:    tileCoder,
+		Settings:     settings,
+		numFeatures:  numFeatures,
+		features:     make([]int, tileCoder.NumTilings),
+		nextFeatures: make([]int, tileCoder.NumTilings),
+		values:       make([]float64, numActions),
+		rng:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Gets the value of an action given the active features.
+func (a *LinearSARSA) value(features []int, action int) float64 {
+	w := a.Weights[action*a.numFeatures : (action+1)*a.numFeatures]
+	v := 0.0
+	for _, f := range features {
+		v += w[f]
+	}
+	return v
+}
+
+// Values gets the value of every action for the observation.
+func (a *LinearSARSA) Values(observation []float64) []float64 {
+	features := make([]int, a.TileCoder.NumTilings)
+	a.TileCoder.Features(observation, features)
+	values := make([]float64, a.NumActions)
+	for i := range values {
+		values[i] = a.value(features, i)
+	}
+	return values
+}
+
+// Picks the greedy action given the active features.
+func (a *LinearSARSA) greedy(features []int) int {
+	for i := range a.values {
+		a.values[i] = a.value(features, i)
+	}
+	return argmax(a.values)
+}
+
+// Picks an epsilon greedy action given the active features.
+func (a *LinearSARSA) explore(features []int) int {
+	if a.rng.Float64() < a.Settings.Epsilon {
+		return a.rng.Intn(a.NumActions)
+	}
+	return a.greedy(features)
+}
+
+// ActCategorical picks the action with the highest value. It implements gym.CategoricalPolicy.
+func (a *LinearSARSA) ActCategorical(observation []float64) int {
+	a.TileCoder.Features(observation, a.features)
+	return a.greedy(a.features)
+}
+
+// Explore picks a random action with probability Epsilon, and otherwise the action with the highest value.
+func (a *LinearSARSA) Explore(observation []float64) int {
+	a.TileCoder.Features(observation, a.features)
+	return a.explore(a.features)
+}
+
+// Update learns from a single step of experience, along with the action that will be taken next.
+func (a *LinearSARSA) Update(observation []float64, action int, reward float64, nextObservation []float64, nextAction int, terminated bool) {
+	a.TileCoder.Features(observation, a.features)
+	a.TileCoder.Features(nextObservation, a.nextFeatures)
+	a.update(a.features, action, reward, a.nextFeatures, nextAction, terminated)
+}
+
+// Applies a semi-gradient SARSA update given the active features.
+func (a *LinearSARSA) update(features []int, action int, reward float64, nextFeatures []int, nextAction int, terminated bool) {
+	target := reward
+	if !terminated {
+		target += a.Settings.Discount * a.value(nextFeatures, nextAction)
+	}
+	delta := a.Settings.LearningRate / float64(len(features)) * (target - a.value(features, action))
+	w := a.Weights[action*a.numFeatures : (action+1)*a.numFeatures]
+	for _, f := range features {
+		w[f] += delta
+	}
+}
+
+// TrainEpisode runs one episode of at most maxSteps steps (or no limit if maxSteps is 0), learning from every step, and returns its total reward.
+func (a *LinearSARSA) TrainEpisode(env gym.Env, maxSteps int) float64 {
+	obs := env.Reset().Observation
+	a.TileCoder.Features(obs, a.features)
+	action := a.explore(a.features)
+	total := 0.0
+	for step := 0; maxSteps <= 0 || step < maxSteps; step++ {
+		stepData := env.Step(env.ConvertCategoricalAction(action))
+		a.TileCoder.Features(stepData.Observation, a.nextFeatures)
+		nextAction := a.explore(a.nextFeatures)
+		a.update(a.features, action, stepData.Reward, a.nextFeatures, nextAction, stepData.Terminated)
+		total += stepData.Reward
+		a.features, a.nextFeatures = a.nextFeatures, a.features
+		action = nextAction
+		if stepData.Terminated {
+			break
+		}
+	}
+	a.Settings.Epsilon = max(a.Settings.MinEpsilon, a.Settings.Epsilon*a.Settings.EpsilonDecay)
+	return total
+}
@@ -0,0 +1,301 @@
+package gym
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gopxl/pixel"
+)
+
+var _ Policy = &RandomPolicy{}
+
+// RandomPolicy picks every action uniformly at random between -1 and 1. It is the baseline that other policies should beat.
+type RandomPolicy struct {
+	actionLength int
+	rng          *rand.Rand
+}
+
+// NewRandomPolicy creates a random policy for the env, with its own seeded source of randomness.
+func NewRandomPolicy(env Env, seed int64) *RandomPolicy {
+	return &RandomPolicy{
+		actionLength: env.ActionLength(),
+		rng:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Act implements Policy.
+func (p *RandomPolicy) Act([]float64) []float64 {
+	action := make([]float64, p.actionLength)
+	for i := range action {
+		action[i] = p.rng.Float64()*2 - 1
+	}
+	return action
+}
+
+var _ Policy = &CartPoleLQR{}
+
+// CartPoleLQR balances the pole of a CartPoleEnv, while keeping the cart near the center, with a linear quadratic regulator.
+// The gains are found by linearising the cartpole physics around the upright position, using the env's settings.
+type CartPoleLQR struct {
+	// The gains on cart position, cart velocity, pole angle and pole angular velocity.
+	Gains    [4]float64
+	settings CartPoleSettings
+}
+
+// NewCartPoleLQR creates an LQR controller for a cartpole with the given settings.
+// The cost weights are for the cart position, cart velocity, pole angle and pole angular velocity, and r is the cost weight of the action.
+func NewCartPoleLQR(settings CartPoleSettings, stateCosts [4]float64, r float64) *CartPoleLQR {
+	dt := settings.TimeStep
+	g := settings.GravityAcceleration
+	// The linearised dynamics of CartPoleEnv.Step, s' = As + Bu, with state [x, v, theta, omega].
+	// The velocities are updated first, then the positions are updated with the new velocities.
+	a := [4][4]float64{
+		{1, dt, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1 + g*dt*dt, dt},
+		{0, 0, g * dt, 1},
+	}
+	b := [4]float64{
+		settings.Acceleration * dt * dt,
+		settings.Acceleration * dt,
+		settings.TorqueMultiplier * dt * dt,
+		settings.TorqueMultiplier * dt,
+	}
+	return &CartPoleLQR{
+		Gains:    solveDiscreteLQR(a, b, stateCosts, r),
+		settings: settings,
+	}
+}
+
+// NewDefaultCartPoleLQR creates an LQR controller with cost weights that work well with DefaultCartPoleSettings.
+func NewDefaultCartPoleLQR(settings CartPoleSettings) *CartPoleLQR {
+	return NewCartPoleLQR(settings, [4]float64{1, 1, 10, 1}, 1)
+}
+
+// Act implements Policy.
+func (p *CartPoleLQR) Act(observation []float64) []float64 {
+	// Undo the normalisation of the observation.
+	state := [4]float64{
+		observation[0],
+		observation[1] * p.settings.MaxVelocity,
+		observation[2] * 180,
+		observation[3] * p.settings.MaxRotationalVelocity,
+	}
+	u := 0.0
+	for i := range state {
+		u -= p.Gains[i] * state[i]
+	}
+	return []float64{math.Max(-1, math.Min(1, u))}
+}
+
+// Finds the optimal gains K for the control law u = -Ks, for a system with one input, by iterating the discrete Riccati equation until it converges.
+func solveDiscreteLQR(a [4][4]float64, b [4]float64, q [4]float64, r float64) [4]float64 {
+	var p [4][4]float64
+	for i := range p {
+		p[i][i] = q[i]
+	}
+	var k [4]float64
+	for iter := 0; iter < 10000; iter++ {
+		// pb = P B, and bpa = B^T P A.
+		var pb, bpa [4]float64
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				pb[i] += p[i][j] * b[j]
+			}
+		}
+		bpb := r
+		for i := 0; i < 4; i++ {
+			bpb += b[i] * pb[i]
+		}
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 4; i++ {
+				bpa[j] += pb[i] * a[i][j]
+			}
+		}
+		for j := range k {
+			k[j] = bpa[j] / bpb
+		}
+
+		// P = Q + A^T P A - A^T P B K.
+		var next [4][4]float64
+		change := 0.0
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				v := -bpa[i] * k[j]
+				for m := 0; m < 4; m++ {
+					for n := 0; n < 4; n++ {
+						v += a[m][i] * p[m][n] * a[n][j]
+					}
+				}
+				if i == j {
+					v += q[i]
+				}
+				next[i][j] = v
+			}
+		}
+		// Rounding errors make P slightly asymmetric, and without this they grow until the iteration diverges.
+		for i := 0; i < 4; i++ {
+			for j := i; j < 4; j++ {
+				sym := (next[i][j] + next[j][i]) / 2
+				change = math.Max(change, math.Abs(sym-p[i][j]))
+				next[i][j], next[j][i] = sym, sym
+			}
+		}
+		p = next
+		if change < 1e-9 {
+			break
+		}
+	}
+	return k
+}
+
+var _ Policy = &BallPushController{}
+
+// BallPushController is a scripted controller for BallPushEnv.
+// It picks the first ball that is not in its target, moves to the point behind the ball (going around it if needed), then pushes it into the target.
+// It ignores obstacles.
+type BallPushController struct {
+	// How far behind the ball the agent lines up, on top of the agent and ball radii.
+	Clearance float64
+	settings  *BallPushSettings
+}
+
+// NewBallPushController creates a scripted controller for a ball push env with the given settings.
+func NewBallPushController(settings *BallPushSettings) *BallPushController {
+	return &BallPushController{
+		Clearance: 1,
+		settings:  settings,
+	}
+}
+
+// Act implements Policy.
+func (p *BallPushController) Act(observation []float64) []float64 {
+	s := p.settings
+	r, maxSpeed := s.BoundaryRadius, s.AgentMaxSpeed()
+	agent := pixel.V(observation[0], observation[1]).Scaled(r)
+	agentVel := pixel.V(observation[4], observation[5]).Scaled(maxSpeed)
+
+	// Read the position of each ball and its target from the observation.
+	balls := []pixel.Vec{agent.Add(pixel.V(observation[2], observation[3]).Scaled(2 * r))}
+	targets := []pixel.Vec{pixel.ZV}
+	i := 8
+	if s.RandomTargets {
+		targets[0] = balls[0].Add(pixel.V(observation[8], observation[9]).Scaled(2 * r))
+		i = 10
+	}
+	for b := 1; b < s.numBalls(); b, i = b+1, i+6 {
+		ball := agent.Add(pixel.V(observation[i], observation[i+1]).Scaled(2 * r))
+		balls = append(balls, ball)
+		targets = append(targets, ball.Add(pixel.V(observation[i+4], observation[i+5]).Scaled(2*r)))
+	}
+
+	// Pick the first ball that is not already in its target.
+	ball, target := pixel.ZV, pixel.ZV
+	done := true
+	for b := range balls {
+		if balls[b].Sub(targets[b]).Len() >= (s.TargetRadius-s.BallRadius)*0.5 {
+			ball, target = balls[b], targets[b]
+			done = false
+			break
+		}
+	}
+	if done {
+		// Stay still.
+		return p.velocityAction(pixel.ZV, agentVel)
+	}
+
+	toTarget := target.Sub(ball)
+	dir := toTarget.Unit()
+	rel := agent.Sub(ball)
+	along := rel.Dot(dir)
+	side := rel.Sub(dir.Scaled(along))
+	var desired pixel.Vec
+	if along < 0 && side.Len() < s.BallRadius*0.5 {
+		// The agent is behind the ball, so push it towards the target, slowing down as it gets close so the ball does not overshoot.
+		speed := math.Min(maxSpeed, s.BallDrag*toTarget.Len()*0.8)
+		desired = ball.Sub(agent).Unit().Scaled(speed)
+	} else {
+		// Go to the point behind the ball. If the agent is not already roughly behind it, circle around the ball towards that point instead.
+		orbit := s.BallRadius + s.AgentRadius + p.Clearance
+		waypoint := ball.Sub(dir.Scaled(orbit))
+		diff := math.Remainder(dir.Scaled(-1).Angle()-rel.Angle(), 2*math.Pi)
+		if math.Abs(diff) > math.Pi/4 {
+			waypoint = ball.Add(pixel.V(orbit, 0).Rotated(rel.Angle() + math.Copysign(math.Pi/4, diff)))
+		}
+		toWaypoint := waypoint.Sub(agent)
+		desired = toWaypoint.Unit().Scaled(math.Min(maxSpeed, toWaypoint.Len()*3))
+	}
+	return p.velocityAction(desired, agentVel)
+}
+
+// Gets the action that accelerates the agent from its current velocity towards the desired velocity.
+func (p *BallPushController) velocityAction(desired, current pixel.Vec) []float64 {
+	accel := desired.Sub(current)
+	if accel.Len() < 0.05 {
+		return []float64{0, 0}
+	}
+	accel = accel.Unit()
+	return []float64{accel.X, accel.Y}
+}
+
+var _ Policy = &WalkerCPG{}
+
+// WalkerCPG is a central pattern generator gait for WalkerEnv.
+// Each joint tracks a sine wave with its own amplitude, offset and phase, using a proportional controller on the motor speed, which the env applies with Player.SetMotorSpeeds.
+// The legs are half a cycle apart. It keeps its own clock, which advances by TimeStep every call to Act.
+type WalkerCPG struct {
+	// The frequency of the gait in cycles per second.
+	Frequency float64
+	// The amplitude and offset of the hip and knee angles, in radians. A negative amplitude inverts the wave.
+	HipAmplitude  float64
+	HipOffset     float64
+	KneeAmplitude float64
+	KneeOffset    float64
+	// How far the knee leads the hip, in radians.
+	KneePhase float64
+	// The motor speed, as a fraction of the max, per radian of angle error.
+	Gain float64
+
+	settings WalkerSettings
+	time     float64
+}
+
+// NewWalkerCPG creates a gait with parameters that walk forwards with DefaultWalkerSettings.
+// A TimeStep of 0 or less is replaced with the default, in the same way as NewWalkerEnv, so the clock stays in step with the env.
+func NewWalkerCPG(settings WalkerSettings) *WalkerCPG {
+	settings.applyPhysicsDefaults()
+	return &WalkerCPG{
+		Frequency:     0.5,
+		HipAmplitude:  -1.2,
+		HipOffset:     0,
+		KneeAmplitude: -0.8,
+		KneeOffset:    0.6,
+		KneePhase:     math.Pi,
+		Gain:          2,
+		settings:      settings,
+	}
+}
+
+// Reset restarts the gait from the start of its cycle.
+func (p *WalkerCPG) Reset() {
+	p.time = 0
+}
+
+// Act implements Policy.
+func (p *WalkerCPG) Act(observation []float64) []float64 {
+	phase := 2 * math.Pi * p.Frequency * p.time
+	p.time += p.settings.TimeStep
+	// The joints are ordered left hip, right hip, left knee, right knee.
+	targets := [4]float64{
+		p.HipOffset + p.HipAmplitude*math.Sin(phase),
+		p.HipOffset + p.HipAmplitude*math.Sin(phase+math.Pi),
+		p.KneeOffset + p.KneeAmplitude*math.Sin(phase+p.KneePhase),
+		p.KneeOffset + p.KneeAmplitude*math.Sin(phase+p.KneePhase+math.Pi),
+	}
+	action := make([]float64, 4)
+	for i, target := range targets {
+		angle := observation[i] * p.settings.JointMaxAngle
+		action[i] = math.Max(-1, math.Min(1, p.Gain*(target-angle)))
+	}
+	return action
+}
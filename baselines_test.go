@@ -0,0 +1,54 @@
+package gym
+
+import "testing"
+
+// TestBaselines evaluates every baseline controller against a RandomPolicy on its env, and fails if any of them do not beat it by a clear margin.
+// This is a sanity check of the envs and their rewards: if it fails, either an env or a controller has been broken.
+func TestBaselines(t *testing.T) {
+	cartPole := NewCartPoleEnv(DefaultCartPoleSettings)
+	ballPush := NewBallPushEnv(DefaultBallPushSettings)
+	walker := NewWalkerEnv(DefaultWalkerSettings)
+	cases := []struct {
+		env      Env
+		baseline Policy
+		// The amount that the mean return of the baseline must be above the mean return of the random policy.
+		margin float64
+	}{
+		{cartPole, NewDefaultCartPoleLQR(cartPole.Settings), 200},
+		{ballPush, NewBallPushController(ballPush.Settings), 2},
+		{walker, NewWalkerCPG(DefaultWalkerSettings), 10},
+	}
+	for _, c := range cases {
+		t.Run(c.env.Name(), func(t *testing.T) {
+			baseline, err := Evaluate(c.env, c.baseline, 5, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			random, err := Evaluate(c.env, NewRandomPolicy(c.env, 1), 5, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if baseline.MeanReturn < random.MeanReturn+c.margin {
+				t.Errorf("baseline has mean return %.3f, which does not beat the random policy's %.3f by %.3f",
+					baseline.MeanReturn, random.MeanReturn, c.margin)
+			}
+		})
+	}
+}
+
+// TestWalkerCPGDefaultTimeStep checks that a WalkerCPG made with a TimeStep of 0 uses the same default as the WalkerEnv, rather than never advancing its clock.
+func TestWalkerCPGDefaultTimeStep(t *testing.T) {
+	settings := DefaultWalkerSettings
+	settings.TimeStep = 0
+	zero := NewWalkerCPG(settings)
+	def := NewWalkerCPG(DefaultWalkerSettings)
+	observation := NewWalkerEnv(settings).Reset().Observation
+	for i := 0; i < 30; i++ {
+		got, want := zero.Act(observation), def.Act(observation)
+		for j := range want {
+			if got[j] != want[j] {
+				t.Fatalf("step %d: got action %v, want %v", i, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package bench measures how fast the gym envs step.
+// The benchmarks are ordinary functions that take a *testing.B. Run them with go test -bench . ./bench,
+// or with the gymbench command, which runs the same Suite and can compare the results with a saved baseline.
+package bench
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/JoshPattman/gym"
+)
+
+// The number of random actions that the benchmarks cycle through. They are generated before timing starts, so that generating them is not measured.
+const numActions = 1024
+
+// Generates random valid actions for the env.
+func randomActions(env gym.Env, seed int64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	actions := make([][]float64, numActions)
+	for i := range actions {
+		actions[i] = make([]float64, env.ActionLength())
+		for j := range actions[i] {
+			actions[i][j] = rng.Float64()*2 - 1
+		}
+	}
+	return actions
+}
+
+// Step benchmarks Step with random actions. The env is reset whenever its episode terminates, so the cost of occasional resets is included.
+func Step(b *testing.B, env gym.Env) {
+	actions := randomActions(env, 1)
+	env.Reset()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if env.Step(actions[i%numActions]).Terminated {
+			env.Reset()
+		}
+	}
+}
+
+// StepInto benchmarks gym.StepInto with random actions, reusing a single StepData.
+// The env is reset whenever its episode terminates, so the cost of occasional resets is included.
+func StepInto(b *testing.B, env gym.Env) {
+	actions := randomActions(env, 1)
+	env.Reset()
+	var data gym.StepData
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gym.StepInto(env, actions[i%numActions], &data)
+		if data.Terminated {
+			env.Reset()
+		}
+	}
+}
+
+// CartPoleBatch benchmarks stepping a gym.CartPoleBatch of n envs, split between the given number of goroutines, with AutoReset on.
+// Each op is one step of the whole batch, and the env steps per second are reported as the "env-steps/s" metric.
+func CartPoleBatch(b *testing.B, n, workers int) {
+	batch := gym.NewCartPoleBatch(n, gym.DefaultCartPoleSettings)
+	batch.Workers = workers
+	batch.AutoReset = true
+	batch.Seed(1)
+	batch.Reset()
+	rng := rand.New(rand.NewSource(1))
+	actions := make([][]float64, 16)
+	for i := range actions {
+		actions[i] = make([]float64, n)
+		for j := range actions[i] {
+			actions[i][j] = rng.Float64()*2 - 1
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch.Step(actions[i%len(actions)])
+	}
+	b.ReportMetric(float64(b.N)*float64(n)/b.Elapsed().Seconds(), "env-steps/s")
+}
@@ -0,0 +1,46 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JoshPattman/gym"
+)
+
+// Runs every case in Suite whose name ends with "/"+op as a sub-benchmark named after its env, so that go test and gymbench measure exactly the same thing.
+func runSuite(b *testing.B, op string) {
+	for _, c := range Suite() {
+		if env, ok := strings.CutSuffix(c.Name, "/"+op); ok {
+			b.Run(env, c.Run)
+		}
+	}
+}
+
+func BenchmarkStep(b *testing.B) {
+	runSuite(b, "Step")
+}
+
+func BenchmarkStepInto(b *testing.B) {
+	runSuite(b, "StepInto")
+}
+
+func BenchmarkReset(b *testing.B) {
+	runSuite(b, "Reset")
+}
+
+// BenchmarkStepIntoNoInfo benchmarks StepInto with Info disabled, which should report 0 allocs/op for every env.
+func BenchmarkStepIntoNoInfo(b *testing.B) {
+	cartPole := gym.DefaultCartPoleSettings
+	cartPole.DisableInfo = true
+	deterministicCartPole := cartPole
+	deterministicCartPole.Deterministic = true
+	ballPush := *gym.DefaultBallPushSettings
+	ballPush.DisableInfo = true
+	deterministicBallPush := ballPush
+	deterministicBallPush.Deterministic = true
+
+	b.Run("CartPole", func(b *testing.B) { StepInto(b, gym.NewCartPoleEnv(cartPole)) })
+	b.Run("CartPoleDeterministic", func(b *testing.B) { StepInto(b, gym.NewCartPoleEnv(deterministicCartPole)) })
+	b.Run("BallPush", func(b *testing.B) { StepInto(b, gym.NewBallPushEnv(&ballPush)) })
+	b.Run("BallPushDeterministic", func(b *testing.B) { StepInto(b, gym.NewBallPushEnv(&deterministicBallPush)) })
+}
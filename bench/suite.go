@@ -0,0 +1,244 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"regexp"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/JoshPattman/gym"
+)
+
+// Case is a single named benchmark in the suite.
+type Case struct {
+	Name string
+	// The number of env steps taken by each op, used to compute steps per second. 0 means the op is not a step, such as a reset.
+	StepsPerOp int
+	Run        func(b *testing.B)
+}
+
+// Suite gets a benchmark of Step, StepInto and Reset for every env and wrapper, each with its default settings.
+// The names are stable, so results can be compared between versions.
+func Suite() []Case {
+	envs := []struct {
+		name string
+		new  func() gym.Env
+	}{
+		{"CartPole", func() gym.Env { return gym.NewCartPoleEnv(gym.DefaultCartPoleSettings) }},
+		{"BallPush", func() gym.Env { s := *gym.DefaultBallPushSettings; return gym.NewBallPushEnv(&s) }},
+		{"Walker", func() gym.Env { return gym.NewWalkerEnv(gym.DefaultWalkerSettings) }},
+		{"Pong", func() gym.Env { return gym.NewPongEnv(gym.DefaultPongSettings) }},
+		{"Rope", func() gym.Env { return gym.NewRopeEnv(gym.DefaultRopeSettings) }},
+		{"PixelObservation(CartPole)", func() gym.Env {
+			return gym.NewPixelObservationEnv(gym.NewCartPoleEnv(gym.DefaultCartPoleSettings), gym.DefaultPixelObservationSettings)
+		}},
+	}
+	multiEnvs := []struct {
+		name string
+		new  func() gym.MultiAgentEnv
+	}{
+		{"CompetitiveBallPush", func() gym.MultiAgentEnv {
+			s := *gym.DefaultCompetitiveBallPushSettings
+			return gym.NewCompetitiveBallPushEnv(&s, "a", "b")
+		}},
+		{"MultiAgentPong", func() gym.MultiAgentEnv { return gym.NewMultiAgentPongEnv(gym.DefaultPongSettings, "a", "b") }},
+	}
+
+	var cases []Case
+	for _, e := range envs {
+		e := e
+		cases = append(cases,
+			Case{e.name + "/Step", 1, func(b *testing.B) { Step(b, e.new()) }},
+			Case{e.name + "/Reset", 0, func(b *testing.B) { Reset(b, e.new()) }},
+		)
+		if _, ok := e.new().(gym.StepIntoEnv); ok {
+			cases = append(cases, Case{e.name + "/StepInto", 1, func(b *testing.B) { StepInto(b, e.new()) }})
+		}
+	}
+	for _, e := range multiEnvs {
+		e := e
+		cases = append(cases,
+			Case{e.name + "/Step", 1, func(b *testing.B) { MultiAgentStep(b, e.new()) }},
+			Case{e.name + "/Reset", 0, func(b *testing.B) { MultiAgentReset(b, e.new()) }},
+		)
+	}
+	cases = append(cases, Case{"CartPoleBatch(1024)/Step", 1024, func(b *testing.B) { CartPoleBatch(b, 1024, 1) }})
+	return cases
+}
+
+// Reset benchmarks Reset.
+func Reset(b *testing.B, env gym.Env) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env.Reset()
+	}
+}
+
+// MultiAgentStep benchmarks Step on a multi-agent env, with random actions for every agent.
+// The env is reset whenever any agent's episode terminates, so the cost of occasional resets is included.
+func MultiAgentStep(b *testing.B, env gym.MultiAgentEnv) {
+	rng := rand.New(rand.NewSource(1))
+	actions := make([]map[gym.AgentID][]float64, numActions)
+	for i := range actions {
+		actions[i] = make(map[gym.AgentID][]float64)
+		for _, id := range env.Agents() {
+			action := make([]float64, env.ActionLength())
+			for j := range action {
+				action[j] = rng.Float64()*2 - 1
+			}
+			actions[i][id] = action
+		}
+	}
+	env.Reset()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, terminated := range env.Step(actions[i%numActions]).Terminated {
+			if terminated {
+				env.Reset()
+				break
+			}
+		}
+	}
+}
+
+// MultiAgentReset benchmarks Reset on a multi-agent env.
+func MultiAgentReset(b *testing.B, env gym.MultiAgentEnv) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env.Reset()
+	}
+}
+
+// Result is the outcome of one benchmark case.
+type Result struct {
+	Name        string  `json:"name"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	StepsPerSec float64 `json:"steps_per_sec,omitempty"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+}
+
+// Run runs every case whose name matches filter, or every case if filter is nil. Each case runs for the -test.benchtime, which defaults to 1s.
+// If progress is not nil, each result is written to it as soon as it is ready.
+func Run(cases []Case, filter *regexp.Regexp, progress io.Writer) []Result {
+	var results []Result
+	for _, c := range cases {
+		if filter != nil && !filter.MatchString(c.Name) {
+			continue
+		}
+		r := testing.Benchmark(c.Run)
+		result := Result{
+			Name:        c.Name,
+			NsPerOp:     float64(r.NsPerOp()),
+			AllocsPerOp: r.AllocsPerOp(),
+			BytesPerOp:  r.AllocedBytesPerOp(),
+		}
+		// NsPerOp is rounded down to a whole number, which loses too much precision for the fastest envs.
+		if r.N > 0 {
+			result.NsPerOp = float64(r.T.Nanoseconds()) / float64(r.N)
+		}
+		if c.StepsPerOp > 0 && result.NsPerOp > 0 {
+			result.StepsPerSec = float64(c.StepsPerOp) * 1e9 / result.NsPerOp
+		}
+		if progress != nil {
+			fmt.Fprintf(progress, "%s\t%.1f ns/op\n", result.Name, result.NsPerOp)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// WriteTable writes the results as an aligned table.
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "benchmark\tns/op\tsteps/s\tallocs/op\tB/op\t")
+	for _, r := range results {
+		steps := "-"
+		if r.StepsPerSec > 0 {
+			steps = fmt.Sprintf("%.0f", r.StepsPerSec)
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%s\t%d\t%d\t\n", r.Name, r.NsPerOp, steps, r.AllocsPerOp, r.BytesPerOp)
+	}
+	return tw.Flush()
+}
+
+// Comparison is the change in one benchmark between a baseline and the current results.
+type Comparison struct {
+	Name     string
+	Baseline Result
+	Current  Result
+	// The relative change in time per op, so 0.1 is 10% slower and -0.1 is 10% faster.
+	TimeChange float64
+	// True if the time or allocations per op grew by more than the threshold. An op that did not allocate at all has regressed if it allocates now.
+	Regressed bool
+}
+
+// Compare compares the current results with a baseline. Benchmarks that are only in one of them are skipped.
+// A benchmark has regressed if its time or allocations per op grew by more than threshold (0.1 for 10%).
+// Some envs, such as Walker, allocate a varying amount, so small changes in allocations are not counted.
+func Compare(baseline, current []Result, threshold float64) []Comparison {
+	byName := make(map[string]Result, len(baseline))
+	for _, r := range baseline {
+		byName[r.Name] = r
+	}
+	var comparisons []Comparison
+	for _, cur := range current {
+		base, ok := byName[cur.Name]
+		if !ok || base.NsPerOp <= 0 {
+			continue
+		}
+		change := cur.NsPerOp/base.NsPerOp - 1
+		comparisons = append(comparisons, Comparison{
+			Name:       cur.Name,
+			Baseline:   base,
+			Current:    cur,
+			TimeChange: change,
+			Regressed:  change > threshold || float64(cur.AllocsPerOp) > float64(base.AllocsPerOp)*(1+threshold),
+		})
+	}
+	return comparisons
+}
+
+// WriteComparison writes the comparisons as an aligned table, marking regressions.
+func WriteComparison(w io.Writer, comparisons []Comparison) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "benchmark\tbaseline ns/op\tns/op\tchange\tbaseline allocs/op\tallocs/op\t\t")
+	for _, c := range comparisons {
+		mark := ""
+		if c.Regressed {
+			mark = "REGRESSED"
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%+.1f%%\t%d\t%d\t%s\t\n",
+			c.Name, c.Baseline.NsPerOp, c.Current.NsPerOp, c.TimeChange*100, c.Baseline.AllocsPerOp, c.Current.AllocsPerOp, mark)
+	}
+	return tw.Flush()
+}
+
+// SaveResultsFile writes results to a JSON file, to be used as a baseline later.
+func SaveResultsFile(path string, results []Result) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadResultsFile reads results written by SaveResultsFile.
+func LoadResultsFile(path string) ([]Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return results, nil
+}
@@ -0,0 +1,75 @@
+package buffer
+
+import "github.com/JoshPattman/gym"
+
+// NStep turns a stream of 1-step transitions from a single env into n-step transitions.
+// The reward of each n-step transition is the discounted sum of the next N rewards, and its next observation is the one N steps later.
+// If the episode terminates within the N steps, the return stops there and the transition is marked as terminated, so it is not bootstrapped.
+// If the episode is truncated, the return also stops there, but the transition is not marked as terminated, so it is still bootstrapped from the last observation.
+type NStep struct {
+	N     int
+	Gamma float64
+
+	pending []Transition
+}
+
+// NewNStep creates an n-step accumulator with the given number of steps and discount factor.
+func NewNStep(n int, gamma float64) *NStep {
+	if n < 1 {
+		panic("n must be at least 1")
+	}
+	return &NStep{N: n, Gamma: gamma}
+}
+
+// Add adds the next 1-step transition, and returns any n-step transitions that it completes.
+// Usually this returns one transition, but it returns none until N transitions have been seen, and all of the pending ones when the episode ends.
+func (s *NStep) Add(t Transition) []Transition {
+	t.Observation = append([]float64(nil), t.Observation...)
+	t.Action = append([]float64(nil), t.Action...)
+	t.NextObservation = append([]float64(nil), t.NextObservation...)
+	s.pending = append(s.pending, t)
+	if t.Terminated || t.Truncated {
+		return s.Flush()
+	}
+	if len(s.pending) < s.N {
+		return nil
+	}
+	completed := []Transition{s.combine(0)}
+	s.pending = s.pending[1:]
+	return completed
+}
+
+// AddStep adds a 1-step transition. See NewTransition.
+func (s *NStep) AddStep(observation, action []float64, step gym.StepData, truncated bool) []Transition {
+	return s.Add(NewTransition(observation, action, step, truncated))
+}
+
+// Flush returns every pending transition, each covering however many steps are left, and clears them.
+// This is called automatically when an episode ends, but should also be called if collection stops in the middle of an episode.
+func (s *NStep) Flush() []Transition {
+	completed := make([]Transition, len(s.pending))
+	for i := range s.pending {
+		completed[i] = s.combine(i)
+	}
+	s.pending = s.pending[:0]
+	return completed
+}
+
+// Combines the pending transitions from index start onwards into one transition.
+func (s *NStep) combine(start int) Transition {
+	first, last := s.pending[start], s.pending[len(s.pending)-1]
+	reward, discount := 0.0, 1.0
+	for _, t := range s.pending[start:] {
+		reward += discount * t.Reward
+		discount *= s.Gamma
+	}
+	return Transition{
+		Observation:     first.Observation,
+		Action:          first.Action,
+		Reward:          reward,
+		NextObservation: last.NextObservation,
+		Terminated:      last.Terminated,
+		Truncated:       last.Truncated,
+		Steps:           len(s.pending) - start,
+	}
+}
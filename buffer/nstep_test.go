@@ -0,0 +1,87 @@
+package buffer
+
+import (
+	"math"
+	"testing"
+)
+
+// Creates a transition with a one value observation, so that the observations show which step each transition came from.
+func stepTransition(step int, reward float64, terminated, truncated bool) Transition {
+	return Transition{
+		Observation:     []float64{float64(step)},
+		Action:          []float64{0},
+		Reward:          reward,
+		NextObservation: []float64{float64(step + 1)},
+		Terminated:      terminated,
+		Truncated:       truncated,
+		Steps:           1,
+	}
+}
+
+func TestNStepReturns(t *testing.T) {
+	s := NewNStep(3, 0.5)
+	if got := s.Add(stepTransition(0, 1, false, false)); len(got) != 0 {
+		t.Fatalf("got %d transitions after 1 step, want 0", len(got))
+	}
+	s.Add(stepTransition(1, 2, false, false))
+	got := s.Add(stepTransition(2, 4, false, false))
+	if len(got) != 1 {
+		t.Fatalf("got %d transitions after 3 steps, want 1", len(got))
+	}
+	want := Transition{Observation: []float64{0}, NextObservation: []float64{3}, Reward: 1 + 0.5*2 + 0.25*4, Steps: 3}
+	checkTransition(t, "full", got[0], want)
+
+	got = s.Add(stepTransition(3, 8, false, false))
+	want = Transition{Observation: []float64{1}, NextObservation: []float64{4}, Reward: 2 + 0.5*4 + 0.25*8, Steps: 3}
+	checkTransition(t, "sliding", got[0], want)
+}
+
+func TestNStepStopsAtEpisodeEnd(t *testing.T) {
+	for _, terminated := range []bool{true, false} {
+		name := "terminated"
+		if !terminated {
+			name = "truncated"
+		}
+		s := NewNStep(3, 0.5)
+		s.Add(stepTransition(0, 1, false, false))
+		got := s.Add(stepTransition(1, 2, terminated, !terminated))
+		if len(got) != 2 {
+			t.Fatalf("%s: got %d transitions, want 2", name, len(got))
+		}
+		checkTransition(t, name, got[0], Transition{Observation: []float64{0}, NextObservation: []float64{2}, Reward: 1 + 0.5*2, Steps: 2, Terminated: terminated, Truncated: !terminated})
+		checkTransition(t, name, got[1], Transition{Observation: []float64{1}, NextObservation: []float64{2}, Reward: 2, Steps: 1, Terminated: terminated, Truncated: !terminated})
+
+		// A terminated transition must not be bootstrapped from, but a truncated one must be, with gamma to the power of its steps.
+		r := NewReplay(2, 1, 1)
+		for _, tr := range got {
+			r.Add(tr)
+		}
+		b := NewBatch(2, 1, 1)
+		for row := range b.Indices {
+			r.fill(b, row, row)
+		}
+		wantDiscounts := []float64{0.25, 0.5}
+		if terminated {
+			wantDiscounts = []float64{0, 0}
+		}
+		for i, d := range b.Discounts(0.5) {
+			if d != wantDiscounts[i] {
+				t.Errorf("%s: discount %d is %v, want %v", name, i, d, wantDiscounts[i])
+			}
+		}
+
+		// The next episode must start from scratch.
+		if got := s.Add(stepTransition(10, 1, false, false)); len(got) != 0 {
+			t.Errorf("%s: got %d transitions at the start of the next episode, want 0", name, len(got))
+		}
+	}
+}
+
+func checkTransition(t *testing.T, name string, got, want Transition) {
+	t.Helper()
+	if got.Observation[0] != want.Observation[0] || got.NextObservation[0] != want.NextObservation[0] ||
+		math.Abs(got.Reward-want.Reward) > 1e-12 || got.Steps != want.Steps ||
+		got.Terminated != want.Terminated || got.Truncated != want.Truncated {
+		t.Errorf("%s: got %+v, want %+v", name, got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package buffer
+
+import (
+	"math"
+
+	"github.com/JoshPattman/gym"
+)
+
+// PrioritisedReplay is a replay buffer that samples transitions in proportion to their priority, as in Prioritized Experience Replay (Schaul et al. 2016).
+// New transitions are given the highest priority seen so far, so they are likely to be sampled soon after being added.
+// The priorities should be updated with UpdatePriorities after each training step, usually with the absolute TD errors.
+type PrioritisedReplay struct {
+	*Replay
+	// How strongly sampling is prioritised. 0 is uniform sampling, and 1 is fully proportional to priority.
+	Alpha float64
+	// How strongly the importance sampling weights correct for the prioritised sampling. This is usually annealed up to 1 over training.
+	Beta float64
+	// A small value added to every priority, so that every transition has some chance of being sampled.
+	Epsilon float64
+
+	tree        *sumTree
+	maxPriority float64
+}
+
+// NewPrioritisedReplay creates an empty prioritised replay buffer that holds up to capacity transitions.
+func NewPrioritisedReplay(capacity, observationLength, actionLength int, alpha, beta float64) *PrioritisedReplay {
+	return &PrioritisedReplay{
+		Replay:      NewReplay(capacity, observationLength, actionLength),
+		Alpha:       alpha,
+		Beta:        beta,
+		Epsilon:     1e-6,
+		tree:        newSumTree(capacity),
+		maxPriority: 1,
+	}
+}
+
+// Add copies a transition into the buffer with the max priority, and returns the index it was stored at.
+func (p *PrioritisedReplay) Add(t Transition) int {
+	i := p.Replay.Add(t)
+	p.tree.set(i, p.maxPriority)
+	return i
+}
+
+// AddStep adds a 1-step transition with the max priority. See NewTransition.
+func (p *PrioritisedReplay) AddStep(observation, action []float64, step gym.StepData, truncated bool) int {
+	return p.Add(NewTransition(observation, action, step, truncated))
+}
+
+// Priority gets the priority of the transition at index i, after Alpha has been applied.
+func (p *PrioritisedReplay) Priority(i int) float64 {
+	return p.tree.get(i)
+}
+
+// UpdatePriorities sets the priority of each transition in indices. Negative priorities are made positive.
+func (p *PrioritisedReplay) UpdatePriorities(indices []int, priorities []float64) {
+	if len(indices) != len(priorities) {
+		panic("indices and priorities must have the same length")
+	}
+	for j, i := range indices {
+		if i < 0 || i >= p.size {
+			panic("replay index out of range")
+		}
+		priority := math.Pow(math.Abs(priorities[j])+p.Epsilon, p.Alpha)
+		p.tree.set(i, priority)
+		p.maxPriority = math.Max(p.maxPriority, priority)
+	}
+}
+
+// Sample samples n transitions in proportion to their priority into a new batch.
+func (p *PrioritisedReplay) Sample(n int) *Batch {
+	b := NewBatch(n, p.obsLen, p.actLen)
+	p.SampleInto(b)
+	return b
+}
+
+// SampleInto samples b.Size transitions in proportion to their priority, overwriting the contents of b. This does not allocate.
+// Sampling is stratified: the total priority is split into b.Size equal segments, and one transition is sampled from each.
+// The weights are normalised so that the largest possible weight is 1.
+func (p *PrioritisedReplay) SampleInto(b *Batch) {
+	p.checkBatch(b)
+	total := p.tree.total()
+	segment := total / float64(b.Size)
+	maxWeight := math.Pow(float64(p.size)*p.tree.min()/total, -p.Beta)
+	for row := 0; row < b.Size; row++ {
+		i := p.tree.find((float64(row) + p.float64()) * segment)
+		if i >= p.size {
+			// Rounding can push the prefix past the last leaf.
+			i = p.size - 1
+		}
+		p.fill(b, row, i)
+		b.Weights[row] = math.Pow(float64(p.size)*p.tree.get(i)/total, -p.Beta) / maxWeight
+	}
+}
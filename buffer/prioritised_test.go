@@ -0,0 +1,66 @@
+package buffer
+
+import (
+	"math"
+	"testing"
+)
+
+// Creates a prioritised buffer with Epsilon 0 holding one transition for each priority, with the transition's reward set to its index.
+func newTestPrioritisedReplay(priorities []float64, alpha, beta float64) *PrioritisedReplay {
+	p := NewPrioritisedReplay(len(priorities), 1, 1, alpha, beta)
+	p.Epsilon = 0
+	p.Seed(1)
+	indices := make([]int, len(priorities))
+	for i := range priorities {
+		indices[i] = p.Add(Transition{Observation: []float64{0}, Action: []float64{0}, NextObservation: []float64{0}, Reward: float64(i)})
+	}
+	p.UpdatePriorities(indices, priorities)
+	return p
+}
+
+func TestPrioritisedSamplingFrequencies(t *testing.T) {
+	priorities := []float64{1, 2, 3, 4}
+	p := newTestPrioritisedReplay(priorities, 1, 1)
+	counts := make([]int, len(priorities))
+	b := NewBatch(10, 1, 1)
+	const batches = 20000
+	for i := 0; i < batches; i++ {
+		p.SampleInto(b)
+		for row, index := range b.Indices {
+			if b.Rewards[row] != float64(index) {
+				t.Fatalf("row %d has index %d but the reward of transition %v", row, index, b.Rewards[row])
+			}
+			counts[index]++
+		}
+	}
+	for i, count := range counts {
+		got, want := float64(count)/(batches*10), priorities[i]/10
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("transition %d was sampled %.4f of the time, want %.4f", i, got, want)
+		}
+	}
+}
+
+func TestPrioritisedAlpha(t *testing.T) {
+	p := newTestPrioritisedReplay([]float64{4, 9}, 0.5, 1)
+	if p.Priority(0) != 2 || p.Priority(1) != 3 {
+		t.Errorf("priorities are %v and %v, want 2 and 3", p.Priority(0), p.Priority(1))
+	}
+}
+
+func TestPrioritisedImportanceWeights(t *testing.T) {
+	priorities := []float64{1, 2, 3, 4}
+	for _, beta := range []float64{0, 0.5, 1} {
+		p := newTestPrioritisedReplay(priorities, 1, beta)
+		// The weight of transition i is (N * P(i)) ^ -beta, normalised by the largest weight, which belongs to the lowest priority.
+		want := func(i int) float64 {
+			return math.Pow(4*priorities[i]/10, -beta) / math.Pow(4*0.1, -beta)
+		}
+		b := p.Sample(100)
+		for row, index := range b.Indices {
+			if math.Abs(b.Weights[row]-want(index)) > 1e-12 {
+				t.Errorf("beta %v: transition %d has weight %v, want %v", beta, index, b.Weights[row], want(index))
+			}
+		}
+	}
+}
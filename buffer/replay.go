@@ -0,0 +1,261 @@
+// Package buffer provides experience replay buffers for off-policy training on gym environments.
+package buffer
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/JoshPattman/gym"
+)
+
+// Transition is a single experience, from taking Action after seeing Observation.
+type Transition struct {
+	Observation []float64
+	Action      []float64
+	// The (possibly discounted sum of) reward received. For n-step transitions this is the discounted return over Steps env steps.
+	Reward float64
+	// The observation after the transition. If Terminated is false, the value of this observation should be bootstrapped from.
+	NextObservation []float64
+	// True if the episode ended because the env terminated. The value of NextObservation should then be treated as 0.
+	Terminated bool
+	// True if the episode was cut short by something other than the env, such as a time limit. The value of NextObservation should still be bootstrapped from.
+	Truncated bool
+	// The number of env steps covered by the transition. 0 is treated as 1.
+	Steps int
+}
+
+// NewTransition creates a 1-step transition from the observation an action was taken in, and the step data that the env returned.
+// The observation is usually from the ResetData or previous StepData.
+func NewTransition(observation, action []float64, step gym.StepData, truncated bool) Transition {
+	return Transition{
+		Observation:     observation,
+		Action:          action,
+		Reward:          step.Reward,
+		NextObservation: step.Observation,
+		Terminated:      step.Terminated,
+		Truncated:       truncated,
+		Steps:           1,
+	}
+}
+
+// Replay is a fixed capacity ring buffer of transitions, sampled uniformly. Once it is full, the oldest transitions are overwritten.
+// Transitions are copied into flat preallocated slices, so adding does not allocate.
+type Replay struct {
+	obsLen   int
+	actLen   int
+	capacity int
+
+	observations     []float64
+	actions          []float64
+	nextObservations []float64
+	rewards          []float64
+	terminated       []bool
+	steps            []int
+
+	next int
+	size int
+	rng  *rand.Rand
+}
+
+// NewReplay creates an empty replay buffer that holds up to capacity transitions with the given observation and action lengths.
+func NewReplay(capacity, observationLength, actionLength int) *Replay {
+	if capacity < 1 {
+		panic("replay capacity must be at least 1")
+	}
+	return &Replay{
+		obsLen:           observationLength,
+		actLen:           actionLength,
+		capacity:         capacity,
+		observations:     make([]float64, capacity*observationLength),
+		actions:          make([]float64, capacity*actionLength),
+		nextObservations: make([]float64, capacity*observationLength),
+		rewards:          make([]float64, capacity),
+		terminated:       make([]bool, capacity),
+		steps:            make([]int, capacity),
+	}
+}
+
+// Seed makes sampling reproducible. Until it is called, the global math/rand functions are used.
+func (r *Replay) Seed(seed int64) {
+	r.rng = rand.New(rand.NewSource(seed))
+}
+
+// Len gets the number of transitions in the buffer.
+func (r *Replay) Len() int {
+	return r.size
+}
+
+// Cap gets the max number of transitions in the buffer.
+func (r *Replay) Cap() int {
+	return r.capacity
+}
+
+// ObservationLength gets the length of the observations in the buffer.
+func (r *Replay) ObservationLength() int {
+	return r.obsLen
+}
+
+// ActionLength gets the length of the actions in the buffer.
+func (r *Replay) ActionLength() int {
+	return r.actLen
+}
+
+// Add copies a transition into the buffer, and returns the index it was stored at.
+func (r *Replay) Add(t Transition) int {
+	if len(t.Observation) != r.obsLen || len(t.NextObservation) != r.obsLen {
+		panic("transition has wrong observation length")
+	}
+	if len(t.Action) != r.actLen {
+		panic("transition has wrong action length")
+	}
+	i := r.next
+	copy(r.observations[i*r.obsLen:(i+1)*r.obsLen], t.Observation)
+	copy(r.actions[i*r.actLen:(i+1)*r.actLen], t.Action)
+	copy(r.nextObservations[i*r.obsLen:(i+1)*r.obsLen], t.NextObservation)
+	r.rewards[i] = t.Reward
+	r.terminated[i] = t.Terminated
+	r.steps[i] = t.Steps
+	if r.steps[i] < 1 {
+		r.steps[i] = 1
+	}
+	r.next = (r.next + 1) % r.capacity
+	if r.size < r.capacity {
+		r.size++
+	}
+	return i
+}
+
+// AddStep adds a 1-step transition. See NewTransition.
+func (r *Replay) AddStep(observation, action []float64, step gym.StepData, truncated bool) int {
+	return r.Add(NewTransition(observation, action, step, truncated))
+}
+
+// Get gets the transition stored at index i. The slices are copies, so can be kept.
+// Truncated is always false, as truncation is already accounted for by Terminated being false.
+func (r *Replay) Get(i int) Transition {
+	if i < 0 || i >= r.size {
+		panic("replay index out of range")
+	}
+	return Transition{
+		Observation:     append([]float64(nil), r.observations[i*r.obsLen:(i+1)*r.obsLen]...),
+		Action:          append([]float64(nil), r.actions[i*r.actLen:(i+1)*r.actLen]...),
+		Reward:          r.rewards[i],
+		NextObservation: append([]float64(nil), r.nextObservations[i*r.obsLen:(i+1)*r.obsLen]...),
+		Terminated:      r.terminated[i],
+		Steps:           r.steps[i],
+	}
+}
+
+// Sample samples n transitions uniformly, with replacement, into a new batch.
+func (r *Replay) Sample(n int) *Batch {
+	b := NewBatch(n, r.obsLen, r.actLen)
+	r.SampleInto(b)
+	return b
+}
+
+// SampleInto samples b.Size transitions uniformly, with replacement, overwriting the contents of b. This does not allocate.
+func (r *Replay) SampleInto(b *Batch) {
+	r.checkBatch(b)
+	for row := 0; row < b.Size; row++ {
+		r.fill(b, row, r.intn(r.size))
+		b.Weights[row] = 1
+	}
+}
+
+func (r *Replay) checkBatch(b *Batch) {
+	if r.size == 0 {
+		panic("cannot sample from an empty replay buffer")
+	}
+	if b.ObservationLength != r.obsLen || b.ActionLength != r.actLen {
+		panic("batch shape does not match replay buffer")
+	}
+}
+
+// Copies the transition at index i into row of the batch.
+func (r *Replay) fill(b *Batch, row, i int) {
+	copy(b.Observation(row), r.observations[i*r.obsLen:(i+1)*r.obsLen])
+	copy(b.Action(row), r.actions[i*r.actLen:(i+1)*r.actLen])
+	copy(b.NextObservation(row), r.nextObservations[i*r.obsLen:(i+1)*r.obsLen])
+	b.Rewards[row] = r.rewards[i]
+	b.Terminated[row] = r.terminated[i]
+	b.Steps[row] = r.steps[i]
+	b.Indices[row] = i
+}
+
+func (r *Replay) intn(n int) int {
+	if r.rng == nil {
+		return rand.Intn(n)
+	}
+	return r.rng.Intn(n)
+}
+
+func (r *Replay) float64() float64 {
+	if r.rng == nil {
+		return rand.Float64()
+	}
+	return r.rng.Float64()
+}
+
+// Batch is a set of sampled transitions, stored as flat row-major slabs so they can be passed straight to a network.
+// Row i of Observations is Observations[i*ObservationLength : (i+1)*ObservationLength], and similarly for Actions and NextObservations.
+type Batch struct {
+	Size              int
+	ObservationLength int
+	ActionLength      int
+
+	Observations     []float64
+	Actions          []float64
+	Rewards          []float64
+	NextObservations []float64
+	Terminated       []bool
+	Steps            []int
+
+	// The buffer index of each sampled transition, for updating priorities.
+	Indices []int
+	// The importance sampling weight of each sampled transition. These are all 1 for uniform sampling.
+	Weights []float64
+}
+
+// NewBatch creates an empty batch with room for size transitions.
+func NewBatch(size, observationLength, actionLength int) *Batch {
+	return &Batch{
+		Size:              size,
+		ObservationLength: observationLength,
+		ActionLength:      actionLength,
+		Observations:      make([]float64, size*observationLength),
+		Actions:           make([]float64, size*actionLength),
+		Rewards:           make([]float64, size),
+		NextObservations:  make([]float64, size*observationLength),
+		Terminated:        make([]bool, size),
+		Steps:             make([]int, size),
+		Indices:           make([]int, size),
+		Weights:           make([]float64, size),
+	}
+}
+
+// Observation gets row i of Observations. This is a view, not a copy.
+func (b *Batch) Observation(i int) []float64 {
+	return b.Observations[i*b.ObservationLength : (i+1)*b.ObservationLength]
+}
+
+// Action gets row i of Actions. This is a view, not a copy.
+func (b *Batch) Action(i int) []float64 {
+	return b.Actions[i*b.ActionLength : (i+1)*b.ActionLength]
+}
+
+// NextObservation gets row i of NextObservations. This is a view, not a copy.
+func (b *Batch) NextObservation(i int) []float64 {
+	return b.NextObservations[i*b.ObservationLength : (i+1)*b.ObservationLength]
+}
+
+// Discounts gets the factor to multiply the value of each next observation by when computing targets.
+// This is gamma to the power of the number of steps, or 0 if the transition terminated.
+func (b *Batch) Discounts(gamma float64) []float64 {
+	discounts := make([]float64, b.Size)
+	for i := range discounts {
+		if !b.Terminated[i] {
+			discounts[i] = math.Pow(gamma, float64(b.Steps[i]))
+		}
+	}
+	return discounts
+}
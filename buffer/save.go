@@ -0,0 +1,199 @@
+package buffer
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// The version of the saved buffer format. This should be increased whenever savedReplay changes.
+const savedReplayVersion = 1
+
+// savedReplay is the on-disk form of a replay buffer. It is encoded with encoding/gob.
+type savedReplay struct {
+	Version           int
+	ObservationLength int
+	ActionLength      int
+	Capacity          int
+	Next              int
+	Size              int
+	Observations      []float64
+	Actions           []float64
+	NextObservations  []float64
+	Rewards           []float64
+	Terminated        []bool
+	Steps             []int
+
+	// These are only set for prioritised buffers.
+	Prioritised bool
+	Priorities  []float64
+	MaxPriority float64
+	Alpha       float64
+	Beta        float64
+	Epsilon     float64
+}
+
+func (r *Replay) toSaved() *savedReplay {
+	return &savedReplay{
+		Version:           savedReplayVersion,
+		ObservationLength: r.obsLen,
+		ActionLength:      r.actLen,
+		Capacity:          r.capacity,
+		Next:              r.next,
+		Size:              r.size,
+		Observations:      r.observations,
+		Actions:           r.actions,
+		NextObservations:  r.nextObservations,
+		Rewards:           r.rewards,
+		Terminated:        r.terminated,
+		Steps:             r.steps,
+	}
+}
+
+func (s *savedReplay) toReplay() (*Replay, error) {
+	if s.Version != savedReplayVersion {
+		return nil, fmt.Errorf("unsupported replay buffer version %d", s.Version)
+	}
+	// Check everything that NewReplay, Add and Sample rely on, so that a corrupt file gives an error now rather than a panic later.
+	switch {
+	case s.Capacity < 1:
+		return nil, fmt.Errorf("corrupt replay buffer: capacity %d is less than 1", s.Capacity)
+	case s.ObservationLength < 0 || s.ActionLength < 0:
+		return nil, fmt.Errorf("corrupt replay buffer: negative observation or action length")
+	case s.Next < 0 || s.Next >= s.Capacity:
+		return nil, fmt.Errorf("corrupt replay buffer: next index %d is outside of 0 to %d", s.Next, s.Capacity-1)
+	case s.Size < 0 || s.Size > s.Capacity:
+		return nil, fmt.Errorf("corrupt replay buffer: size %d is outside of 0 to %d", s.Size, s.Capacity)
+	}
+	r := NewReplay(s.Capacity, s.ObservationLength, s.ActionLength)
+	if len(s.Observations) != len(r.observations) || len(s.Actions) != len(r.actions) || len(s.NextObservations) != len(r.nextObservations) ||
+		len(s.Rewards) != s.Capacity || len(s.Terminated) != s.Capacity || len(s.Steps) != s.Capacity {
+		return nil, fmt.Errorf("corrupt replay buffer: data does not match its shape")
+	}
+	r.observations = s.Observations
+	r.actions = s.Actions
+	r.nextObservations = s.NextObservations
+	r.rewards = s.Rewards
+	r.terminated = s.Terminated
+	r.steps = s.Steps
+	r.next = s.Next
+	r.size = s.Size
+	return r, nil
+}
+
+func decodeSaved(reader io.Reader) (*savedReplay, error) {
+	s := &savedReplay{}
+	if err := gob.NewDecoder(reader).Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the contents of the buffer to w. The random seed is not saved.
+func (r *Replay) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(r.toSaved())
+}
+
+// LoadReplay reads a buffer written by Replay.Save.
+func LoadReplay(reader io.Reader) (*Replay, error) {
+	s, err := decodeSaved(reader)
+	if err != nil {
+		return nil, err
+	}
+	if s.Prioritised {
+		return nil, fmt.Errorf("buffer is prioritised, use LoadPrioritisedReplay")
+	}
+	return s.toReplay()
+}
+
+// Save writes the contents and priorities of the buffer to w. The random seed is not saved.
+func (p *PrioritisedReplay) Save(w io.Writer) error {
+	s := p.Replay.toSaved()
+	s.Prioritised = true
+	s.Priorities = make([]float64, p.capacity)
+	for i := range s.Priorities {
+		s.Priorities[i] = p.tree.get(i)
+	}
+	s.MaxPriority = p.maxPriority
+	s.Alpha, s.Beta, s.Epsilon = p.Alpha, p.Beta, p.Epsilon
+	return gob.NewEncoder(w).Encode(s)
+}
+
+// LoadPrioritisedReplay reads a buffer written by PrioritisedReplay.Save.
+func LoadPrioritisedReplay(reader io.Reader) (*PrioritisedReplay, error) {
+	s, err := decodeSaved(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Prioritised {
+		return nil, fmt.Errorf("buffer is not prioritised, use LoadReplay")
+	}
+	r, err := s.toReplay()
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Priorities) != s.Capacity {
+		return nil, fmt.Errorf("corrupt replay buffer: wrong number of priorities")
+	}
+	for _, priority := range s.Priorities[:s.Size] {
+		if !(priority >= 0) || math.IsInf(priority, 0) {
+			return nil, fmt.Errorf("corrupt replay buffer: priority %v is not a finite, non-negative number", priority)
+		}
+	}
+	p := &PrioritisedReplay{
+		Replay:      r,
+		Alpha:       s.Alpha,
+		Beta:        s.Beta,
+		Epsilon:     s.Epsilon,
+		tree:        newSumTree(s.Capacity),
+		maxPriority: s.MaxPriority,
+	}
+	for i := 0; i < s.Size; i++ {
+		p.tree.set(i, s.Priorities[i])
+	}
+	return p, nil
+}
+
+// SaveFile saves the buffer to a file, overwriting it if it exists.
+func (r *Replay) SaveFile(path string) error {
+	return saveFile(path, r.Save)
+}
+
+// SaveFile saves the buffer to a file, overwriting it if it exists.
+func (p *PrioritisedReplay) SaveFile(path string) error {
+	return saveFile(path, p.Save)
+}
+
+// LoadReplayFile loads a buffer saved with Replay.SaveFile.
+func LoadReplayFile(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadReplay(f)
+}
+
+// LoadPrioritisedReplayFile loads a buffer saved with PrioritisedReplay.SaveFile.
+func LoadPrioritisedReplayFile(path string) (*PrioritisedReplay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadPrioritisedReplay(f)
+}
+
+func saveFile(path string, save func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
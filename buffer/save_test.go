@@ -0,0 +1,121 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+// Fills a buffer past its capacity, so that it has wrapped around.
+func fillTestReplay(add func(Transition) int, n int) {
+	for i := 0; i < n; i++ {
+		add(Transition{
+			Observation:     []float64{float64(i), -float64(i)},
+			Action:          []float64{float64(i) / 10},
+			Reward:          float64(i) * 2,
+			NextObservation: []float64{float64(i + 1), -float64(i + 1)},
+			Terminated:      i%3 == 0,
+			Steps:           i%2 + 1,
+		})
+	}
+}
+
+func checkSameReplay(t *testing.T, got, want *Replay) {
+	t.Helper()
+	if got.Len() != want.Len() || got.Cap() != want.Cap() || got.ObservationLength() != want.ObservationLength() || got.ActionLength() != want.ActionLength() {
+		t.Fatalf("loaded buffer has len %d cap %d, want len %d cap %d", got.Len(), got.Cap(), want.Len(), want.Cap())
+	}
+	for i := 0; i < want.Len(); i++ {
+		if !reflect.DeepEqual(got.Get(i), want.Get(i)) {
+			t.Errorf("transition %d is %+v, want %+v", i, got.Get(i), want.Get(i))
+		}
+	}
+}
+
+func TestReplaySaveLoad(t *testing.T) {
+	r := NewReplay(4, 2, 1)
+	fillTestReplay(r.Add, 6)
+	var buf bytes.Buffer
+	if err := r.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameReplay(t, loaded, r)
+	// The loaded buffer must overwrite the same slot as the original.
+	fillTestReplay(r.Add, 1)
+	fillTestReplay(loaded.Add, 1)
+	checkSameReplay(t, loaded, r)
+}
+
+func TestPrioritisedReplaySaveLoad(t *testing.T) {
+	p := NewPrioritisedReplay(4, 2, 1, 0.6, 0.4)
+	fillTestReplay(p.Add, 3)
+	p.UpdatePriorities([]int{0, 2}, []float64{5, 0.5})
+	var buf bytes.Buffer
+	if err := p.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadReplay(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("LoadReplay loaded a prioritised buffer")
+	}
+	loaded, err := LoadPrioritisedReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameReplay(t, loaded.Replay, p.Replay)
+	for i := 0; i < p.Len(); i++ {
+		if loaded.Priority(i) != p.Priority(i) {
+			t.Errorf("priority %d is %v, want %v", i, loaded.Priority(i), p.Priority(i))
+		}
+	}
+	if loaded.Alpha != p.Alpha || loaded.Beta != p.Beta || loaded.Epsilon != p.Epsilon || loaded.maxPriority != p.maxPriority {
+		t.Errorf("loaded settings %v %v %v %v, want %v %v %v %v", loaded.Alpha, loaded.Beta, loaded.Epsilon, loaded.maxPriority, p.Alpha, p.Beta, p.Epsilon, p.maxPriority)
+	}
+}
+
+func TestLoadCorruptReplay(t *testing.T) {
+	valid := func() *savedReplay {
+		p := NewPrioritisedReplay(4, 2, 1, 0.6, 0.4)
+		fillTestReplay(p.Add, 2)
+		var buf bytes.Buffer
+		p.Save(&buf)
+		s, _ := decodeSaved(&buf)
+		return s
+	}
+	cases := []struct {
+		name    string
+		corrupt func(s *savedReplay)
+	}{
+		{"ZeroCapacity", func(s *savedReplay) { s.Capacity = 0 }},
+		{"NegativeNext", func(s *savedReplay) { s.Next = -1 }},
+		{"NextPastEnd", func(s *savedReplay) { s.Next = s.Capacity }},
+		{"NegativeSize", func(s *savedReplay) { s.Size = -1 }},
+		{"SizePastEnd", func(s *savedReplay) { s.Size = s.Capacity + 1 }},
+		{"ShortRewards", func(s *savedReplay) { s.Rewards = s.Rewards[:1] }},
+		{"NegativePriority", func(s *savedReplay) { s.Priorities[0] = -1 }},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := valid()
+			c.corrupt(s)
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+			if _, err := LoadPrioritisedReplay(bytes.NewReader(data)); err == nil {
+				t.Error("LoadPrioritisedReplay did not return an error")
+			}
+			s.Prioritised = false
+			buf.Reset()
+			gob.NewEncoder(&buf).Encode(s)
+			if _, err := LoadReplay(&buf); err == nil && c.name != "NegativePriority" {
+				t.Error("LoadReplay did not return an error")
+			}
+		})
+	}
+}
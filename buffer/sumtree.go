@@ -0,0 +1,66 @@
+package buffer
+
+import "math"
+
+// sumTree is a complete binary tree over a fixed number of leaf values, where each node holds the sum and min of its children.
+// It supports updating a leaf, and finding the leaf at a given prefix sum, in O(log n).
+type sumTree struct {
+	leaves int
+	sums   []float64
+	mins   []float64
+}
+
+func newSumTree(n int) *sumTree {
+	leaves := 1
+	for leaves < n {
+		leaves *= 2
+	}
+	t := &sumTree{
+		leaves: leaves,
+		sums:   make([]float64, 2*leaves),
+		mins:   make([]float64, 2*leaves),
+	}
+	for i := range t.mins {
+		t.mins[i] = math.Inf(1)
+	}
+	return t
+}
+
+// Sets the value of leaf i, and updates every node above it.
+func (t *sumTree) set(i int, v float64) {
+	node := i + t.leaves
+	t.sums[node] = v
+	t.mins[node] = v
+	for node > 1 {
+		node /= 2
+		t.sums[node] = t.sums[2*node] + t.sums[2*node+1]
+		t.mins[node] = math.Min(t.mins[2*node], t.mins[2*node+1])
+	}
+}
+
+func (t *sumTree) get(i int) float64 {
+	return t.sums[i+t.leaves]
+}
+
+func (t *sumTree) total() float64 {
+	return t.sums[1]
+}
+
+// Gets the smallest leaf value that has been set.
+func (t *sumTree) min() float64 {
+	return t.mins[1]
+}
+
+// Finds the leaf where the running sum of leaf values first exceeds prefix.
+func (t *sumTree) find(prefix float64) int {
+	node := 1
+	for node < t.leaves {
+		if prefix < t.sums[2*node] || t.sums[2*node+1] == 0 {
+			node = 2 * node
+		} else {
+			prefix -= t.sums[2*node]
+			node = 2*node + 1
+		}
+	}
+	return node - t.leaves
+}
@@ -0,0 +1,51 @@
+package buffer
+
+import "testing"
+
+func TestSumTree(t *testing.T) {
+	// 5 leaves is not a power of two, so the tree has 3 unused leaves which must never be found.
+	tree := newSumTree(5)
+	for i, v := range []float64{1, 2, 3, 4, 5} {
+		tree.set(i, v)
+	}
+	if tree.total() != 15 {
+		t.Errorf("total is %v, want 15", tree.total())
+	}
+	if tree.min() != 1 {
+		t.Errorf("min is %v, want 1", tree.min())
+	}
+	// The leaves cover the prefix sums [0, 1), [1, 3), [3, 6), [6, 10) and [10, 15).
+	cases := []struct {
+		prefix float64
+		want   int
+	}{
+		{0, 0}, {0.99, 0}, {1, 1}, {2.99, 1}, {3, 2}, {5.99, 2}, {6, 3}, {9.99, 3}, {10, 4}, {14.99, 4}, {15, 4},
+	}
+	for _, c := range cases {
+		if got := tree.find(c.prefix); got != c.want {
+			t.Errorf("find(%v) is %d, want %d", c.prefix, got, c.want)
+		}
+	}
+
+	// Updating a leaf must update the sums and mins above it.
+	tree.set(0, 10)
+	tree.set(3, 0.5)
+	if tree.total() != 20.5 {
+		t.Errorf("total after update is %v, want 20.5", tree.total())
+	}
+	if tree.min() != 0.5 {
+		t.Errorf("min after update is %v, want 0.5", tree.min())
+	}
+	if tree.get(0) != 10 || tree.get(3) != 0.5 {
+		t.Errorf("leaves are %v and %v, want 10 and 0.5", tree.get(0), tree.get(3))
+	}
+	// The leaves now cover [0, 10), [10, 12), [12, 15), [15, 15.5) and [15.5, 20.5).
+	for _, c := range []struct {
+		prefix float64
+		want   int
+	}{{9.99, 0}, {10, 1}, {15.2, 3}, {15.5, 4}} {
+		if got := tree.find(c.prefix); got != c.want {
+			t.Errorf("after update, find(%v) is %d, want %d", c.prefix, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Command gymbench runs the benchmark suite from the bench package and prints a table of the results.
+// It runs the same benchmarks as go test -bench . ./bench.
+// Results can be saved as JSON and used as a baseline for later runs, to catch changes that make an env slower.
+//
+// Usage:
+//
+//	gymbench [-run regexp] [-benchtime 1s] [-save results.json] [-baseline results.json] [-threshold 0.1]
+//
+// When a baseline is given, gymbench prints the change in each benchmark and exits with status 1 if any of them regressed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/JoshPattman/gym/bench"
+)
+
+func main() {
+	// The testing flags must be registered so that -benchtime can be passed on to testing.Benchmark.
+	testing.Init()
+	run := flag.String("run", "", "only run benchmarks whose names match this regexp")
+	benchtime := flag.String("benchtime", "1s", "how long to run each benchmark for, or how many iterations, such as 100x")
+	save := flag.String("save", "", "write the results to this JSON file")
+	baseline := flag.String("baseline", "", "compare the results with this JSON file, written by an earlier run with -save")
+	threshold := flag.Float64("threshold", 0.1, "the relative slowdown that counts as a regression")
+	flag.Parse()
+	if err := flag.Set("test.benchtime", *benchtime); err != nil {
+		fail(fmt.Errorf("bad -benchtime: %v", err))
+	}
+
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			fail(fmt.Errorf("bad -run: %v", err))
+		}
+	}
+	var base []bench.Result
+	if *baseline != "" {
+		var err error
+		if base, err = bench.LoadResultsFile(*baseline); err != nil {
+			fail(err)
+		}
+	}
+
+	results := bench.Run(bench.Suite(), filter, os.Stderr)
+	if len(results) == 0 {
+		fail(fmt.Errorf("no benchmarks match %q", *run))
+	}
+	fmt.Println()
+	bench.WriteTable(os.Stdout, results)
+	if *save != "" {
+		if err := bench.SaveResultsFile(*save, results); err != nil {
+			fail(err)
+		}
+	}
+	if base != nil {
+		comparisons := bench.Compare(base, results, *threshold)
+		fmt.Println()
+		bench.WriteComparison(os.Stdout, comparisons)
+		for _, c := range comparisons {
+			if c.Regressed {
+				fmt.Fprintln(os.Stderr, "gymbench: some benchmarks regressed")
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "gymbench:", err)
+	os.Exit(1)
+}
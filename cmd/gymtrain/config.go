@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Config describes a single training run. It is read from a JSON or YAML experiment file.
+type Config struct {
+	// The name of the run, which is used to name the run directory. Defaults to the name of the experiment file.
+	// It may only contain letters, digits, '.', '_' and '-', so that the run directory is always directly inside OutDir.
+	Name string `json:"name"`
+	// The ID of the env to train on, such as "CartPole". See envIDs for every ID.
+	Env string `json:"env"`
+	// Overrides for the env's default settings, keyed by the Go field names of its settings struct.
+	EnvSettings json.RawMessage `json:"env_settings"`
+	// The training algorithm, such as "ppo". See algorithms for every algorithm.
+	Algorithm string `json:"algorithm"`
+	// Overrides for the algorithm's default settings, keyed by the Go field names of its settings struct.
+	Hyperparameters json.RawMessage `json:"hyperparameters"`
+	// If true, envs that have categorical actions are still trained with continuous actions.
+	Continuous bool `json:"continuous"`
+	// The sizes of the hidden layers of the policy network, for algorithms that use one.
+	Hidden []int `json:"hidden"`
+	// The number of bins (tabular algorithms) or tiles (linear_sarsa) per observation dimension,
+	// and the range of each dimension they cover. The range defaults to -1 to 1.
+	Bins    int       `json:"bins"`
+	Tilings int       `json:"tilings"`
+	Low     []float64 `json:"low"`
+	High    []float64 `json:"high"`
+
+	Seed int64 `json:"seed"`
+	// Training stops after this many env steps.
+	Steps int `json:"steps"`
+	// The number of env copies, for algorithms that use more than one.
+	NumEnvs int `json:"num_envs"`
+	// Episodes are truncated after this many steps, both in training and in evaluation.
+	MaxEpisodeSteps int `json:"max_episode_steps"`
+
+	// The policy is evaluated and checkpointed after about this many env steps.
+	EvalEvery    int `json:"eval_every"`
+	EvalEpisodes int `json:"eval_episodes"`
+	// If true, every evaluation episode is recorded as a GIF.
+	Videos      bool `json:"videos"`
+	VideoWidth  int  `json:"video_width"`
+	VideoHeight int  `json:"video_height"`
+
+	// The directory that the run directory is created in.
+	OutDir string `json:"out_dir"`
+	// The formats that metrics are logged in: "csv" (metrics.csv), "jsonl" (metrics.jsonl) and "tensorboard" (event files in tensorboard/).
+	Loggers []string `json:"loggers"`
+}
+
+var defaultConfig = Config{
+	Hidden:          []int{32, 32},
+	Bins:            6,
+	Tilings:         8,
+	Seed:            1,
+	Steps:           100000,
+	NumEnvs:         4,
+	MaxEpisodeSteps: 1000,
+	EvalEvery:       10000,
+	EvalEpisodes:    5,
+	VideoWidth:      300,
+	VideoHeight:     300,
+	OutDir:          "runs",
+	Loggers:         []string{"csv"},
+}
+
+// loadConfig reads an experiment file. Files ending in .yaml or .yml are parsed as YAML, and anything else as JSON.
+// Fields that are not in the file keep their default values, and unknown fields are an error.
+func loadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		value, err := parseYAML(data)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %v", path, err)
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return Config{}, err
+		}
+	}
+	cfg := defaultConfig
+	cfg.Hidden = append([]int{}, defaultConfig.Hidden...)
+	cfg.Loggers = append([]string{}, defaultConfig.Loggers...)
+	if err := decodeStrict(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if cfg.Name == "" {
+		cfg.Name = invalidNameChars.ReplaceAllString(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "_")
+	}
+	return cfg, cfg.validate()
+}
+
+// A run name must be usable as a single directory name on every platform.
+var (
+	validName        = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+)
+
+func (c Config) validate() error {
+	switch {
+	case !validName.MatchString(c.Name) || strings.Trim(c.Name, ".") == "":
+		return fmt.Errorf("name %q must only contain letters, digits, '.', '_' and '-', and must not be only dots", c.Name)
+	case c.Env == "":
+		return fmt.Errorf("env must be set")
+	case c.Algorithm == "":
+		return fmt.Errorf("algorithm must be set")
+	case c.Steps < 1:
+		return fmt.Errorf("steps must be at least 1")
+	case c.NumEnvs < 1:
+		return fmt.Errorf("num_envs must be at least 1")
+	case c.EvalEpisodes < 1:
+		return fmt.Errorf("eval_episodes must be at least 1")
+	case len(c.Low) != len(c.High):
+		return fmt.Errorf("low and high must have the same length")
+	}
+	for _, name := range c.Loggers {
+		if _, ok := loggers[name]; !ok {
+			return fmt.Errorf("unknown logger %q, must be one of %s", name, strings.Join(sortedKeys(loggers), ", "))
+		}
+	}
+	return nil
+}
+
+// decodeStrict decodes JSON into v, failing on fields that v does not have. Empty data leaves v unchanged.
+func decodeStrict(data []byte, v interface{}) error {
+	if len(bytes.TrimSpace(data)) == 0 || string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
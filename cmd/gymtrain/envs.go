@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/JoshPattman/gym"
+)
+
+// envIDs maps each env ID to a function that creates an env factory from the settings overrides in the config.
+// The overrides are decoded on top of the env's default settings before any env is created, so a bad override is reported straight away.
+var envIDs = map[string]func(overrides json.RawMessage) (func() gym.Env, error){
+	"CartPole": func(overrides json.RawMessage) (func() gym.Env, error) {
+		settings := gym.DefaultCartPoleSettings
+		err := decodeStrict(overrides, &settings)
+		return func() gym.Env { return gym.NewCartPoleEnv(settings) }, err
+	},
+	"BallPush": func(overrides json.RawMessage) (func() gym.Env, error) {
+		settings := *gym.DefaultBallPushSettings
+		err := decodeStrict(overrides, &settings)
+		return func() gym.Env { s := settings; return gym.NewBallPushEnv(&s) }, err
+	},
+	"Walker": func(overrides json.RawMessage) (func() gym.Env, error) {
+		settings := gym.DefaultWalkerSettings
+		err := decodeStrict(overrides, &settings)
+		return func() gym.Env { return gym.NewWalkerEnv(settings) }, err
+	},
+	"Pong": func(overrides json.RawMessage) (func() gym.Env, error) {
+		settings := gym.DefaultPongSettings
+		err := decodeStrict(overrides, &settings)
+		return func() gym.Env { return gym.NewPongEnv(settings) }, err
+	},
+	"Rope": func(overrides json.RawMessage) (func() gym.Env, error) {
+		settings := gym.DefaultRopeSettings
+		err := decodeStrict(overrides, &settings)
+		return func() gym.Env { return gym.NewRopeEnv(settings) }, err
+	},
+}
+
+// makeEnvFactory creates a factory for the env with the given ID and settings overrides.
+func makeEnvFactory(id string, overrides json.RawMessage) (func() gym.Env, error) {
+	makeFactory, ok := envIDs[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown env %q, must be one of %s", id, strings.Join(sortedKeys(envIDs), ", "))
+	}
+	factory, err := makeFactory(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("bad env_settings for %s: %v", id, err)
+	}
+	return factory, nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var _ gym.Seedable = &countingEnv{}
+
+// countingEnv counts every step taken in an env, so that training can be measured in env steps no matter which algorithm is used.
+// The counter is shared between every copy of an env, and is safe to use from several goroutines.
+type countingEnv struct {
+	gym.Env
+	steps *atomic.Int64
+}
+
+// Step implements gym.Env.
+func (e *countingEnv) Step(action []float64) gym.StepData {
+	e.steps.Add(1)
+	return e.Env.Step(action)
+}
+
+// Seed implements gym.Seedable. It does nothing if the wrapped env is not Seedable.
+func (e *countingEnv) Seed(seed int64) {
+	if seedable, ok := e.Env.(gym.Seedable); ok {
+		seedable.Seed(seed)
+	}
+}
@@ -0,0 +1,178 @@
+// Command gymtrain trains a policy on one of the gym envs, as described by an experiment file, without writing any Go.
+//
+// Usage:
+//
+//	gymtrain [-out dir] experiment.yaml
+//
+// The experiment file is JSON, or YAML if it ends in .yaml or .yml. For example:
+//
+//	name: cartpole-ppo
+//	env: CartPole
+//	env_settings:
+//	  MaxInitialAngle: 0.2
+//	algorithm: ppo
+//	hyperparameters:
+//	  ActorLearningRate: 0.001
+//	hidden: [32, 32]
+//	seed: 1
+//	steps: 200000
+//	videos: true
+//	loggers: [csv, tensorboard]
+//
+// See Config for every field. Each run writes to a new directory, out_dir/name-time, which holds:
+//
+//	config.json      the full config, including defaults
+//	metrics.csv      one row per training iteration, with evaluation results on the rows where the policy was evaluated
+//	metrics.jsonl    the same metrics as JSON lines, if loggers includes "jsonl"
+//	tensorboard/     the same metrics as a TensorBoard event file, if loggers includes "tensorboard"
+//	checkpoints/     the policy at every evaluation and at the end (final.gob), encoded with encoding/gob
+//	videos/          GIFs of every evaluation episode, if videos is true
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/JoshPattman/gym"
+)
+
+func main() {
+	outDir := flag.String("out", "", "the directory to create the run directory in, overriding out_dir in the experiment file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gymtrain [-out dir] experiment.yaml\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg, err := loadConfig(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gymtrain:", err)
+		os.Exit(1)
+	}
+	if *outDir != "" {
+		cfg.OutDir = *outDir
+	}
+	if err := train(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "gymtrain:", err)
+		os.Exit(1)
+	}
+}
+
+// The columns of metrics.csv, after the step. Cells are left empty when the metric does not apply to a row.
+var metricColumns = []string{
+	"iteration", "wall_time", "train_return", "train_episodes", "policy_loss", "value_loss", "entropy", "mean_fitness", "max_fitness", "epsilon",
+	"eval_return", "eval_return_std", "eval_length", "eval_success_rate",
+}
+
+// loggers maps each logger name to a function that creates the logger in a run directory.
+var loggers = map[string]func(runDir string) (gym.Logger, error){
+	"csv": func(runDir string) (gym.Logger, error) {
+		f, err := os.Create(filepath.Join(runDir, "metrics.csv"))
+		if err != nil {
+			return nil, err
+		}
+		return gym.NewCSVLogger(f, metricColumns...), nil
+	},
+	"jsonl": func(runDir string) (gym.Logger, error) {
+		f, err := os.Create(filepath.Join(runDir, "metrics.jsonl"))
+		if err != nil {
+			return nil, err
+		}
+		return gym.NewJSONLLogger(f), nil
+	},
+	"tensorboard": func(runDir string) (gym.Logger, error) {
+		return gym.NewTensorBoardLoggerDir(filepath.Join(runDir, "tensorboard"))
+	},
+}
+
+func train(cfg Config) error {
+	factory, err := makeEnvFactory(cfg.Env, cfg.EnvSettings)
+	if err != nil {
+		return err
+	}
+	r := &run{cfg: cfg, factory: factory, steps: &atomic.Int64{}}
+	t, err := makeTrainer(r)
+	if err != nil {
+		return err
+	}
+
+	runDir := filepath.Join(cfg.OutDir, fmt.Sprintf("%s-%s", cfg.Name, time.Now().Format("20060102-150405")))
+	if err := os.MkdirAll(filepath.Join(runDir, "checkpoints"), 0o755); err != nil {
+		return err
+	}
+	configJSON, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "config.json"), configJSON, 0o644); err != nil {
+		return err
+	}
+	var logger gym.MultiLogger
+	defer func() { logger.Close() }()
+	for _, name := range cfg.Loggers {
+		l, err := loggers[name](runDir)
+		if err != nil {
+			return err
+		}
+		logger = append(logger, l)
+	}
+	fmt.Printf("training %s on %s, writing to %s\n", cfg.Algorithm, cfg.Env, runDir)
+
+	evalEnv := factory()
+	start := time.Now()
+	nextEval := cfg.EvalEvery
+	for iteration := 0; ; iteration++ {
+		values := t.iterate()
+		steps := int(r.steps.Load())
+		done := steps >= cfg.Steps
+		if done || (cfg.EvalEvery > 0 && steps >= nextEval) {
+			for nextEval <= steps {
+				nextEval += max(1, cfg.EvalEvery)
+			}
+			result, err := evaluate(cfg, t, evalEnv, runDir, steps)
+			if err != nil {
+				return err
+			}
+			values["eval_return"] = result.MeanReturn
+			values["eval_return_std"] = result.StdReturn
+			values["eval_length"] = result.MeanLength
+			if result.HasSuccess {
+				values["eval_success_rate"] = result.SuccessRate
+			}
+			if err := t.save(filepath.Join(runDir, "checkpoints", fmt.Sprintf("step_%09d.gob", steps))); err != nil {
+				return err
+			}
+			fmt.Printf("step %d (%s): %s\n", steps, time.Since(start).Round(time.Second), result)
+		}
+		values["iteration"] = float64(iteration)
+		values["wall_time"] = time.Since(start).Seconds()
+		if err := logger.Log(steps, values); err != nil {
+			return err
+		}
+		if done {
+			return t.save(filepath.Join(runDir, "checkpoints", "final.gob"))
+		}
+	}
+}
+
+// evaluate runs the current policy for EvalEpisodes episodes, recording videos if they are enabled.
+func evaluate(cfg Config, t trainer, env gym.Env, runDir string, steps int) (gym.EvaluationResult, error) {
+	settings := gym.DefaultEvaluateSettings
+	settings.MaxEpisodeSteps = cfg.MaxEpisodeSteps
+	if cfg.Videos {
+		settings.VideoDir = filepath.Join(runDir, "videos", fmt.Sprintf("step_%09d", steps))
+		settings.VideoWidth, settings.VideoHeight = cfg.VideoWidth, cfg.VideoHeight
+		if err := os.MkdirAll(settings.VideoDir, 0o755); err != nil {
+			return gym.EvaluationResult{}, err
+		}
+	}
+	return gym.EvaluateWithSettings(env, t.policy(), cfg.EvalEpisodes, cfg.Seed, settings)
+}
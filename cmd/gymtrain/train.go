@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/JoshPattman/gym"
+	"github.com/JoshPattman/gym/agents"
+)
+
+// trainer is one training algorithm, set up for a run.
+type trainer interface {
+	// iterate runs one iteration of training, such as one PPO update or one episode, and returns its metrics.
+	iterate() map[string]float64
+	// policy gets the current policy, for evaluation.
+	policy() gym.Policy
+	// save writes a checkpoint of the current policy to a file.
+	save(path string) error
+}
+
+// run holds everything that an algorithm needs to create its trainer.
+type run struct {
+	cfg     Config
+	factory func() gym.Env
+	// Counts the steps taken in every env created by newEnv.
+	steps *atomic.Int64
+}
+
+// newEnv creates an env whose steps are counted.
+func (r *run) newEnv() gym.Env {
+	return &countingEnv{Env: r.factory(), steps: r.steps}
+}
+
+// newEnvs creates NumEnvs envs whose steps are counted.
+func (r *run) newEnvs() []gym.Env {
+	envs := make([]gym.Env, r.cfg.NumEnvs)
+	for i := range envs {
+		envs[i] = r.newEnv()
+	}
+	return envs
+}
+
+// categorical decides whether the env should be trained with categorical actions.
+func (r *run) categorical(env gym.Env) bool {
+	return !r.cfg.Continuous && supportsCategorical(env)
+}
+
+// supportsCategorical checks if an env has categorical actions. Envs without them panic in NumCategoricalActions.
+func supportsCategorical(env gym.Env) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return env.NumCategoricalActions() > 0
+}
+
+// bounds gets the range of each observation dimension covered by the tabular and linear algorithms.
+func (r *run) bounds(env gym.Env) ([]float64, []float64, error) {
+	n := env.ObservationLength()
+	if len(r.cfg.Low) == 0 {
+		low, high := make([]float64, n), make([]float64, n)
+		for i := range low {
+			low[i], high[i] = -1, 1
+		}
+		return low, high, nil
+	}
+	if len(r.cfg.Low) != n {
+		return nil, nil, fmt.Errorf("low and high must have one value for each of the %d observation dimensions", n)
+	}
+	return r.cfg.Low, r.cfg.High, nil
+}
+
+// algorithms maps each algorithm name to a function that creates its trainer.
+var algorithms = map[string]func(r *run) (trainer, error){
+	"ppo":          newPPOTrainer,
+	"reinforce":    newReinforceTrainer,
+	"cem":          newEvolutionTrainer,
+	"cmaes":        newEvolutionTrainer,
+	"es":           newEvolutionTrainer,
+	"qlearning":    newTabularTrainer,
+	"sarsa":        newTabularTrainer,
+	"linear_sarsa": newTabularTrainer,
+}
+
+func makeTrainer(r *run) (trainer, error) {
+	newTrainer, ok := algorithms[r.cfg.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown algorithm %q, must be one of %s", r.cfg.Algorithm, strings.Join(sortedKeys(algorithms), ", "))
+	}
+	return newTrainer(r)
+}
+
+func newActorCritic(r *run, env gym.Env) *agents.ActorCritic {
+	if r.categorical(env) {
+		return agents.NewCategoricalActorCritic(env, r.cfg.Hidden, r.cfg.Seed)
+	}
+	return agents.NewActorCritic(env, r.cfg.Hidden, r.cfg.Seed)
+}
+
+// Adds the metrics of a policy gradient update.
+func updateMetrics(result agents.UpdateResult) map[string]float64 {
+	m := map[string]float64{
+		"policy_loss": result.PolicyLoss,
+		"value_loss":  result.ValueLoss,
+		"entropy":     result.Entropy,
+	}
+	if result.Episodes > 0 {
+		m["train_return"] = result.MeanReturn
+		m["train_episodes"] = float64(result.Episodes)
+	}
+	return m
+}
+
+type ppoTrainer struct {
+	ppo *agents.PPO
+}
+
+func newPPOTrainer(r *run) (trainer, error) {
+	settings := agents.DefaultPPOSettings
+	settings.MaxEpisodeSteps = r.cfg.MaxEpisodeSteps
+	if err := decodeStrict(r.cfg.Hyperparameters, &settings); err != nil {
+		return nil, fmt.Errorf("bad hyperparameters for ppo: %v", err)
+	}
+	envs := r.newEnvs()
+	seedEnvs(envs, r.cfg.Seed)
+	return &ppoTrainer{agents.NewPPO(newActorCritic(r, envs[0]), settings, r.cfg.Seed, envs...)}, nil
+}
+
+func (t *ppoTrainer) iterate() map[string]float64 { return updateMetrics(t.ppo.Update()) }
+func (t *ppoTrainer) policy() gym.Policy          { return t.ppo.Policy }
+func (t *ppoTrainer) save(path string) error      { return saveGob(path, t.ppo.Policy) }
+
+type reinforceTrainer struct {
+	reinforce *agents.Reinforce
+	env       gym.Env
+}
+
+func newReinforceTrainer(r *run) (trainer, error) {
+	settings := agents.DefaultReinforceSettings
+	settings.MaxEpisodeSteps = r.cfg.MaxEpisodeSteps
+	if err := decodeStrict(r.cfg.Hyperparameters, &settings); err != nil {
+		return nil, fmt.Errorf("bad hyperparameters for reinforce: %v", err)
+	}
+	env := r.newEnv()
+	seedEnvs([]gym.Env{env}, r.cfg.Seed)
+	return &reinforceTrainer{agents.NewReinforce(newActorCritic(r, env), settings), env}, nil
+}
+
+func (t *reinforceTrainer) iterate() map[string]float64 {
+	return updateMetrics(t.reinforce.Update(t.env))
+}
+func (t *reinforceTrainer) policy() gym.Policy     { return t.reinforce.Policy }
+func (t *reinforceTrainer) save(path string) error { return saveGob(path, t.reinforce.Policy) }
+
+type evolutionTrainer struct {
+	evolution *agents.Evolution
+}
+
+func newEvolutionTrainer(r *run) (trainer, error) {
+	envs := r.newEnvs()
+	var policy *agents.MLPPolicy
+	if r.categorical(envs[0]) {
+		policy = agents.NewCategoricalMLPPolicy(envs[0], r.cfg.Hidden, r.cfg.Seed)
+	} else {
+		policy = agents.NewMLPPolicy(envs[0], r.cfg.Hidden, r.cfg.Seed)
+	}
+	evolutionSettings := agents.DefaultEvolutionSettings
+	evolutionSettings.MaxEpisodeSteps = r.cfg.MaxEpisodeSteps
+	evolutionSettings.Seed = r.cfg.Seed
+
+	// The hyperparameters hold the settings of both the optimiser and the evolution trainer.
+	var optimiser agents.Optimiser
+	var err error
+	switch r.cfg.Algorithm {
+	case "cem":
+		settings := struct {
+			agents.CEMSettings
+			agents.EvolutionSettings
+		}{agents.DefaultCEMSettings, evolutionSettings}
+		err = decodeStrict(r.cfg.Hyperparameters, &settings)
+		optimiser, evolutionSettings = agents.NewCEM(policy.Net.Params, settings.CEMSettings, r.cfg.Seed), settings.EvolutionSettings
+	case "cmaes":
+		settings := struct {
+			agents.CMAESSettings
+			agents.EvolutionSettings
+		}{agents.DefaultCMAESSettings, evolutionSettings}
+		err = decodeStrict(r.cfg.Hyperparameters, &settings)
+		optimiser, evolutionSettings = agents.NewCMAES(policy.Net.Params, settings.CMAESSettings, r.cfg.Seed), settings.EvolutionSettings
+	case "es":
+		settings := struct {
+			agents.ESSettings
+			agents.EvolutionSettings
+		}{agents.DefaultESSettings, evolutionSettings}
+		err = decodeStrict(r.cfg.Hyperparameters, &settings)
+		optimiser, evolutionSettings = agents.NewES(policy.Net.Params, settings.ESSettings, r.cfg.Seed), settings.EvolutionSettings
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bad hyperparameters for %s: %v", r.cfg.Algorithm, err)
+	}
+	return &evolutionTrainer{agents.NewEvolution(optimiser, policy, evolutionSettings, envs...)}, nil
+}
+
+func (t *evolutionTrainer) iterate() map[string]float64 {
+	result := t.evolution.Step()
+	return map[string]float64{
+		"mean_fitness": result.MeanFitness,
+		"max_fitness":  result.MaxFitness,
+	}
+}
+func (t *evolutionTrainer) policy() gym.Policy     { return t.evolution.Policy }
+func (t *evolutionTrainer) save(path string) error { return t.evolution.SaveFile(path) }
+
+type tabularTrainer struct {
+	learner  agents.Learner
+	env      gym.Env
+	maxSteps int
+	// The data written to checkpoints.
+	checkpoint func() interface{}
+	epsilon    func() float64
+}
+
+func newTabularTrainer(r *run) (trainer, error) {
+	settings := agents.DefaultTabularSettings
+	if err := decodeStrict(r.cfg.Hyperparameters, &settings); err != nil {
+		return nil, fmt.Errorf("bad hyperparameters for %s: %v", r.cfg.Algorithm, err)
+	}
+	env := r.newEnv()
+	seedEnvs([]gym.Env{env}, r.cfg.Seed)
+	if !supportsCategorical(env) {
+		return nil, fmt.Errorf("%s needs an env with categorical actions", r.cfg.Algorithm)
+	}
+	low, high, err := r.bounds(env)
+	if err != nil {
+		return nil, err
+	}
+	bins := make([]int, len(low))
+	for i := range bins {
+		bins[i] = r.cfg.Bins
+	}
+	t := &tabularTrainer{env: env, maxSteps: r.cfg.MaxEpisodeSteps}
+	switch r.cfg.Algorithm {
+	case "qlearning":
+		q := agents.NewQLearning(env, agents.NewDiscretiser(low, high, bins), settings, r.cfg.Seed)
+		t.learner, t.epsilon = q, func() float64 { return q.Settings.Epsilon }
+		t.checkpoint = func() interface{} { return struct{ Q []float64 }{q.Q} }
+	case "sarsa":
+		s := agents.NewSARSA(env, agents.NewDiscretiser(low, high, bins), settings, r.cfg.Seed)
+		t.learner, t.epsilon = s, func() float64 { return s.Settings.Epsilon }
+		t.checkpoint = func() interface{} { return struct{ Q []float64 }{s.Q} }
+	case "linear_sarsa":
+		l := agents.NewLinearSARSA(env, agents.NewTileCoder(low, high, bins, r.cfg.Tilings), settings, r.cfg.Seed)
+		t.learner, t.epsilon = l, func() float64 { return l.Settings.Epsilon }
+		t.checkpoint = func() interface{} { return l }
+	}
+	return t, nil
+}
+
+func (t *tabularTrainer) iterate() map[string]float64 {
+	ret := t.learner.TrainEpisode(t.env, t.maxSteps)
+	return map[string]float64{
+		"train_return":   ret,
+		"train_episodes": 1,
+		"epsilon":        t.epsilon(),
+	}
+}
+func (t *tabularTrainer) policy() gym.Policy     { return gym.FromCategorical(t.env, t.learner) }
+func (t *tabularTrainer) save(path string) error { return saveGob(path, t.checkpoint()) }
+
+// seedEnvs gives each env a different seed derived from the run's seed, if it is Seedable.
+func seedEnvs(envs []gym.Env, seed int64) {
+	for i, env := range envs {
+		if seedable, ok := env.(gym.Seedable); ok {
+			seedable.Seed(seed + int64(i)*1000003)
+		}
+	}
+}
+
+// saveGob writes v to a file with encoding/gob, overwriting it if it exists.
+func saveGob(path string, v interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
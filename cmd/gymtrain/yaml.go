@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML that experiment files need: nested block mappings, block and flow lists, scalars and comments.
+// Anchors, multi-line strings and flow mappings are not supported. Mappings are returned as map[string]interface{} and lists as []interface{},
+// so the result can be re-encoded as JSON.
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := strings.TrimRight(stripYAMLComment(raw), " \t")
+		trimmed := strings.TrimLeft(text, " \t")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.Contains(text[:len(text)-len(trimmed)], "\t") {
+			return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(text) - len(trimmed), text: trimmed, num: i + 1})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	value, err := p.parseNode(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+type yamlLine struct {
+	indent int
+	text   string
+	num    int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// Parses the mapping or list starting at the current line, which must have the given indent.
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	if isYAMLListItem(p.lines[p.pos].text) {
+		return p.parseList(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isYAMLListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", line.num)
+		}
+		if _, exists := m[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+		if rest != "" {
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line.num, err)
+			}
+			m[key] = value
+			continue
+		}
+		// A key with no value is followed by a nested block, which is either indented further or is a list at the same indent.
+		switch {
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			value, err := p.parseNode(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLListItem(p.lines[p.pos].text):
+			value, err := p.parseList(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		default:
+			m[key] = nil
+		}
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseList(indent int) (interface{}, error) {
+	list := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		switch {
+		case rest == "":
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				list = append(list, nil)
+				continue
+			}
+			value, err := p.parseNode(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		case isYAMLMapEntry(rest):
+			// The item is a mapping whose first key is on the same line as the dash. The line is rewritten as that key, indented to match the keys that follow it.
+			p.lines[p.pos] = yamlLine{indent: line.indent + len(line.text) - len(rest), text: rest, num: line.num}
+			value, err := p.parseMap(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		default:
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line.num, err)
+			}
+			list = append(list, value)
+			p.pos++
+		}
+	}
+	return list, nil
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func isYAMLMapEntry(text string) bool {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") || strings.HasPrefix(text, "[") {
+		return false
+	}
+	_, _, ok := splitYAMLKey(text)
+	return ok
+}
+
+// Splits 'key: value' or 'key:' into the key and the value.
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasSuffix(text, ":") {
+		return strings.TrimSpace(text[:len(text)-1]), "", true
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:]), true
+}
+
+// Removes a comment, which starts with a # at the start of the line or after a space, and is not inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// Splits the inside of a flow list on the commas that are not inside quotes or a nested list.
+func splitYAMLFlowList(inner string) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, inner[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, inner[start:])
+}
+
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "\""):
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("bad quoted string %s", text)
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("bad quoted string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated list %s", text)
+		}
+		list := []interface{}{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return list, nil
+		}
+		for _, item := range splitYAMLFlowList(inner) {
+			value, err := parseYAMLScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case text == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(text, "{"):
+		return nil, fmt.Errorf("flow mappings are not supported")
+	}
+	// Only true and false are booleans, as in YAML 1.2, so values such as 'no' or 'on' stay as strings.
+	switch strings.ToLower(text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "~":
+		return nil, nil
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
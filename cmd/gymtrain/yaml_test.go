@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	type m = map[string]interface{}
+	type l = []interface{}
+	cases := []struct {
+		name string
+		yaml string
+		want interface{}
+	}{
+		{"Empty", "# only a comment\n", m{}},
+		{"Scalars", "a: 1\nb: 1.5\nc: true\nd: False\ne: null\nf: ~\ng: hello world\nh: \"quoted: #1\"\ni: 'it''s'",
+			m{"a": int64(1), "b": 1.5, "c": true, "d": false, "e": nil, "f": nil, "g": "hello world", "h": "quoted: #1", "i": "it's"}},
+		{"NotBooleans", "a: yes\nb: no\nc: on\nd: off\ne: Y", m{"a": "yes", "b": "no", "c": "on", "d": "off", "e": "Y"}},
+		{"NestedMaps", "env:\n  name: CartPole\n  settings:\n    max_steps: 200\nseed: 3",
+			m{"env": m{"name": "CartPole", "settings": m{"max_steps": int64(200)}}, "seed": int64(3)}},
+		{"BlockList", "seeds:\n  - 1\n  - 2\nnames:\n- a\n- b", m{"seeds": l{int64(1), int64(2)}, "names": l{"a", "b"}}},
+		{"ListOfMaps", "runs:\n  - name: a\n    seed: 1\n  - name: b\n    env:\n      name: Pong\n",
+			m{"runs": l{m{"name": "a", "seed": int64(1)}, m{"name": "b", "env": m{"name": "Pong"}}}}},
+		{"DashThenMap", "runs:\n  -\n    name: a\n", m{"runs": l{m{"name": "a"}}}},
+		{"FlowLists", "a: []\nb: [1, 2.5, x]\nc: [[1, 2], [3]]", m{"a": l{}, "b": l{int64(1), 2.5, "x"}, "c": l{l{int64(1), int64(2)}, l{int64(3)}}}},
+		{"FlowListQuotedCommas", "tags: [\"a, b\", 'c, d', \"e\\\", f\", g]", m{"tags": l{"a, b", "c, d", "e\", f", "g"}}},
+		{"Comments", "# header\na: 1 # trailing\n\nb: \"# not a comment\"\nc: x#y # comment\n", m{"a": int64(1), "b": "# not a comment", "c": "x#y"}},
+		{"DocumentMarker", "---\na: 1", m{"a": int64(1)}},
+		{"TopLevelList", "- 1\n- 2", l{int64(1), int64(2)}},
+		{"EmptyValue", "a:\nb: 1", m{"a": nil, "b": int64(1)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(c.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{"TabIndent", "a:\n\tb: 1", "line 2: tabs"},
+		{"DuplicateKey", "a: 1\nb: 2\na: 3", "line 3: duplicate key \"a\""},
+		{"NestedDuplicateKey", "a:\n  b: 1\n  b: 2", "line 3: duplicate key \"b\""},
+		{"BadIndent", "a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"NotAMapEntry", "a: 1\nb", "line 2: expected 'key: value'"},
+		{"UnterminatedList", "a: [1, 2", "unterminated list"},
+		{"BadQuote", "a: \"abc", "bad quoted string"},
+		{"FlowMapping", "a: {b: 1}", "flow mappings are not supported"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(c.yaml))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("error %q does not contain %q", err, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package gym
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+)
+
+// TrajectoryChecksum seeds and resets the env, then takes the given number of steps, and returns a hex SHA-256 hash of every observation, reward and termination flag.
+// The env is reset whenever it terminates. The actions are categorical, each held for 10 steps and cycling through every action in turn.
+// Two envs produce the same checksum only if their trajectories are bit-for-bit identical.
+func TrajectoryChecksum(env Env, seed int64, steps int) string {
+	seedable, ok := env.(Seedable)
+	if !ok {
+		panic("env must be seedable to compute a trajectory checksum")
+	}
+	seedable.Seed(seed)
+
+	h := sha256.New()
+	var buf [8]byte
+	writeFloats := func(vals ...float64) {
+		for _, v := range vals {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			h.Write(buf[:])
+		}
+	}
+
+	writeFloats(env.Reset().Observation...)
+	for i := 0; i < steps; i++ {
+		stepData := env.Step(env.ConvertCategoricalAction((i / 10) % env.NumCategoricalActions()))
+		writeFloats(stepData.Observation...)
+		writeFloats(stepData.Reward)
+		if stepData.Terminated {
+			h.Write([]byte{1})
+			writeFloats(env.Reset().Observation...)
+		} else {
+			h.Write([]byte{0})
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
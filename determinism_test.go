@@ -0,0 +1,65 @@
+package gym
+
+import "testing"
+
+// goldenTrajectory is a trajectory of a deterministic env, along with its checksum.
+// The checksums were recorded once, and must be the same on every platform.
+type goldenTrajectory struct {
+	name     string
+	newEnv   func() Env
+	seed     int64
+	steps    int
+	checksum string
+}
+
+var goldenTrajectories = []goldenTrajectory{
+	{
+		name: "CartPole",
+		newEnv: func() Env {
+			settings := DefaultCartPoleSettings
+			settings.Deterministic = true
+			return NewCartPoleEnv(settings)
+		},
+		seed:     1,
+		steps:    2000,
+		checksum: "38078f2bf4ec66857c326e8addbd5a016a252f91b33339bc30fa7e5d1ab5a3b5",
+	},
+	{
+		name: "BallPush",
+		newEnv: func() Env {
+			settings := *DefaultBallPushSettings
+			settings.Deterministic = true
+			return NewBallPushEnv(&settings)
+		},
+		seed:     1,
+		steps:    2000,
+		checksum: "5abdec333bdb7fb639847e2468c13c8bdd74f94dfe40bfa163595a57eb21987d",
+	},
+	{
+		name: "BallPushObstacles",
+		newEnv: func() Env {
+			settings := *DefaultBallPushSettings
+			settings.Deterministic = true
+			settings.NumBalls = 3
+			settings.NumObstacles = 4
+			settings.RandomTargets = true
+			settings.TerminateOnSuccess = true
+			return NewBallPushEnv(&settings)
+		},
+		seed:     2,
+		steps:    2000,
+		checksum: "de75bbd262872eb4ab4b30f0308e234e9e54f7e7be1df68834701ca2433e65d8",
+	},
+}
+
+// TestGoldenTrajectories replays the recorded trajectories of the deterministic envs, and fails if any of them have changed.
+// If this fails on one platform but passes on another, the deterministic physics is using platform dependent maths.
+func TestGoldenTrajectories(t *testing.T) {
+	for _, g := range goldenTrajectories {
+		t.Run(g.name, func(t *testing.T) {
+			if got := TrajectoryChecksum(g.newEnv(), g.seed, g.steps); got != g.checksum {
+				t.Errorf("got checksum %s, want %s", got, g.checksum)
+			}
+		})
+	}
+}
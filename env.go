@@ -50,3 +50,118 @@ type Env interface {
 	// ObservationLength gets the length of the observation vector.
 	ObservationLength() int
 }
+
+// StepIntoEnv is implemented by envs that can step without allocating, for high-throughput training.
+type StepIntoEnv interface {
+	Env
+	// StepInto is the same as Step, but writes the result into data.
+	// The Observation slice and Info map in data are reused if possible, so they are overwritten by the next call.
+	StepInto(action []float64, data *StepData)
+}
+
+// StepInto steps the env, writing the result into data. It uses the env's StepInto method if it is a StepIntoEnv,
+// and otherwise stores the result of Step in data.
+func StepInto(env Env, action []float64, data *StepData) {
+	if e, ok := env.(StepIntoEnv); ok {
+		e.StepInto(action, data)
+		return
+	}
+	*data = env.Step(action)
+}
+
+// AgentID identifies a single agent within a MultiAgentEnv.
+type AgentID string
+
+// MultiAgentStepData is the data returned by the Step function of a MultiAgentEnv.
+type MultiAgentStepData struct {
+	// Observations maps each agent to its observation, a list of floats between -1 and 1.
+	Observations map[AgentID][]float64
+	// Rewards maps each agent to its reward for the step we just took.
+	Rewards map[AgentID]float64
+	// Terminated maps each agent to whether its episode is over.
+	Terminated map[AgentID]bool
+	// Info is a map of extra information. This is environment specific and really just for debugging.
+	Info map[string]interface{}
+}
+
+// MultiAgentResetData is the data returned by the Reset function of a MultiAgentEnv.
+type MultiAgentResetData struct {
+	// Observations maps each agent to its observation, a list of floats between -1 and 1.
+	Observations map[AgentID][]float64
+	// Info is a map of extra information. This is environment specific and really just for debugging.
+	Info map[string]interface{}
+}
+
+// MultiAgentEnv is an environment that is shared by a number of agents, each of which is identified by an AgentID.
+// All agents share the same action and observation spaces.
+type MultiAgentEnv interface {
+	// Name gets the name of the environment. E.g. 'CompetitiveBallPush'
+	Name() string
+
+	// Render renders the environment to the target.
+	// It should remember to also clear the background.
+	Render(target pixel.Target)
+	// RenderSize specifies the dimensions that the environment should be rendered at.
+	// This sets the window size.
+	RenderSize() (float64, float64)
+
+	// Agents gets the IDs of the agents currently in the environment, in a stable order.
+	Agents() []AgentID
+	// AddAgent adds a new agent to the environment. It panics if the ID is already in use.
+	AddAgent(id AgentID)
+	// RemoveAgent removes an agent from the environment. It panics if the ID is not in use.
+	RemoveAgent(id AgentID)
+
+	// Step takes an action for every agent and steps the environment one timestep forwards.
+	Step(actions map[AgentID][]float64) MultiAgentStepData
+	// Reset resets the environment.
+	Reset() MultiAgentResetData
+
+	// ConvertCategoricalAction converts a categorical action to a one-hot vector.
+	ConvertCategoricalAction(int) []float64
+	// NumCategoricalActions gets the number of categorical actions that the environment supports.
+	NumCategoricalActions() int
+	// ActionLength gets the length of the action vector of a single agent.
+	ActionLength() int
+	// ObservationLength gets the length of the observation vector of a single agent.
+	ObservationLength() int
+}
+
+// Renderable is anything that can be drawn by BeginRenderLoop. Both Env and MultiAgentEnv are Renderable.
+type Renderable interface {
+	// Name gets the name of the environment.
+	Name() string
+	// Render renders the environment to the target.
+	Render(target pixel.Target)
+	// RenderSize specifies the dimensions that the environment should be rendered at.
+	RenderSize() (float64, float64)
+}
+
+// ObservationDict is an observation split into the parts needed for goal-conditioned learning.
+// All values are between -1 and 1.
+type ObservationDict struct {
+	// Observation is everything the agent can sense, except for the goals.
+	Observation []float64
+	// AchievedGoal is the goal that the agent has currently achieved.
+	AchievedGoal []float64
+	// DesiredGoal is the goal that the agent is trying to achieve.
+	DesiredGoal []float64
+}
+
+// GoalEnv is an Env where the agent is trying to achieve a goal that can be swapped out, such as a target position.
+// This allows hindsight experience replay, where the goals of past transitions are replaced with goals that were actually achieved.
+type GoalEnv interface {
+	Env
+
+	// ObservationDict gets the structured observation of the current state. Call it after Step or Reset.
+	ObservationDict() ObservationDict
+	// ComputeReward computes the reward for having achieved a goal when the desired goal was another one.
+	// It must only depend on its arguments, so that it can be called on relabelled goals.
+	ComputeReward(achievedGoal, desiredGoal []float64, info map[string]interface{}) float64
+}
+
+// Seedable is implemented by environments whose randomness can be seeded, so that episodes can be reproduced exactly.
+type Seedable interface {
+	// Seed sets the seed used for all future randomness in the environment, such as on Reset.
+	Seed(seed int64)
+}
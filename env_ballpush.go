@@ -1,14 +1,14 @@
 package gym
 
 import (
+	"fmt"
 	"math"
-	"math/rand"
 
 	"github.com/gopxl/pixel"
 	"github.com/gopxl/pixel/imdraw"
 )
 
-var _ Env = &BallPushEnv{}
+var _ GoalEnv = &BallPushEnv{}
 
 var DefaultBallPushSettings = &BallPushSettings{
 	BallRadius:          2,
@@ -24,6 +24,18 @@ var DefaultBallPushSettings = &BallPushSettings{
 	Scale:               10,
 	TargetRadius:        3,
 	DeltaTime:           1.0 / 60.0,
+	NumBalls:            1,
+	TargetRingRadius:    25,
+	NumObstacles:        0,
+	ObstacleMinRadius:   2,
+	ObstacleMaxRadius:   6,
+	SuccessHoldSteps:    30,
+	TerminateOnSuccess:  false,
+	TimePenalty:         0,
+	RandomTargets:       false,
+	SparseGoalReward:    false,
+	Deterministic:       false,
+	DisableInfo:         false,
 }
 
 type BallPushSettings struct {
@@ -40,32 +52,128 @@ type BallPushSettings struct {
 	TargetRadius        float64
 	Scale               float64
 	DeltaTime           float64
+
+	// The number of balls. Each ball has its own target: the first ball's target is the center,
+	// and the others are evenly spaced around a ring of radius TargetRingRadius. Less than 1 is treated as 1.
+	NumBalls         int
+	TargetRingRadius float64
+	// The number of static circular obstacles, randomly placed on each reset.
+	NumObstacles int
+	// The range of radii of the obstacles.
+	ObstacleMinRadius float64
+	ObstacleMaxRadius float64
+
+	// The number of consecutive steps that every ball must stay within its target for the episode to count as a success.
+	SuccessHoldSteps int
+	// If true, the episode terminates on success.
+	TerminateOnSuccess bool
+	// A reward added on every step. This should be negative, to encourage finishing quickly.
+	TimePenalty float64
+
+	// If true, every target is moved to a random position on each reset, instead of the fixed center and ring positions.
+	RandomTargets bool
+	// If true, the reward from Step is the sparse reward from ComputeReward, instead of the shaped reward.
+	SparseGoalReward bool
+
+	// If true, the physics, rewards and resets are calculated in fixed point, so that trajectories are bit-for-bit identical on every platform.
+	// The dynamics are the same as the float64 physics, up to rounding.
+	Deterministic bool
+
+	// If true, Step and Reset return a nil Info map, so that no map is allocated on every step.
+	DisableInfo bool
 }
 
+// BallInCenter returns true if the first ball is within its target.
 func (b *BallPushEnv) BallInCenter() bool {
-	return b.Ball.Position().Len() < b.Settings.TargetRadius-b.Settings.BallRadius
+	return b.BallInTarget(0)
+}
+
+// BallInTarget returns true if the i'th ball is entirely within its target.
+func (b *BallPushEnv) BallInTarget(i int) bool {
+	return b.distance(b.Balls[i].Position(), b.Targets[i]) < b.Settings.TargetRadius-b.Settings.BallRadius
+}
+
+// IsSuccess returns true if every ball has stayed within its target for at least SuccessHoldSteps steps (and at least one step).
+func (b *BallPushEnv) IsSuccess() bool {
+	return b.StepsInTarget > 0 && b.StepsInTarget >= b.Settings.SuccessHoldSteps
 }
 
 func (b *BallPushSettings) AgentMaxSpeed() float64 {
 	return b.AgentAcceleration / b.AgentDrag
 }
 
+func (b *BallPushSettings) numBalls() int {
+	if b.NumBalls < 1 {
+		return 1
+	}
+	return b.NumBalls
+}
+
+// BallPushObstacle is a static circular obstacle in a BallPushEnv.
+type BallPushObstacle struct {
+	Center pixel.Vec
+	Radius float64
+}
+
 type BallPushEnv struct {
-	Agent           *VerletParticle
-	Ball            *VerletParticle
+	Agent *VerletParticle
+	// The first ball. This is the same as Balls[0].
+	Ball *VerletParticle
+	// All of the balls. Ball i should be pushed into Targets[i].
+	Balls   []*VerletParticle
+	Targets []pixel.Vec
+	// The obstacles for the current episode.
+	Obstacles []BallPushObstacle
+	// HasTouchedBall is true if any ball has been touched, and HasCenteredBall is true if every ball has been in its target.
 	HasTouchedBall  bool
 	HasCenteredBall bool
-	Settings        *BallPushSettings
+	// Whether each ball has been touched, and whether each ball has been in its target.
+	TouchedBalls  []bool
+	CenteredBalls []bool
+	// The number of consecutive steps that every ball has been within its target.
+	StepsInTarget int
+	Settings      *BallPushSettings
 
 	imd *imdraw.IMDraw
+	rng envRand
+
+	// Buffers that are reused by every step, so that stepping does not allocate.
+	justTouchedBalls  []bool
+	justCenteredBalls []bool
+	fixedAgent        fixedParticle
+	fixedBalls        []*fixedParticle
+	fixedTargets      []fixedVec
 }
 
 func NewBallPushEnv(settings *BallPushSettings) *BallPushEnv {
+	balls := make([]*VerletParticle, settings.numBalls())
+	targets := make([]pixel.Vec, len(balls))
+	for i := range balls {
+		balls[i] = NewVerletParticle(pixel.ZV, 1, settings.DeltaTime)
+		if i > 0 && settings.Deterministic {
+			angle := fixedTwoPi.Mul(FixedFromInt(i - 1)).Div(FixedFromInt(len(balls) - 1))
+			targets[i] = fixedVec{FixedFromFloat(settings.TargetRingRadius), 0}.Rotated(angle).Vec()
+		} else if i > 0 {
+			targets[i] = pixel.V(settings.TargetRingRadius, 0).Rotated(2 * math.Pi * float64(i-1) / float64(len(balls)-1))
+		}
+	}
 	e := &BallPushEnv{
-		Agent:    NewVerletParticle(pixel.ZV, 1, settings.DeltaTime),
-		Ball:     NewVerletParticle(pixel.ZV, 1, settings.DeltaTime),
-		Settings: settings,
-		imd:      imdraw.New(nil),
+		Agent:         NewVerletParticle(pixel.ZV, 1, settings.DeltaTime),
+		Ball:          balls[0],
+		Balls:         balls,
+		Targets:       targets,
+		TouchedBalls:  make([]bool, len(balls)),
+		CenteredBalls: make([]bool, len(balls)),
+		Settings:      settings,
+		imd:           imdraw.New(nil),
+
+		justTouchedBalls:  make([]bool, len(balls)),
+		justCenteredBalls: make([]bool, len(balls)),
+		fixedBalls:        make([]*fixedParticle, len(balls)),
+		fixedTargets:      make([]fixedVec, len(balls)),
+	}
+	for i := range e.fixedBalls {
+		e.fixedBalls[i] = &fixedParticle{}
 	}
 	e.Reset()
 	return e
@@ -78,6 +186,12 @@ func (*BallPushEnv) ActionLength() int {
 
 // ConvertCategoricalAction implements Env.
 func (*BallPushEnv) ConvertCategoricalAction(a int) []float64 {
+	return convertMoveCategoricalAction(a)
+}
+
+// Converts a categorical action into a 2D movement direction. This is shared by all of the top-down environments.
+// CAction 0 is right, 1 is left, 2 is up, 3 is down and 4 is stay still.
+func convertMoveCategoricalAction(a int) []float64 {
 	switch a {
 	case 0:
 		return []float64{1, 0}
@@ -93,6 +207,11 @@ func (*BallPushEnv) ConvertCategoricalAction(a int) []float64 {
 	panic("invalid action")
 }
 
+// Seed implements Seedable.
+func (e *BallPushEnv) Seed(seed int64) {
+	e.rng.seed(seed)
+}
+
 // Name implements Env.
 func (*BallPushEnv) Name() string {
 	return "BallPush"
@@ -110,50 +229,293 @@ func (e *BallPushEnv) ObservationLength() int {
 
 // Reset implements Env.
 func (b *BallPushEnv) Reset() ResetData {
-	b.Agent.SlideToPosition(pixel.V(0, rand.Float64()*b.Settings.BoundaryRadius*0.75).Rotated(rand.Float64() * 2 * math.Pi))
+	if b.Settings.RandomTargets {
+		for i := range b.Targets {
+			b.Targets[i] = b.randomPosition()
+		}
+	}
+
+	b.Obstacles = b.Obstacles[:0]
+	for i := 0; i < b.Settings.NumObstacles; i++ {
+		radius := b.randomRange(b.Settings.ObstacleMinRadius, b.Settings.ObstacleMaxRadius)
+		b.Obstacles = append(b.Obstacles, BallPushObstacle{
+			Center: b.randomFreePosition(radius, b.Settings.TargetRadius),
+			Radius: radius,
+		})
+	}
+
+	b.Agent.SlideToPosition(b.randomFreePosition(b.Settings.AgentRadius, 0))
 	b.Agent.SetVelocity(pixel.ZV)
 
-	b.Ball.SlideToPosition(pixel.V(0, rand.Float64()*b.Settings.BoundaryRadius*0.75).Rotated(rand.Float64() * 2 * math.Pi))
-	b.Ball.SetVelocity(pixel.ZV)
+	for i, ball := range b.Balls {
+		ball.SlideToPosition(b.randomFreePosition(b.Settings.BallRadius, 0))
+		ball.SetVelocity(pixel.ZV)
+		b.TouchedBalls[i] = false
+		b.CenteredBalls[i] = false
+	}
 
 	b.HasTouchedBall = false
 	b.HasCenteredBall = false
+	b.StepsInTarget = 0
 
 	return ResetData{
 		Observation: b.getObservation(),
-		Info:        map[string]interface{}{},
+		Info:        b.getInfo(),
 	}
 
 }
 
+// Picks a random position for a circle of the given radius that does not overlap any obstacle.
+// The circle also keeps at least targetClearance away from every target, which stops obstacles from blocking the targets.
+func (b *BallPushEnv) randomFreePosition(radius, targetClearance float64) pixel.Vec {
+	for tries := 0; ; tries++ {
+		pos := b.randomPosition()
+		free := true
+		for _, o := range b.Obstacles {
+			if b.distance(pos, o.Center) < o.Radius+radius {
+				free = false
+			}
+		}
+		for _, t := range b.Targets {
+			if targetClearance > 0 && b.distance(pos, t) < targetClearance+radius {
+				free = false
+			}
+		}
+		// If the arena is too crowded, give up and let the collisions push things apart.
+		if free || tries > 100 {
+			return pos
+		}
+	}
+}
+
+// Picks a uniformly random distance from the center and angle, within three quarters of the boundary radius.
+func (b *BallPushEnv) randomPosition() pixel.Vec {
+	if b.Settings.Deterministic {
+		dist := FixedFromFloat(b.rng.Float64()).Mul(FixedFromFloat(b.Settings.BoundaryRadius * 0.75))
+		angle := FixedFromFloat(b.rng.Float64()).Mul(fixedTwoPi)
+		return fixedVec{0, dist}.Rotated(angle).Vec()
+	}
+	return pixel.V(0, b.rng.Float64()*b.Settings.BoundaryRadius*0.75).Rotated(b.rng.Float64() * 2 * math.Pi)
+}
+
+// Picks a uniformly random number between min and max.
+func (b *BallPushEnv) randomRange(min, max float64) float64 {
+	if b.Settings.Deterministic {
+		return (FixedFromFloat(min) + FixedFromFloat(b.rng.Float64()).Mul(FixedFromFloat(max)-FixedFromFloat(min))).Float()
+	}
+	return min + b.rng.Float64()*(max-min)
+}
+
+// Gets the distance between two points, in fixed point if the env is deterministic.
+func (b *BallPushEnv) distance(u, v pixel.Vec) float64 {
+	if b.Settings.Deterministic {
+		return fixedVecFrom(u).Sub(fixedVecFrom(v)).Len().Float()
+	}
+	return u.Sub(v).Len()
+}
+
 func (b *BallPushEnv) getObservation() []float64 {
+	return b.observationInto(nil)
+}
+
+// Writes the observation into obs, reusing it if it is big enough.
+func (b *BallPushEnv) observationInto(obs []float64) []float64 {
 	// Things in the observation
 	// 1. Vector from agent to center
-	// 2. Vector from agent to ball
+	// 2. Vector from agent to first ball
 	// 3. Agent velocity
-	// 4. Ball velocity
-	return []float64{
+	// 4. First ball velocity
+	// 5. Vector from first ball to its target (only if the targets are random)
+	// For each other ball:
+	// 5. Vector from agent to ball
+	// 6. Ball velocity
+	// 7. Vector from ball to its target
+	// For each obstacle:
+	// 8. Vector from agent to obstacle
+	// 9. Obstacle radius
+	obs = append(obs[:0],
+		b.Agent.Position().X/b.Settings.BoundaryRadius,
+		b.Agent.Position().Y/b.Settings.BoundaryRadius,
+		b.Ball.Position().Sub(b.Agent.Position()).X/(2*b.Settings.BoundaryRadius),
+		b.Ball.Position().Sub(b.Agent.Position()).Y/(2*b.Settings.BoundaryRadius),
+		b.Agent.Velocity().X/b.Settings.AgentMaxSpeed(),
+		b.Agent.Velocity().Y/b.Settings.AgentMaxSpeed(),
+		b.Ball.Velocity().X/b.Settings.AgentMaxSpeed(),
+		b.Ball.Velocity().Y/b.Settings.AgentMaxSpeed(),
+	)
+	if b.Settings.RandomTargets {
+		toTarget := b.Targets[0].Sub(b.Ball.Position())
+		obs = append(obs,
+			toTarget.X/(2*b.Settings.BoundaryRadius),
+			toTarget.Y/(2*b.Settings.BoundaryRadius),
+		)
+	}
+	for i, ball := range b.Balls[1:] {
+		toBall := ball.Position().Sub(b.Agent.Position())
+		toTarget := b.Targets[i+1].Sub(ball.Position())
+		obs = append(obs,
+			toBall.X/(2*b.Settings.BoundaryRadius),
+			toBall.Y/(2*b.Settings.BoundaryRadius),
+			ball.Velocity().X/b.Settings.AgentMaxSpeed(),
+			ball.Velocity().Y/b.Settings.AgentMaxSpeed(),
+			toTarget.X/(2*b.Settings.BoundaryRadius),
+			toTarget.Y/(2*b.Settings.BoundaryRadius),
+		)
+	}
+	for _, o := range b.Obstacles {
+		toObstacle := o.Center.Sub(b.Agent.Position())
+		obs = append(obs,
+			toObstacle.X/(2*b.Settings.BoundaryRadius),
+			toObstacle.Y/(2*b.Settings.BoundaryRadius),
+			o.Radius/b.Settings.BoundaryRadius,
+		)
+	}
+	return obs
+}
+
+// ObservationDict implements GoalEnv.
+// The achieved goal is the position of every ball, and the desired goal is the position of every target.
+// The observation is the same as the flat observation, but without any vectors to the targets.
+func (b *BallPushEnv) ObservationDict() ObservationDict {
+	obs := []float64{
 		b.Agent.Position().X / b.Settings.BoundaryRadius,
 		b.Agent.Position().Y / b.Settings.BoundaryRadius,
-		b.Ball.Position().Sub(b.Agent.Position()).X / (2 * b.Settings.BoundaryRadius),
-		b.Ball.Position().Sub(b.Agent.Position()).Y / (2 * b.Settings.BoundaryRadius),
 		b.Agent.Velocity().X / b.Settings.AgentMaxSpeed(),
 		b.Agent.Velocity().Y / b.Settings.AgentMaxSpeed(),
-		b.Ball.Velocity().X / b.Settings.AgentMaxSpeed(),
-		b.Ball.Velocity().Y / b.Settings.AgentMaxSpeed(),
+	}
+	achieved := make([]float64, 0, len(b.Balls)*2)
+	desired := make([]float64, 0, len(b.Balls)*2)
+	for i, ball := range b.Balls {
+		toBall := ball.Position().Sub(b.Agent.Position())
+		obs = append(obs,
+			toBall.X/(2*b.Settings.BoundaryRadius),
+			toBall.Y/(2*b.Settings.BoundaryRadius),
+			ball.Velocity().X/b.Settings.AgentMaxSpeed(),
+			ball.Velocity().Y/b.Settings.AgentMaxSpeed(),
+		)
+		achieved = append(achieved, ball.Position().X/b.Settings.BoundaryRadius, ball.Position().Y/b.Settings.BoundaryRadius)
+		desired = append(desired, b.Targets[i].X/b.Settings.BoundaryRadius, b.Targets[i].Y/b.Settings.BoundaryRadius)
+	}
+	for _, o := range b.Obstacles {
+		toObstacle := o.Center.Sub(b.Agent.Position())
+		obs = append(obs,
+			toObstacle.X/(2*b.Settings.BoundaryRadius),
+			toObstacle.Y/(2*b.Settings.BoundaryRadius),
+			o.Radius/b.Settings.BoundaryRadius,
+		)
+	}
+	return ObservationDict{
+		Observation:  obs,
+		AchievedGoal: achieved,
+		DesiredGoal:  desired,
 	}
 }
 
+// ComputeReward implements GoalEnv.
+// The reward is 0 if every ball is within its target, and -1 otherwise.
+func (b *BallPushEnv) ComputeReward(achievedGoal, desiredGoal []float64, info map[string]interface{}) float64 {
+	for i := 0; i+1 < len(achievedGoal); i += 2 {
+		offset := pixel.V(achievedGoal[i]-desiredGoal[i], achievedGoal[i+1]-desiredGoal[i+1]).Scaled(b.Settings.BoundaryRadius)
+		if b.distance(offset, pixel.ZV) >= b.Settings.TargetRadius-b.Settings.BallRadius {
+			return -1
+		}
+	}
+	return 0
+}
+
 func (b *BallPushEnv) getInfo() map[string]interface{} {
-	return make(map[string]interface{})
+	return b.infoInto(nil)
+}
+
+// Writes the Info into info, reusing it if it is not nil.
+func (b *BallPushEnv) infoInto(info map[string]interface{}) map[string]interface{} {
+	info = reuseInfo(info, b.Settings.DisableInfo, 6)
+	if info == nil {
+		return nil
+	}
+	info[InfoIsSuccess] = b.IsSuccess()
+	info[InfoTouchedBall] = b.HasTouchedBall
+	info[InfoBallInCenter] = b.BallInCenter()
+	info[InfoBallDist] = b.distance(b.Ball.Position(), b.Targets[0])
+	info[InfoAgentBallDist] = b.distance(b.Ball.Position(), b.Agent.Position())
+	info[InfoStepsInTarget] = b.StepsInTarget
+	return info
+}
+
+// ObservationNames implements ObservationSchema. The names follow the layout of the observation, with balls and obstacles numbered from 0.
+func (b *BallPushEnv) ObservationNames() []string {
+	names := []string{
+		"agent_x", "agent_y",
+		"agent_to_ball_0_x", "agent_to_ball_0_y",
+		"agent_velocity_x", "agent_velocity_y",
+		"ball_0_velocity_x", "ball_0_velocity_y",
+	}
+	if b.Settings.RandomTargets {
+		names = append(names, "ball_0_to_target_x", "ball_0_to_target_y")
+	}
+	for i := 1; i < len(b.Balls); i++ {
+		names = append(names,
+			fmt.Sprintf("agent_to_ball_%d_x", i), fmt.Sprintf("agent_to_ball_%d_y", i),
+			fmt.Sprintf("ball_%d_velocity_x", i), fmt.Sprintf("ball_%d_velocity_y", i),
+			fmt.Sprintf("ball_%d_to_target_x", i), fmt.Sprintf("ball_%d_to_target_y", i),
+		)
+	}
+	for i := range b.Obstacles {
+		names = append(names,
+			fmt.Sprintf("agent_to_obstacle_%d_x", i), fmt.Sprintf("agent_to_obstacle_%d_y", i),
+			fmt.Sprintf("obstacle_%d_radius", i),
+		)
+	}
+	return names
+}
+
+// InfoKeys implements InfoSchema.
+func (*BallPushEnv) InfoKeys() []InfoKey {
+	return []InfoKey{
+		{InfoIsSuccess, "bool", "True if every ball has stayed within its target for SuccessHoldSteps steps."},
+		{InfoTouchedBall, "bool", "True if the agent has touched any ball this episode."},
+		{InfoBallInCenter, "bool", "True if the first ball is entirely within its target."},
+		{InfoBallDist, "float64", "The distance from the first ball to its target."},
+		{InfoAgentBallDist, "float64", "The distance from the agent to the first ball."},
+		{InfoStepsInTarget, "int", "The number of consecutive steps that every ball has been within its target."},
+	}
 }
 
 // Step implements Env.
 func (e *BallPushEnv) Step(action []float64) StepData {
+	var data StepData
+	e.StepInto(action, &data)
+	return data
+}
+
+// StepInto implements StepIntoEnv. It does not allocate once data's buffers have been created, as long as DisableInfo is set
+// and SparseGoalReward is not.
+func (e *BallPushEnv) StepInto(action []float64, data *StepData) {
 	validateAction(action, 2)
 
-	justTouchedBall := false
-	justCenteredBall := false
+	var reward float64
+	if e.Settings.Deterministic {
+		reward = e.stepFixed(action)
+	} else {
+		reward = e.stepFloat(action)
+	}
+
+	data.Info = e.infoInto(data.Info)
+	if e.Settings.SparseGoalReward {
+		goals := e.ObservationDict()
+		reward = e.ComputeReward(goals.AchievedGoal, goals.DesiredGoal, data.Info)
+	}
+
+	data.Observation = e.observationInto(data.Observation)
+	data.Reward = reward
+	data.Terminated = e.Settings.TerminateOnSuccess && e.IsSuccess()
+}
+
+// Steps the physics with float64 maths, and returns the shaped reward.
+func (e *BallPushEnv) stepFloat(action []float64) float64 {
+	justTouchedBalls, justCenteredBalls := e.justTouchedBalls, e.justCenteredBalls
+	clear(justTouchedBalls)
+	clear(justCenteredBalls)
 
 	// Ensure that going diagonally does not go faster than sideways
 	controlVec := pixel.V(action[0], action[1])
@@ -165,62 +527,199 @@ func (e *BallPushEnv) Step(action []float64) StepData {
 	agentDragForce := e.Agent.Velocity().Scaled(e.Settings.AgentDrag)
 	e.Agent.ApplyForce(agentControlForce.Sub(agentDragForce))
 
-	ballDragForce := e.Ball.Velocity().Scaled(e.Settings.BallDrag)
-	e.Ball.ApplyForce(ballDragForce.Scaled(-1))
+	for _, ball := range e.Balls {
+		ballDragForce := ball.Velocity().Scaled(e.Settings.BallDrag)
+		ball.ApplyForce(ballDragForce.Scaled(-1))
+	}
 
-	boundaryOverlap := e.Agent.Position().Len() + e.Settings.AgentRadius - e.Settings.BoundaryRadius
-	if boundaryOverlap > 0 {
-		e.Agent.SlideToPosition(e.Agent.Position().Unit().Scaled(e.Settings.BoundaryRadius - e.Settings.AgentRadius))
+	containParticle(e.Agent, e.Settings.AgentRadius, e.Settings.BoundaryRadius)
+	for _, o := range e.Obstacles {
+		excludeParticle(e.Agent, e.Settings.AgentRadius, o.Center, o.Radius)
 	}
 
-	ballOverlap := (e.Settings.AgentRadius + e.Settings.BallRadius) - e.Agent.Position().Sub(e.Ball.Position()).Len()
-	if ballOverlap > 0 {
-		if !e.HasTouchedBall {
-			justTouchedBall = true
+	for i, ball := range e.Balls {
+		if collideParticles(e.Agent, ball, e.Settings.AgentRadius, e.Settings.BallRadius) && !e.TouchedBalls[i] {
+			justTouchedBalls[i] = true
+			e.TouchedBalls[i] = true
 			e.HasTouchedBall = true
 		}
-		correctionVec := e.Agent.Position().Sub(e.Ball.Position()).Unit().Scaled(ballOverlap / 2)
-		e.Agent.SlideToPosition(e.Agent.Position().Add(correctionVec))
-		e.Ball.SlideToPosition(e.Ball.Position().Sub(correctionVec))
 	}
 
-	ballBoundaryOverlap := e.Ball.Position().Len() + e.Settings.BallRadius - e.Settings.BoundaryRadius
-	if ballBoundaryOverlap > 0 {
-		e.Ball.SlideToPosition(e.Ball.Position().Unit().Scaled(e.Settings.BoundaryRadius - e.Settings.BallRadius))
+	for i, ball := range e.Balls {
+		for _, other := range e.Balls[i+1:] {
+			collideParticles(ball, other, e.Settings.BallRadius, e.Settings.BallRadius)
+		}
+		for _, o := range e.Obstacles {
+			excludeParticle(ball, e.Settings.BallRadius, o.Center, o.Radius)
+		}
+		containParticle(ball, e.Settings.BallRadius, e.Settings.BoundaryRadius)
 	}
 
-	ballInCenter := e.BallInCenter()
-	if ballInCenter && !e.HasCenteredBall {
-		justCenteredBall = true
-		e.HasCenteredBall = true
+	e.HasCenteredBall = true
+	allInTarget := true
+	for i := range e.Balls {
+		inTarget := e.BallInTarget(i)
+		if inTarget && !e.CenteredBalls[i] {
+			justCenteredBalls[i] = true
+			e.CenteredBalls[i] = true
+		}
+		e.HasCenteredBall = e.HasCenteredBall && e.CenteredBalls[i]
+		allInTarget = allInTarget && inTarget
+	}
+	if allInTarget {
+		e.StepsInTarget++
+	} else {
+		e.StepsInTarget = 0
 	}
 
 	e.Agent.StepParticle()
-	e.Ball.StepParticle()
-
-	ballVelTowardsCenter := e.Ball.Velocity().Dot(e.Ball.Position().Unit().Scaled(-1))
-
-	reward := 0.0
-	if justTouchedBall {
-		reward += e.Settings.TouchBallReward
+	for _, ball := range e.Balls {
+		ball.StepParticle()
 	}
-	if justCenteredBall {
-		reward += e.Settings.PlaceInCenterReward
+
+	reward := e.Settings.TimePenalty
+	for i, ball := range e.Balls {
+		if justTouchedBalls[i] {
+			reward += e.Settings.TouchBallReward
+		}
+		if justCenteredBalls[i] {
+			reward += e.Settings.PlaceInCenterReward
+		}
+		ballVelTowardsTarget := ball.Velocity().Dot(e.Targets[i].Sub(ball.Position()).Unit())
+		reward += e.Settings.MoveToCenterReward * ballVelTowardsTarget * e.Settings.DeltaTime / e.Settings.BoundaryRadius // when we divide by boundary radius and delta time, we get a reward of 1 by moving all the way from the boundary to the center
 	}
-	reward += e.Settings.MoveToCenterReward * ballVelTowardsCenter * e.Settings.DeltaTime / e.Settings.BoundaryRadius // when we divide by boundary radius and delta time, we get a reward of 1 by moving all the way from the boundary to the center
 
-	if !e.HasTouchedBall {
-		agentBallDir := e.Ball.Position().Sub(e.Agent.Position()).Unit()
+	// Reward moving towards the nearest ball that has not been touched yet.
+	nearestUntouched := -1
+	for i, ball := range e.Balls {
+		if e.TouchedBalls[i] {
+			continue
+		}
+		if nearestUntouched == -1 || ball.Position().Sub(e.Agent.Position()).Len() < e.Balls[nearestUntouched].Position().Sub(e.Agent.Position()).Len() {
+			nearestUntouched = i
+		}
+	}
+	if nearestUntouched != -1 {
+		agentBallDir := e.Balls[nearestUntouched].Position().Sub(e.Agent.Position()).Unit()
 		agentVelTowardsBall := e.Agent.Velocity().Dot(agentBallDir)
 		reward += e.Settings.MoveToBallReward * agentVelTowardsBall * e.Settings.DeltaTime / (2 * e.Settings.BoundaryRadius)
 	}
 
-	return StepData{
-		Observation: e.getObservation(),
-		Reward:      reward,
-		Terminated:  false,
-		Info:        e.getInfo(),
+	return reward
+}
+
+// Steps the physics in fixed point, and returns the shaped reward.
+// This follows stepFloat exactly, but every intermediate value is a Fixed.
+func (e *BallPushEnv) stepFixed(action []float64) float64 {
+	agentRadius := FixedFromFloat(e.Settings.AgentRadius)
+	ballRadius := FixedFromFloat(e.Settings.BallRadius)
+	boundaryRadius := FixedFromFloat(e.Settings.BoundaryRadius)
+	deltaTime := FixedFromFloat(e.Settings.DeltaTime)
+
+	agent := &e.fixedAgent
+	agent.load(e.Agent)
+	balls := e.fixedBalls
+	for i, ball := range e.Balls {
+		balls[i].load(ball)
+	}
+	targets := e.fixedTargets
+	for i, t := range e.Targets {
+		targets[i] = fixedVecFrom(t)
+	}
+	justTouchedBalls, justCenteredBalls := e.justTouchedBalls, e.justCenteredBalls
+	clear(justTouchedBalls)
+	clear(justCenteredBalls)
+
+	// Ensure that going diagonally does not go faster than sideways
+	controlVec := fixedVec{FixedFromFloat(action[0]), FixedFromFloat(action[1])}
+	if controlVec.Len() > 0 {
+		controlVec = controlVec.Unit()
+	}
+
+	agentControlForce := controlVec.Scaled(FixedFromFloat(e.Settings.AgentAcceleration))
+	agentDragForce := agent.velocity.Scaled(FixedFromFloat(e.Settings.AgentDrag))
+	agent.force = agent.force.Add(agentControlForce.Sub(agentDragForce))
+
+	for _, ball := range balls {
+		ball.force = ball.force.Sub(ball.velocity.Scaled(FixedFromFloat(e.Settings.BallDrag)))
+	}
+
+	containFixedParticle(agent, agentRadius, boundaryRadius)
+	for _, o := range e.Obstacles {
+		excludeFixedParticle(agent, agentRadius, fixedVecFrom(o.Center), FixedFromFloat(o.Radius))
 	}
+
+	for i, ball := range balls {
+		if collideFixedParticles(agent, ball, agentRadius, ballRadius) && !e.TouchedBalls[i] {
+			justTouchedBalls[i] = true
+			e.TouchedBalls[i] = true
+			e.HasTouchedBall = true
+		}
+	}
+
+	for i, ball := range balls {
+		for _, other := range balls[i+1:] {
+			collideFixedParticles(ball, other, ballRadius, ballRadius)
+		}
+		for _, o := range e.Obstacles {
+			excludeFixedParticle(ball, ballRadius, fixedVecFrom(o.Center), FixedFromFloat(o.Radius))
+		}
+		containFixedParticle(ball, ballRadius, boundaryRadius)
+	}
+
+	e.HasCenteredBall = true
+	allInTarget := true
+	for i, ball := range balls {
+		inTarget := ball.current.Sub(targets[i]).Len() < FixedFromFloat(e.Settings.TargetRadius)-ballRadius
+		if inTarget && !e.CenteredBalls[i] {
+			justCenteredBalls[i] = true
+			e.CenteredBalls[i] = true
+		}
+		e.HasCenteredBall = e.HasCenteredBall && e.CenteredBalls[i]
+		allInTarget = allInTarget && inTarget
+	}
+	if allInTarget {
+		e.StepsInTarget++
+	} else {
+		e.StepsInTarget = 0
+	}
+
+	agent.step()
+	agent.store()
+	for _, ball := range balls {
+		ball.step()
+		ball.store()
+	}
+
+	reward := FixedFromFloat(e.Settings.TimePenalty)
+	for i, ball := range balls {
+		if justTouchedBalls[i] {
+			reward += FixedFromFloat(e.Settings.TouchBallReward)
+		}
+		if justCenteredBalls[i] {
+			reward += FixedFromFloat(e.Settings.PlaceInCenterReward)
+		}
+		ballVelTowardsTarget := ball.velocity.Dot(targets[i].Sub(ball.current).Unit())
+		reward += FixedFromFloat(e.Settings.MoveToCenterReward).Mul(ballVelTowardsTarget).Mul(deltaTime).Div(boundaryRadius)
+	}
+
+	// Reward moving towards the nearest ball that has not been touched yet.
+	nearestUntouched := -1
+	for i, ball := range balls {
+		if e.TouchedBalls[i] {
+			continue
+		}
+		if nearestUntouched == -1 || ball.current.Sub(agent.current).Len() < balls[nearestUntouched].current.Sub(agent.current).Len() {
+			nearestUntouched = i
+		}
+	}
+	if nearestUntouched != -1 {
+		agentBallDir := balls[nearestUntouched].current.Sub(agent.current).Unit()
+		agentVelTowardsBall := agent.velocity.Dot(agentBallDir)
+		reward += FixedFromFloat(e.Settings.MoveToBallReward).Mul(agentVelTowardsBall).Mul(deltaTime).Div(2 * boundaryRadius)
+	}
+
+	return reward.Float()
 }
 
 // RenderSize implements Env.
@@ -245,19 +744,28 @@ func (b *BallPushEnv) Render(target pixel.Target) {
 	b.imd.Push(pixel.V(b.RenderSize()).Scaled(0.5))
 	b.imd.Circle(b.Settings.BoundaryRadius*b.Settings.Scale, 3)
 
-	// Draw the target.
-	if b.BallInCenter() {
-		b.imd.Color = pixel.RGB(0.0, 0.9, 0.0)
-	} else {
-		b.imd.Color = pixel.RGB(0.0, 0.2, 0.8)
+	// Draw the obstacles.
+	b.imd.Color = pixel.RGB(0.4, 0.4, 0.4)
+	for _, o := range b.Obstacles {
+		b.imd.Push(o.Center.Scaled(b.Settings.Scale).Add(pixel.V(b.RenderSize()).Scaled(0.5)))
+		b.imd.Circle(o.Radius*b.Settings.Scale, 0)
 	}
-	b.imd.Push(pixel.V(b.RenderSize()).Scaled(0.5))
-	b.imd.Circle(b.Settings.TargetRadius*b.Settings.Scale, 3)
 
-	// Draw the ball.
-	b.imd.Color = pixel.RGB(0.0, 0.2, 0.8)
-	b.imd.Push(b.Ball.Position().Scaled(b.Settings.Scale).Add(pixel.V(b.RenderSize()).Scaled(0.5)))
-	b.imd.Circle(b.Settings.BallRadius*b.Settings.Scale, 0)
+	// Draw the targets and balls, each target in the colour of its ball.
+	for i, ball := range b.Balls {
+		col := ballPushBallColors[i%len(ballPushBallColors)]
+		if b.BallInTarget(i) {
+			b.imd.Color = pixel.RGB(0.0, 0.9, 0.0)
+		} else {
+			b.imd.Color = col
+		}
+		b.imd.Push(b.Targets[i].Scaled(b.Settings.Scale).Add(pixel.V(b.RenderSize()).Scaled(0.5)))
+		b.imd.Circle(b.Settings.TargetRadius*b.Settings.Scale, 3)
+
+		b.imd.Color = col
+		b.imd.Push(ball.Position().Scaled(b.Settings.Scale).Add(pixel.V(b.RenderSize()).Scaled(0.5)))
+		b.imd.Circle(b.Settings.BallRadius*b.Settings.Scale, 0)
+	}
 
 	// Draw the agent.
 	b.imd.Color = pixel.RGB(0.8, 0.2, 0.0)
@@ -266,3 +774,13 @@ func (b *BallPushEnv) Render(target pixel.Target) {
 
 	b.imd.Draw(target)
 }
+
+// The colours of the balls and their targets. The first ball is always blue.
+var ballPushBallColors = []pixel.RGBA{
+	pixel.RGB(0.0, 0.2, 0.8),
+	pixel.RGB(0.9, 0.8, 0.0),
+	pixel.RGB(0.8, 0.0, 0.8),
+	pixel.RGB(0.0, 0.8, 0.8),
+	pixel.RGB(1.0, 0.5, 0.7),
+	pixel.RGB(0.6, 0.4, 0.2),
+}
@@ -0,0 +1,387 @@
+package gym
+
+import (
+	"math"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/imdraw"
+)
+
+var _ MultiAgentEnv = &CompetitiveBallPushEnv{}
+
+var DefaultCompetitiveBallPushSettings = &CompetitiveBallPushSettings{
+	BallRadius:           2,
+	AgentRadius:          1,
+	AgentAcceleration:    20,
+	AgentDrag:            1.5,
+	BallDrag:             0.75,
+	BoundaryRadius:       50,
+	GoalRadius:           6,
+	MoveBallToGoalReward: 1,
+	ScoreReward:          5,
+	ConcedeReward:        -5,
+	TerminateOnScore:     true,
+	Scale:                10,
+	DeltaTime:            1.0 / 60.0,
+}
+
+// CompetitiveBallPushSettings contains all the settings for the competitive ballpush environment.
+type CompetitiveBallPushSettings struct {
+	BallRadius        float64
+	AgentRadius       float64
+	AgentAcceleration float64
+	AgentDrag         float64
+	BallDrag          float64
+	BoundaryRadius    float64
+	// The radius of each agent's goal. Goals sit against the boundary, evenly spaced around it.
+	GoalRadius float64
+	// The reward for moving the ball towards the agent's own goal. Moving the ball across the whole arena gives roughly this reward.
+	MoveBallToGoalReward float64
+	// The reward for getting the ball into the agent's own goal.
+	ScoreReward float64
+	// The reward given to every other agent when someone scores. This should be negative.
+	ConcedeReward float64
+	// If true, the episode terminates for all agents as soon as anyone scores.
+	TerminateOnScore bool
+	Scale            float64
+	DeltaTime        float64
+}
+
+func (b *CompetitiveBallPushSettings) AgentMaxSpeed() float64 {
+	return b.AgentAcceleration / b.AgentDrag
+}
+
+// CompetitiveBallPushEnv is a multi-agent version of BallPushEnv.
+// There is one ball, and each agent has its own goal. The agents compete to push the ball into their own goal.
+type CompetitiveBallPushEnv struct {
+	// Players maps each agent to the particle that it controls.
+	Players  map[AgentID]*VerletParticle
+	Ball     *VerletParticle
+	Settings *CompetitiveBallPushSettings
+	// Scorer is the agent that scored first this episode, or "" if nobody has scored yet.
+	Scorer AgentID
+
+	// The agent whose goal the ball was in after the last step, or "" if it was not in a goal.
+	// A score is only counted when the ball enters a goal, so a ball resting in a goal does not score on every step.
+	ballInGoal AgentID
+	agentOrder []AgentID
+	imd        *imdraw.IMDraw
+	rng        envRand
+}
+
+// NewCompetitiveBallPushEnv creates a new competitive ballpush environment with the given agents.
+// Use two agents for the standard two-player game.
+func NewCompetitiveBallPushEnv(settings *CompetitiveBallPushSettings, agents ...AgentID) *CompetitiveBallPushEnv {
+	e := &CompetitiveBallPushEnv{
+		Players:  make(map[AgentID]*VerletParticle),
+		Ball:     NewVerletParticle(pixel.ZV, 1, settings.DeltaTime),
+		Settings: settings,
+		imd:      imdraw.New(nil),
+	}
+	for _, id := range agents {
+		e.AddAgent(id)
+	}
+	e.Reset()
+	return e
+}
+
+// Agents implements MultiAgentEnv.
+func (e *CompetitiveBallPushEnv) Agents() []AgentID {
+	agents := make([]AgentID, len(e.agentOrder))
+	copy(agents, e.agentOrder)
+	return agents
+}
+
+// AddAgent implements MultiAgentEnv.
+// The new agent is placed randomly in the arena. The goals of all agents are re-spaced around the boundary.
+func (e *CompetitiveBallPushEnv) AddAgent(id AgentID) {
+	if _, ok := e.Players[id]; ok {
+		panic("agent already exists: " + string(id))
+	}
+	p := NewVerletParticle(pixel.ZV, 1, e.Settings.DeltaTime)
+	p.SlideToPosition(e.randomPosition())
+	p.SetVelocity(pixel.ZV)
+	e.Players[id] = p
+	e.agentOrder = append(e.agentOrder, id)
+}
+
+// RemoveAgent implements MultiAgentEnv.
+func (e *CompetitiveBallPushEnv) RemoveAgent(id AgentID) {
+	if _, ok := e.Players[id]; !ok {
+		panic("agent does not exist: " + string(id))
+	}
+	delete(e.Players, id)
+	for i, other := range e.agentOrder {
+		if other == id {
+			e.agentOrder = append(e.agentOrder[:i], e.agentOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// GoalPosition gets the center of the goal belonging to the given agent.
+func (e *CompetitiveBallPushEnv) GoalPosition(id AgentID) pixel.Vec {
+	for i, other := range e.agentOrder {
+		if other == id {
+			angle := 2 * math.Pi * float64(i) / float64(len(e.agentOrder))
+			return pixel.V(e.Settings.BoundaryRadius-e.Settings.GoalRadius, 0).Rotated(angle)
+		}
+	}
+	panic("agent does not exist: " + string(id))
+}
+
+// BallInGoal returns true if the ball is entirely within the goal of the given agent.
+func (e *CompetitiveBallPushEnv) BallInGoal(id AgentID) bool {
+	return e.Ball.Position().Sub(e.GoalPosition(id)).Len() < e.Settings.GoalRadius-e.Settings.BallRadius
+}
+
+// ActionLength implements MultiAgentEnv.
+func (*CompetitiveBallPushEnv) ActionLength() int {
+	return 2
+}
+
+// ConvertCategoricalAction implements MultiAgentEnv. The actions are the same as BallPushEnv.
+func (*CompetitiveBallPushEnv) ConvertCategoricalAction(a int) []float64 {
+	return convertMoveCategoricalAction(a)
+}
+
+// NumCategoricalActions implements MultiAgentEnv.
+func (*CompetitiveBallPushEnv) NumCategoricalActions() int {
+	return 5
+}
+
+// Seed implements Seedable.
+func (e *CompetitiveBallPushEnv) Seed(seed int64) {
+	e.rng.seed(seed)
+}
+
+// Name implements MultiAgentEnv.
+func (*CompetitiveBallPushEnv) Name() string {
+	return "CompetitiveBallPush"
+}
+
+// ObservationLength implements MultiAgentEnv.
+func (*CompetitiveBallPushEnv) ObservationLength() int {
+	return 12
+}
+
+func (e *CompetitiveBallPushEnv) randomPosition() pixel.Vec {
+	return pixel.V(0, e.rng.Float64()*e.Settings.BoundaryRadius*0.75).Rotated(e.rng.Float64() * 2 * math.Pi)
+}
+
+// Reset implements MultiAgentEnv.
+func (e *CompetitiveBallPushEnv) Reset() MultiAgentResetData {
+	for _, id := range e.agentOrder {
+		e.Players[id].SlideToPosition(e.randomPosition())
+		e.Players[id].SetVelocity(pixel.ZV)
+	}
+
+	// The ball always starts in the center so that no agent has an advantage.
+	e.Ball.SlideToPosition(pixel.ZV)
+	e.Ball.SetVelocity(pixel.ZV)
+
+	e.Scorer = ""
+	e.ballInGoal = ""
+
+	return MultiAgentResetData{
+		Observations: e.getObservations(),
+		Info:         e.getInfo(),
+	}
+}
+
+func (e *CompetitiveBallPushEnv) getObservations() map[AgentID][]float64 {
+	obs := make(map[AgentID][]float64, len(e.agentOrder))
+	for _, id := range e.agentOrder {
+		obs[id] = e.getObservation(id)
+	}
+	return obs
+}
+
+func (e *CompetitiveBallPushEnv) getObservation(id AgentID) []float64 {
+	// Things in the observation
+	// 1. Vector from agent to center
+	// 2. Vector from agent to ball
+	// 3. Vector from ball to own goal
+	// 4. Agent velocity
+	// 5. Ball velocity
+	// 6. Vector from agent to nearest opponent (zero if there are no opponents)
+	agent := e.Players[id]
+	nearestOpponent := pixel.ZV
+	nearestDist := math.Inf(1)
+	for _, other := range e.agentOrder {
+		if other == id {
+			continue
+		}
+		offset := e.Players[other].Position().Sub(agent.Position())
+		if offset.Len() < nearestDist {
+			nearestDist = offset.Len()
+			nearestOpponent = offset
+		}
+	}
+	toBall := e.Ball.Position().Sub(agent.Position())
+	toGoal := e.GoalPosition(id).Sub(e.Ball.Position())
+	return []float64{
+		agent.Position().X / e.Settings.BoundaryRadius,
+		agent.Position().Y / e.Settings.BoundaryRadius,
+		toBall.X / (2 * e.Settings.BoundaryRadius),
+		toBall.Y / (2 * e.Settings.BoundaryRadius),
+		toGoal.X / (2 * e.Settings.BoundaryRadius),
+		toGoal.Y / (2 * e.Settings.BoundaryRadius),
+		agent.Velocity().X / e.Settings.AgentMaxSpeed(),
+		agent.Velocity().Y / e.Settings.AgentMaxSpeed(),
+		e.Ball.Velocity().X / e.Settings.AgentMaxSpeed(),
+		e.Ball.Velocity().Y / e.Settings.AgentMaxSpeed(),
+		nearestOpponent.X / (2 * e.Settings.BoundaryRadius),
+		nearestOpponent.Y / (2 * e.Settings.BoundaryRadius),
+	}
+}
+
+func (e *CompetitiveBallPushEnv) getInfo() map[string]interface{} {
+	return make(map[string]interface{})
+}
+
+// Step implements MultiAgentEnv. There must be an action for every agent in the environment.
+func (e *CompetitiveBallPushEnv) Step(actions map[AgentID][]float64) MultiAgentStepData {
+	for _, id := range e.agentOrder {
+		action, ok := actions[id]
+		if !ok {
+			panic("Invalid action: missing action for agent " + string(id))
+		}
+		validateAction(action, 2)
+
+		// Ensure that going diagonally does not go faster than sideways
+		controlVec := pixel.V(action[0], action[1])
+		if controlVec.Len() > 0 {
+			controlVec = controlVec.Unit()
+		}
+
+		agent := e.Players[id]
+		agentControlForce := controlVec.Scaled(e.Settings.AgentAcceleration)
+		agentDragForce := agent.Velocity().Scaled(e.Settings.AgentDrag)
+		agent.ApplyForce(agentControlForce.Sub(agentDragForce))
+	}
+
+	ballDragForce := e.Ball.Velocity().Scaled(e.Settings.BallDrag)
+	e.Ball.ApplyForce(ballDragForce.Scaled(-1))
+
+	for i, id := range e.agentOrder {
+		agent := e.Players[id]
+		containParticle(agent, e.Settings.AgentRadius, e.Settings.BoundaryRadius)
+		for _, other := range e.agentOrder[i+1:] {
+			collideParticles(agent, e.Players[other], e.Settings.AgentRadius, e.Settings.AgentRadius)
+		}
+		collideParticles(agent, e.Ball, e.Settings.AgentRadius, e.Settings.BallRadius)
+	}
+	containParticle(e.Ball, e.Settings.BallRadius, e.Settings.BoundaryRadius)
+
+	inGoal := AgentID("")
+	for _, id := range e.agentOrder {
+		if e.BallInGoal(id) {
+			inGoal = id
+			break
+		}
+	}
+	justScored := AgentID("")
+	if inGoal != "" && inGoal != e.ballInGoal {
+		justScored = inGoal
+		if e.Scorer == "" {
+			e.Scorer = inGoal
+		}
+	}
+	e.ballInGoal = inGoal
+
+	for _, id := range e.agentOrder {
+		e.Players[id].StepParticle()
+	}
+	e.Ball.StepParticle()
+
+	rewards := make(map[AgentID]float64, len(e.agentOrder))
+	terminated := make(map[AgentID]bool, len(e.agentOrder))
+	for _, id := range e.agentOrder {
+		ballGoalDir := e.GoalPosition(id).Sub(e.Ball.Position()).Unit()
+		ballVelTowardsGoal := e.Ball.Velocity().Dot(ballGoalDir)
+		reward := e.Settings.MoveBallToGoalReward * ballVelTowardsGoal * e.Settings.DeltaTime / (2 * e.Settings.BoundaryRadius)
+		if justScored == id {
+			reward += e.Settings.ScoreReward
+		} else if justScored != "" {
+			reward += e.Settings.ConcedeReward
+		}
+		rewards[id] = reward
+		terminated[id] = e.Settings.TerminateOnScore && e.Scorer != ""
+	}
+
+	return MultiAgentStepData{
+		Observations: e.getObservations(),
+		Rewards:      rewards,
+		Terminated:   terminated,
+		Info:         e.getInfo(),
+	}
+}
+
+// RenderSize implements MultiAgentEnv.
+func (e *CompetitiveBallPushEnv) RenderSize() (float64, float64) {
+	s := e.Settings.BoundaryRadius * 2 * e.Settings.Scale
+	return s, s
+}
+
+// Render implements MultiAgentEnv.
+func (e *CompetitiveBallPushEnv) Render(target pixel.Target) {
+	center := pixel.V(e.RenderSize()).Scaled(0.5)
+
+	e.imd.Clear()
+	// Clear the screen.
+	e.imd.Color = pixel.RGB(0, 0, 0)
+	e.imd.Push(pixel.V(0, 0), pixel.V(e.RenderSize()))
+	e.imd.Rectangle(0)
+
+	// Draw the bound circle.
+	e.imd.Color = pixel.RGB(0.1, 0.1, 0.1)
+	e.imd.Push(center)
+	e.imd.Circle(e.Settings.BoundaryRadius*e.Settings.Scale, 0)
+	e.imd.Color = pixel.RGB(0.4, 0.4, 0.4)
+	e.imd.Push(center)
+	e.imd.Circle(e.Settings.BoundaryRadius*e.Settings.Scale, 3)
+
+	// Draw the goals and the agents in matching colours.
+	for i, id := range e.agentOrder {
+		col := competitiveAgentColor(i, len(e.agentOrder))
+		e.imd.Color = col
+		e.imd.Push(e.GoalPosition(id).Scaled(e.Settings.Scale).Add(center))
+		if e.BallInGoal(id) {
+			e.imd.Circle(e.Settings.GoalRadius*e.Settings.Scale, 0)
+		} else {
+			e.imd.Circle(e.Settings.GoalRadius*e.Settings.Scale, 3)
+		}
+
+		e.imd.Color = col
+		e.imd.Push(e.Players[id].Position().Scaled(e.Settings.Scale).Add(center))
+		e.imd.Circle(e.Settings.AgentRadius*e.Settings.Scale, 0)
+	}
+
+	// Draw the ball.
+	e.imd.Color = pixel.RGB(0.9, 0.9, 0.9)
+	e.imd.Push(e.Ball.Position().Scaled(e.Settings.Scale).Add(center))
+	e.imd.Circle(e.Settings.BallRadius*e.Settings.Scale, 0)
+
+	e.imd.Draw(target)
+}
+
+// Picks a distinct colour for the i'th of n agents by spreading them around the hue circle.
+func competitiveAgentColor(i, n int) pixel.RGBA {
+	h := float64(i) / float64(n) * 6
+	x := 1 - math.Abs(math.Mod(h, 2)-1)
+	switch int(h) {
+	case 0:
+		return pixel.RGB(1, x, 0)
+	case 1:
+		return pixel.RGB(x, 1, 0)
+	case 2:
+		return pixel.RGB(0, 1, x)
+	case 3:
+		return pixel.RGB(0, x, 1)
+	case 4:
+		return pixel.RGB(x, 0, 1)
+	default:
+		return pixel.RGB(1, 0, x)
+	}
+}
@@ -2,7 +2,6 @@ package gym
 
 import (
 	"math"
-	"math/rand"
 
 	"github.com/gopxl/pixel"
 	"github.com/gopxl/pixel/imdraw"
@@ -38,6 +37,13 @@ type CartPoleSettings struct {
 	OutOfBoundsReward float64
 	// The reward for the pole falling over. This should be negative.
 	PoleFallReward float64
+
+	// If true, the physics is calculated in fixed point, so that trajectories are bit-for-bit identical on every platform.
+	// The dynamics are the same as the float64 physics, up to rounding.
+	Deterministic bool
+
+	// If true, Step and Reset return a nil Info map, so that no map is allocated on every step.
+	DisableInfo bool
 }
 
 var DefaultCartPoleSettings = CartPoleSettings{
@@ -70,6 +76,7 @@ type CartPoleEnv struct {
 	Settings CartPoleSettings
 
 	drawer *imdraw.IMDraw
+	rng    envRand
 }
 
 // NewCartPoleEnv creates a new cartpole environment with the given settings.
@@ -84,76 +91,210 @@ func NewCartPoleEnv(settings CartPoleSettings) *CartPoleEnv {
 // The action is [left_right_move(-1 to 1): the acceleration to apply to the cart left/right]
 // The observation is [cart_position(-1 to 1): the position of the cart, cart_velocity(-1 to 1): the velocity of the cart, pole_angle(-1 to 1): the angle of the pole, pole_angular_velocity(-1 to 1): the angular velocity of the pole]
 func (e *CartPoleEnv) Step(action []float64) StepData {
+	var data StepData
+	e.StepInto(action, &data)
+	return data
+}
+
+// StepInto implements StepIntoEnv. It does not allocate once data's buffers have been created, as long as DisableInfo is set.
+func (e *CartPoleEnv) StepInto(action []float64, data *StepData) {
 	validateAction(action, e.ActionLength())
 
-	forceAction := action[0]
+	var reward float64
+	var failed bool
+	state := e.state()
+	if e.Settings.Deterministic {
+		reward, failed = state.stepFixed(&e.Settings, action[0])
+	} else {
+		reward, failed = state.stepFloat(&e.Settings, action[0])
+	}
+	e.setState(state)
 
+	data.Observation = e.observationInto(data.Observation)
+	data.Reward = reward
+	data.Terminated = failed
+	data.Info = e.infoInto(data.Info)
+}
+
+// cartPoleState is the physical state of a single cartpole. It holds the physics shared by CartPoleEnv and CartPoleBatch, so that they behave identically.
+type cartPoleState struct {
+	BoxPosition            float64
+	BoxVelocity            float64
+	PoleRotation           float64
+	PoleRotationalVelocity float64
+}
+
+// Steps the physics with float64 maths, and returns the reward and whether the episode failed.
+func (c *cartPoleState) stepFloat(settings *CartPoleSettings, forceAction float64) (float64, bool) {
 	// Update box velocity and position.
-	e.BoxVelocity += forceAction * e.Settings.Acceleration * e.Settings.TimeStep
-	if e.BoxVelocity > e.Settings.MaxVelocity {
-		e.BoxVelocity = e.Settings.MaxVelocity
-	} else if e.BoxVelocity < -e.Settings.MaxVelocity {
-		e.BoxVelocity = -e.Settings.MaxVelocity
+	c.BoxVelocity += forceAction * settings.Acceleration * settings.TimeStep
+	if c.BoxVelocity > settings.MaxVelocity {
+		c.BoxVelocity = settings.MaxVelocity
+	} else if c.BoxVelocity < -settings.MaxVelocity {
+		c.BoxVelocity = -settings.MaxVelocity
 	}
-	e.BoxPosition += e.BoxVelocity * e.Settings.TimeStep
+	c.BoxPosition += c.BoxVelocity * settings.TimeStep
 
 	// Update pole rotational velocity and rotation.
-	poleGravityAcceleration := e.Settings.GravityAcceleration * math.Sin(e.PoleRotation)
-	poleTorque := forceAction * math.Cos(e.PoleRotation) * e.Settings.TorqueMultiplier
-
-	e.PoleRotationalVelocity += (poleGravityAcceleration + poleTorque) * e.Settings.TimeStep
-	if e.PoleRotationalVelocity > e.Settings.MaxRotationalVelocity {
-		e.PoleRotationalVelocity = e.Settings.MaxRotationalVelocity
-	} else if e.PoleRotationalVelocity < -e.Settings.MaxRotationalVelocity {
-		e.PoleRotationalVelocity = -e.Settings.MaxRotationalVelocity
+	poleGravityAcceleration := settings.GravityAcceleration * math.Sin(c.PoleRotation)
+	poleTorque := forceAction * math.Cos(c.PoleRotation) * settings.TorqueMultiplier
+
+	c.PoleRotationalVelocity += (poleGravityAcceleration + poleTorque) * settings.TimeStep
+	if c.PoleRotationalVelocity > settings.MaxRotationalVelocity {
+		c.PoleRotationalVelocity = settings.MaxRotationalVelocity
+	} else if c.PoleRotationalVelocity < -settings.MaxRotationalVelocity {
+		c.PoleRotationalVelocity = -settings.MaxRotationalVelocity
 	}
-	e.PoleRotation += e.PoleRotationalVelocity * e.Settings.TimeStep
+	c.PoleRotation += c.PoleRotationalVelocity * settings.TimeStep
 
 	// Check if we failed, and find the reward
 	failed := false
-	reward := e.Settings.CenteredPerStepReward * math.Abs(1-e.BoxPosition) // Reward falls off the further we are from the center.
-	if e.BoxPosition > 1.0 || e.BoxPosition < -1.0 {
+	reward := settings.CenteredPerStepReward * math.Abs(1-c.BoxPosition) // Reward falls off the further we are from the center.
+	if c.BoxPosition > 1.0 || c.BoxPosition < -1.0 {
 		failed = true
-		reward = e.Settings.OutOfBoundsReward
-	} else if e.PoleRotation > e.Settings.FailAngle || e.PoleRotation < -e.Settings.FailAngle {
+		reward = settings.OutOfBoundsReward
+	} else if c.PoleRotation > settings.FailAngle || c.PoleRotation < -settings.FailAngle {
 		failed = true
-		reward = e.Settings.PoleFallReward
+		reward = settings.PoleFallReward
 	}
+	return reward, failed
+}
 
-	// Return the step data.
-	return StepData{
-		Observation: e.getObservation(),
-		Reward:      reward,
-		Terminated:  failed,
-		Info:        e.getInfo(),
+// Steps the physics in fixed point, and returns the reward and whether the episode failed.
+// This follows stepFloat exactly, but every intermediate value is a Fixed.
+func (c *cartPoleState) stepFixed(settings *CartPoleSettings, forceAction float64) (float64, bool) {
+	force := FixedFromFloat(forceAction)
+	dt := FixedFromFloat(settings.TimeStep)
+	maxVelocity := FixedFromFloat(settings.MaxVelocity)
+	maxRotationalVelocity := FixedFromFloat(settings.MaxRotationalVelocity)
+	failAngle := FixedFromFloat(settings.FailAngle)
+
+	// Update box velocity and position.
+	boxVelocity := FixedFromFloat(c.BoxVelocity) + force.Mul(FixedFromFloat(settings.Acceleration)).Mul(dt)
+	boxVelocity = boxVelocity.Clamp(-maxVelocity, maxVelocity)
+	boxPosition := FixedFromFloat(c.BoxPosition) + boxVelocity.Mul(dt)
+
+	// Update pole rotational velocity and rotation.
+	poleRotation := FixedFromFloat(c.PoleRotation)
+	poleGravityAcceleration := FixedFromFloat(settings.GravityAcceleration).Mul(poleRotation.Sin())
+	poleTorque := force.Mul(poleRotation.Cos()).Mul(FixedFromFloat(settings.TorqueMultiplier))
+	poleRotationalVelocity := FixedFromFloat(c.PoleRotationalVelocity) + (poleGravityAcceleration + poleTorque).Mul(dt)
+	poleRotationalVelocity = poleRotationalVelocity.Clamp(-maxRotationalVelocity, maxRotationalVelocity)
+	poleRotation += poleRotationalVelocity.Mul(dt)
+
+	c.BoxPosition = boxPosition.Float()
+	c.BoxVelocity = boxVelocity.Float()
+	c.PoleRotation = poleRotation.Float()
+	c.PoleRotationalVelocity = poleRotationalVelocity.Float()
+
+	// Check if we failed, and find the reward
+	if boxPosition > FixedOne || boxPosition < -FixedOne {
+		return settings.OutOfBoundsReward, true
+	} else if poleRotation > failAngle || poleRotation < -failAngle {
+		return settings.PoleFallReward, true
 	}
+	return FixedFromFloat(settings.CenteredPerStepReward).Mul((FixedOne - boxPosition).Abs()).Float(), false
+}
+
+// Picks a random starting state.
+func (c *cartPoleState) reset(settings *CartPoleSettings, rng *envRand) {
+	if settings.Deterministic {
+		c.BoxPosition = (FixedFromFloat(rng.Float64())*2 - FixedOne).Mul(FixedFromFloat(settings.MaxInitialOffset)).Float()
+		c.PoleRotation = (FixedFromFloat(rng.Float64())*2 - FixedOne).Mul(FixedFromFloat(settings.MaxInitialAngle)).Float()
+	} else {
+		c.BoxPosition = (rng.Float64()*2 - 1) * settings.MaxInitialOffset
+		c.PoleRotation = (rng.Float64()*2 - 1) * settings.MaxInitialAngle
+	}
+	c.BoxVelocity = 0.0
+	c.PoleRotationalVelocity = 0.0
+}
+
+// Writes the observation into the first 4 elements of obs.
+func (c *cartPoleState) observation(settings *CartPoleSettings, obs []float64) {
+	obs[0] = c.BoxPosition
+	obs[1] = c.BoxVelocity / settings.MaxVelocity
+	obs[2] = c.PoleRotation / 180.0
+	obs[3] = c.PoleRotationalVelocity / settings.MaxRotationalVelocity
+	clampInPlace(obs[:4])
+}
+
+// Gets the state of the env.
+func (e *CartPoleEnv) state() cartPoleState {
+	return cartPoleState{e.BoxPosition, e.BoxVelocity, e.PoleRotation, e.PoleRotationalVelocity}
+}
+
+// Sets the state of the env.
+func (e *CartPoleEnv) setState(c cartPoleState) {
+	e.BoxPosition, e.BoxVelocity, e.PoleRotation, e.PoleRotationalVelocity = c.BoxPosition, c.BoxVelocity, c.PoleRotation, c.PoleRotationalVelocity
 }
 
 func (e *CartPoleEnv) getObservation() []float64 {
-	return clampAll(
-		e.BoxPosition,
-		e.BoxVelocity/e.Settings.MaxVelocity,
-		e.PoleRotation/180.0,
-		e.PoleRotationalVelocity/e.Settings.MaxRotationalVelocity,
-	)
+	return e.observationInto(nil)
+}
+
+// Writes the observation into obs, reusing it if it is big enough.
+func (e *CartPoleEnv) observationInto(obs []float64) []float64 {
+	if cap(obs) < 4 {
+		obs = make([]float64, 4)
+	}
+	obs = obs[:4]
+	state := e.state()
+	state.observation(&e.Settings, obs)
+	return obs
 }
 
 func (e *CartPoleEnv) getInfo() map[string]interface{} {
-	return map[string]interface{}{}
+	return e.infoInto(nil)
+}
+
+// Writes the Info into info, reusing it if it is not nil.
+func (e *CartPoleEnv) infoInto(info map[string]interface{}) map[string]interface{} {
+	info = reuseInfo(info, e.Settings.DisableInfo, 6)
+	if info == nil {
+		return nil
+	}
+	info[InfoCartX] = e.BoxPosition
+	info[InfoCartVelocity] = e.BoxVelocity
+	info[InfoPoleAngleRad] = e.PoleRotation
+	info[InfoPoleAngularVelocity] = e.PoleRotationalVelocity
+	info[InfoOutOfBounds] = e.BoxPosition > 1 || e.BoxPosition < -1
+	info[InfoPoleFallen] = e.PoleRotation > e.Settings.FailAngle || e.PoleRotation < -e.Settings.FailAngle
+	return info
+}
+
+// ObservationNames implements ObservationSchema.
+func (*CartPoleEnv) ObservationNames() []string {
+	return []string{"cart_x", "cart_velocity", "pole_angle", "pole_angular_velocity"}
+}
+
+// InfoKeys implements InfoSchema.
+func (*CartPoleEnv) InfoKeys() []InfoKey {
+	return []InfoKey{
+		{InfoCartX, "float64", "The position of the cart, which is between -1 and 1 while it is in bounds."},
+		{InfoCartVelocity, "float64", "The velocity of the cart."},
+		{InfoPoleAngleRad, "float64", "The angle of the pole from upright, in radians."},
+		{InfoPoleAngularVelocity, "float64", "The angular velocity of the pole, in radians per second."},
+		{InfoOutOfBounds, "bool", "True if the cart has left the track, which terminates the episode."},
+		{InfoPoleFallen, "bool", "True if the pole has passed FailAngle, which terminates the episode."},
+	}
 }
 
 // Reset resets the environment.
 func (e *CartPoleEnv) Reset() ResetData {
-	e.BoxPosition = (rand.Float64()*2 - 1) * e.Settings.MaxInitialOffset
-	e.BoxVelocity = 0.0
-	e.PoleRotation = (rand.Float64()*2 - 1) * e.Settings.MaxInitialAngle
-	e.PoleRotationalVelocity = 0.0
+	var state cartPoleState
+	state.reset(&e.Settings, &e.rng)
+	e.setState(state)
 	return ResetData{
 		Observation: e.getObservation(),
 		Info:        e.getInfo(),
 	}
 }
 
+// Seed implements Seedable.
+func (e *CartPoleEnv) Seed(seed int64) {
+	e.rng.seed(seed)
+}
+
 func (e *CartPoleEnv) Name() string {
 	return "CartPole"
 }
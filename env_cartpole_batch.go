@@ -0,0 +1,131 @@
+package gym
+
+import "sync"
+
+// CartPoleBatch is many CartPole envs stored as a struct of arrays, so that thousands of them can be stepped in one tight loop.
+// Env i behaves exactly like a CartPoleEnv with the same settings, seeded with the seed given to Seed plus i.
+// The observations of all of the envs are stored in one slice, with the 4 values of env i at [i*4, i*4+4).
+type CartPoleBatch struct {
+	// The state of each env, indexed by env. See CartPoleEnv for what each one means.
+	BoxPosition            []float64
+	BoxVelocity            []float64
+	PoleRotation           []float64
+	PoleRotationalVelocity []float64
+	// The settings shared by every env.
+	Settings CartPoleSettings
+	// If true, envs that terminate are reset straight away. Their observation is then the first of the new episode,
+	// but Rewards and Terminated still describe the step that ended the old one.
+	AutoReset bool
+	// If more than 1, Step splits the envs between this many goroutines.
+	Workers int
+
+	// The outputs of the last Step or Reset, indexed by env.
+	Observations []float64
+	Rewards      []float64
+	Terminated   []bool
+
+	rngs []envRand
+}
+
+// NewCartPoleBatch creates a batch of n CartPole envs. Call Reset before the first Step.
+func NewCartPoleBatch(n int, settings CartPoleSettings) *CartPoleBatch {
+	if n < 1 {
+		panic("batch must have at least one env")
+	}
+	return &CartPoleBatch{
+		BoxPosition:            make([]float64, n),
+		BoxVelocity:            make([]float64, n),
+		PoleRotation:           make([]float64, n),
+		PoleRotationalVelocity: make([]float64, n),
+		Settings:               settings,
+		Observations:           make([]float64, n*4),
+		Rewards:                make([]float64, n),
+		Terminated:             make([]bool, n),
+		rngs:                   make([]envRand, n),
+	}
+}
+
+// Len gets the number of envs in the batch.
+func (b *CartPoleBatch) Len() int {
+	return len(b.BoxPosition)
+}
+
+// ActionLength gets the length of the action of a single env. Step takes one action per env.
+func (*CartPoleBatch) ActionLength() int {
+	return 1
+}
+
+// ObservationLength gets the length of the observation of a single env.
+func (*CartPoleBatch) ObservationLength() int {
+	return 4
+}
+
+// Seed seeds env i with seed+i.
+func (b *CartPoleBatch) Seed(seed int64) {
+	for i := range b.rngs {
+		b.rngs[i].seed(seed + int64(i))
+	}
+}
+
+// Reset resets every env, and writes their observations into Observations.
+func (b *CartPoleBatch) Reset() {
+	for i := range b.rngs {
+		b.ResetEnv(i)
+	}
+}
+
+// ResetEnv resets env i, and writes its observation into Observations.
+func (b *CartPoleBatch) ResetEnv(i int) {
+	var state cartPoleState
+	state.reset(&b.Settings, &b.rngs[i])
+	b.setState(i, state)
+	b.Terminated[i] = false
+	b.Rewards[i] = 0
+	state.observation(&b.Settings, b.Observations[i*4:])
+}
+
+// Step steps every env, with actions[i] as the single action of env i, and writes the results into Observations, Rewards and Terminated.
+func (b *CartPoleBatch) Step(actions []float64) {
+	validateAction(actions, b.Len())
+	if b.Workers <= 1 {
+		b.stepRange(actions, 0, b.Len())
+		return
+	}
+	chunk := (b.Len() + b.Workers - 1) / b.Workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < b.Len(); lo += chunk {
+		hi := min(lo+chunk, b.Len())
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			b.stepRange(actions, lo, hi)
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
+// Steps the envs from lo up to hi. Each env only touches its own elements, so ranges can be stepped at the same time.
+func (b *CartPoleBatch) stepRange(actions []float64, lo, hi int) {
+	settings := &b.Settings
+	for i := lo; i < hi; i++ {
+		state := b.state(i)
+		if settings.Deterministic {
+			b.Rewards[i], b.Terminated[i] = state.stepFixed(settings, actions[i])
+		} else {
+			b.Rewards[i], b.Terminated[i] = state.stepFloat(settings, actions[i])
+		}
+		if b.Terminated[i] && b.AutoReset {
+			state.reset(settings, &b.rngs[i])
+		}
+		b.setState(i, state)
+		state.observation(settings, b.Observations[i*4:])
+	}
+}
+
+func (b *CartPoleBatch) state(i int) cartPoleState {
+	return cartPoleState{b.BoxPosition[i], b.BoxVelocity[i], b.PoleRotation[i], b.PoleRotationalVelocity[i]}
+}
+
+func (b *CartPoleBatch) setState(i int, c cartPoleState) {
+	b.BoxPosition[i], b.BoxVelocity[i], b.PoleRotation[i], b.PoleRotationalVelocity[i] = c.BoxPosition, c.BoxVelocity, c.PoleRotation, c.PoleRotationalVelocity
+}
@@ -0,0 +1,66 @@
+package gym
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCartPoleBatchMatchesSingle steps a CartPoleBatch and a separate CartPoleEnv for each of its envs with the same random actions,
+// with every combination of float or fixed point physics, goroutines and AutoReset, and checks that every observation, reward and termination is identical.
+func TestCartPoleBatchMatchesSingle(t *testing.T) {
+	const n, steps = 37, 500
+	cases := []struct {
+		deterministic, autoReset bool
+		workers                  int
+	}{
+		{false, false, 1}, {false, false, 4}, {false, true, 1}, {false, true, 4},
+		{true, false, 1}, {true, false, 4}, {true, true, 1}, {true, true, 4},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("deterministic=%v/autoReset=%v/workers=%d", c.deterministic, c.autoReset, c.workers), func(t *testing.T) {
+			settings := DefaultCartPoleSettings
+			settings.Deterministic = c.deterministic
+			batch := NewCartPoleBatch(n, settings)
+			batch.Workers = c.workers
+			batch.AutoReset = c.autoReset
+			batch.Seed(1)
+			batch.Reset()
+			envs := make([]*CartPoleEnv, n)
+			for i := range envs {
+				envs[i] = NewCartPoleEnv(settings)
+				envs[i].Seed(1 + int64(i))
+				envs[i].Reset()
+			}
+
+			var rng envRand
+			rng.seed(2)
+			actions := make([]float64, n)
+			for step := 0; step < steps; step++ {
+				for i := range actions {
+					actions[i] = rng.Float64()*2 - 1
+				}
+				batch.Step(actions)
+				for i, env := range envs {
+					data := env.Step(actions[i : i+1])
+					if batch.Rewards[i] != data.Reward || batch.Terminated[i] != data.Terminated {
+						t.Fatalf("env %d step %d: reward %v terminated %v, want %v %v", i, step, batch.Rewards[i], batch.Terminated[i], data.Reward, data.Terminated)
+					}
+					want := data.Observation
+					if data.Terminated && c.autoReset {
+						want = env.Reset().Observation
+					}
+					obs := batch.Observations[i*4 : i*4+4]
+					for j := range obs {
+						if obs[j] != want[j] {
+							t.Fatalf("env %d step %d: observation %v, want %v", i, step, obs, want)
+						}
+					}
+					if data.Terminated && !c.autoReset {
+						batch.ResetEnv(i)
+						env.Reset()
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package gym
+
+import (
+	"math"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/imdraw"
+)
+
+var _ Env = &PongEnv{}
+var _ MultiAgentEnv = &MultiAgentPongEnv{}
+
+// PongSettings contains all the settings for the pong environments.
+type PongSettings struct {
+	// The width and height of the court. The court is centered on the origin.
+	CourtWidth  float64
+	CourtHeight float64
+	// The size of each paddle, and how far it sits from the back of the court.
+	PaddleHeight float64
+	PaddleWidth  float64
+	PaddleInset  float64
+	// The max speed of a paddle.
+	PaddleSpeed float64
+	BallRadius  float64
+	// The speed of the ball when it is served.
+	BallServeSpeed float64
+	// The ball speed is multiplied by this every time it hits a paddle.
+	BallSpeedup float64
+	// The max speed of the ball.
+	BallMaxSpeed float64
+	// How much vertical velocity is added to the ball when it hits the edge of a paddle rather than the center.
+	PaddleSpin float64
+	// The speed of the scripted opponent, as a fraction of PaddleSpeed.
+	OpponentSkill float64
+
+	// The reward for winning a point.
+	WinPointReward float64
+	// The reward for losing a point. This should be negative.
+	LosePointReward float64
+	// The reward for hitting the ball with your paddle.
+	HitBallReward float64
+
+	// If true, observations are a low resolution image of the court instead of a vector.
+	PixelObservations bool
+	// The resolution of the image used for pixel observations.
+	PixelWidth  int
+	PixelHeight int
+
+	Scale     float64
+	DeltaTime float64
+
+	// If true, Step and Reset return a nil Info map, so that no map is allocated on every step.
+	DisableInfo bool
+}
+
+var DefaultPongSettings = PongSettings{
+	CourtWidth:     40,
+	CourtHeight:    30,
+	PaddleHeight:   6,
+	PaddleWidth:    1,
+	PaddleInset:    2,
+	PaddleSpeed:    25,
+	BallRadius:     0.75,
+	BallServeSpeed: 20,
+	BallSpeedup:    1.05,
+	BallMaxSpeed:   45,
+	PaddleSpin:     15,
+	OpponentSkill:  0.6,
+
+	WinPointReward:  1,
+	LosePointReward: -1,
+	HitBallReward:   0.1,
+
+	PixelObservations: false,
+	PixelWidth:        40,
+	PixelHeight:       30,
+
+	Scale:     20,
+	DeltaTime: 1.0 / 60.0,
+}
+
+// Indexes of the two paddles.
+const (
+	PongLeft  = 0
+	PongRight = 1
+)
+
+// PongState holds the state and rules of a game of pong, shared by PongEnv and MultiAgentPongEnv.
+// The left paddle belongs to side PongLeft and the right paddle to side PongRight.
+type PongState struct {
+	Ball *VerletParticle
+	// The vertical position of the center of each paddle.
+	PaddleY [2]float64
+	// The number of points won by each side since the environment was created.
+	Score    [2]int
+	Settings PongSettings
+
+	imd *imdraw.IMDraw
+	rng envRand
+}
+
+func newPongState(settings PongSettings) PongState {
+	return PongState{
+		Ball:     NewVerletParticle(pixel.ZV, 1, settings.DeltaTime),
+		Settings: settings,
+		imd:      imdraw.New(nil),
+	}
+}
+
+// ActionLength implements Env. The action is [paddle_move(-1 to 1): the speed to move the paddle down/up].
+func (*PongState) ActionLength() int {
+	return 1
+}
+
+// NumCategoricalActions implements Env.
+func (*PongState) NumCategoricalActions() int {
+	return 3
+}
+
+// ConvertCategoricalAction implements Env. CAction 0 stays still, CAction 1 moves up, CAction 2 moves down.
+func (*PongState) ConvertCategoricalAction(a int) []float64 {
+	switch a {
+	case 0:
+		return []float64{0}
+	case 1:
+		return []float64{1}
+	case 2:
+		return []float64{-1}
+	}
+	panic("invalid action")
+}
+
+// ObservationLength implements Env.
+func (s *PongState) ObservationLength() int {
+	if s.Settings.PixelObservations {
+		return s.Settings.PixelWidth * s.Settings.PixelHeight
+	}
+	return 6
+}
+
+// Seed implements Seedable.
+func (s *PongState) Seed(seed int64) {
+	s.rng.seed(seed)
+}
+
+// PaddleX gets the horizontal position of the center of the paddle for the given side.
+func (s *PongState) PaddleX(side int) float64 {
+	x := s.Settings.CourtWidth/2 - s.Settings.PaddleInset
+	if side == PongLeft {
+		return -x
+	}
+	return x
+}
+
+// Puts the paddles back in the middle and serves the ball towards a random side.
+func (s *PongState) reset() {
+	s.PaddleY = [2]float64{0, 0}
+	angle := (s.rng.Float64()*2 - 1) * math.Pi / 4
+	if s.rng.Intn(2) == 0 {
+		angle += math.Pi
+	}
+	s.Ball.SlideToPosition(pixel.ZV)
+	s.Ball.SetVelocity(pixel.V(s.Settings.BallServeSpeed, 0).Rotated(angle))
+}
+
+// Gets the action that the scripted opponent would take for the given side.
+// It follows the ball when it is coming towards it, and drifts back to the center otherwise.
+func (s *PongState) scriptedAction(side int) float64 {
+	target := 0.0
+	towards := s.Ball.implicitVelocity().X < 0
+	if side == PongRight {
+		towards = !towards
+	}
+	if towards {
+		target = s.Ball.Position().Y
+	}
+	diff := (target - s.PaddleY[side]) / (s.Settings.PaddleHeight / 2)
+	return clampAll(diff)[0] * s.Settings.OpponentSkill
+}
+
+// Advances the game by one time step with the given paddle actions.
+// Returns which side hit the ball this step (-1 for neither) and which side won a point (-1 for neither).
+func (s *PongState) step(actions [2]float64) (hitter, winner int) {
+	hitter, winner = -1, -1
+
+	// Move the paddles, keeping them inside the court.
+	maxPaddleY := (s.Settings.CourtHeight - s.Settings.PaddleHeight) / 2
+	for side := range s.PaddleY {
+		s.PaddleY[side] += actions[side] * s.Settings.PaddleSpeed * s.Settings.DeltaTime
+		s.PaddleY[side] = math.Max(-maxPaddleY, math.Min(maxPaddleY, s.PaddleY[side]))
+	}
+
+	pos := s.Ball.Position()
+	vel := s.Ball.implicitVelocity()
+	r := s.Settings.BallRadius
+
+	// Bounce off the top and bottom walls.
+	if pos.Y+r > s.Settings.CourtHeight/2 && vel.Y > 0 {
+		pos.Y = s.Settings.CourtHeight - 2*r - pos.Y
+		vel.Y = -vel.Y
+	} else if pos.Y-r < -s.Settings.CourtHeight/2 && vel.Y < 0 {
+		pos.Y = -s.Settings.CourtHeight + 2*r - pos.Y
+		vel.Y = -vel.Y
+	}
+
+	// Bounce off the paddles. A paddle only hits the ball if the ball is moving towards the paddle's side.
+	for side := range s.PaddleY {
+		dir := 1.0
+		if side == PongLeft {
+			dir = -1.0
+		}
+		if vel.X*dir <= 0 {
+			continue
+		}
+		face := s.PaddleX(side) - dir*s.Settings.PaddleWidth/2
+		offset := pos.Y - s.PaddleY[side]
+		crossedFace := (pos.X+dir*r-face)*dir > 0 && (pos.X-s.PaddleX(side))*dir < s.Settings.PaddleWidth/2
+		if crossedFace && math.Abs(offset) < s.Settings.PaddleHeight/2+r {
+			pos.X = face - dir*r
+			vel.X = -vel.X * s.Settings.BallSpeedup
+			vel.Y += offset / (s.Settings.PaddleHeight / 2) * s.Settings.PaddleSpin
+			if vel.Len() > s.Settings.BallMaxSpeed {
+				vel = vel.Unit().Scaled(s.Settings.BallMaxSpeed)
+			}
+			hitter = side
+		}
+	}
+
+	s.Ball.SlideToPosition(pos)
+	s.Ball.SetVelocity(vel)
+	s.Ball.StepParticle()
+
+	// Check if the ball has left the court.
+	if s.Ball.Position().X < -s.Settings.CourtWidth/2 {
+		winner = PongRight
+	} else if s.Ball.Position().X > s.Settings.CourtWidth/2 {
+		winner = PongLeft
+	}
+	if winner != -1 {
+		s.Score[winner]++
+	}
+	return hitter, winner
+}
+
+// Computes the reward for the given side from the result of a step.
+func (s *PongState) reward(side, hitter, winner int) float64 {
+	reward := 0.0
+	if hitter == side {
+		reward += s.Settings.HitBallReward
+	}
+	if winner == side {
+		reward += s.Settings.WinPointReward
+	} else if winner != -1 {
+		reward += s.Settings.LosePointReward
+	}
+	return reward
+}
+
+// Gets the observation for the given side.
+// The court is mirrored for the right side, so that every agent sees itself as the left paddle.
+func (s *PongState) getObservation(side int) []float64 {
+	mirror := 1.0
+	if side == PongRight {
+		mirror = -1.0
+	}
+	if s.Settings.PixelObservations {
+		return s.getPixelObservation(mirror)
+	}
+	// Things in the observation
+	// 1. Own paddle height
+	// 2. Opponent paddle height
+	// 3. Ball position
+	// 4. Ball velocity
+	return clampAll(
+		s.PaddleY[side]/(s.Settings.CourtHeight/2),
+		s.PaddleY[1-side]/(s.Settings.CourtHeight/2),
+		mirror*s.Ball.Position().X/(s.Settings.CourtWidth/2),
+		s.Ball.Position().Y/(s.Settings.CourtHeight/2),
+		mirror*s.Ball.implicitVelocity().X/s.Settings.BallMaxSpeed,
+		s.Ball.implicitVelocity().Y/s.Settings.BallMaxSpeed,
+	)
+}
+
+func (s *PongState) getInfo() map[string]interface{} {
+	if s.Settings.DisableInfo {
+		return nil
+	}
+	return map[string]interface{}{
+		InfoScoreLeft:  s.Score[PongLeft],
+		InfoScoreRight: s.Score[PongRight],
+		InfoBallX:      s.Ball.Position().X,
+		InfoBallY:      s.Ball.Position().Y,
+	}
+}
+
+// ObservationNames implements ObservationSchema. Positions and velocities are mirrored for the right paddle, so "own" is always the observing side.
+func (s *PongState) ObservationNames() []string {
+	if s.Settings.PixelObservations {
+		return pixelObservationNames(s.Settings.PixelWidth, s.Settings.PixelHeight)
+	}
+	return []string{"own_paddle_y", "opponent_paddle_y", "ball_x", "ball_y", "ball_velocity_x", "ball_velocity_y"}
+}
+
+// InfoKeys implements InfoSchema.
+func (*PongState) InfoKeys() []InfoKey {
+	return []InfoKey{
+		{InfoScoreLeft, "int", "The number of points won by the left side since the env was created."},
+		{InfoScoreRight, "int", "The number of points won by the right side since the env was created."},
+		{InfoBallX, "float64", "The horizontal position of the ball, where 0 is the center of the court."},
+		{InfoBallY, "float64", "The vertical position of the ball, where 0 is the center of the court."},
+	}
+}
+
+// Rasterizes the court into a PixelWidth x PixelHeight grayscale image, row by row from the top.
+// Empty space is -1, and the paddles and ball are 1.
+func (s *PongState) getPixelObservation(mirror float64) []float64 {
+	w, h := s.Settings.PixelWidth, s.Settings.PixelHeight
+	obs := make([]float64, w*h)
+	for i := range obs {
+		obs[i] = -1
+	}
+	fill := func(min, max pixel.Vec) {
+		x0 := int(math.Floor((min.X/s.Settings.CourtWidth + 0.5) * float64(w)))
+		x1 := int(math.Ceil((max.X/s.Settings.CourtWidth + 0.5) * float64(w)))
+		y0 := int(math.Floor((0.5 - max.Y/s.Settings.CourtHeight) * float64(h)))
+		y1 := int(math.Ceil((0.5 - min.Y/s.Settings.CourtHeight) * float64(h)))
+		for y := max0(y0); y < y1 && y < h; y++ {
+			for x := max0(x0); x < x1 && x < w; x++ {
+				obs[y*w+x] = 1
+			}
+		}
+	}
+	for side := range s.PaddleY {
+		c := pixel.V(mirror*s.PaddleX(side), s.PaddleY[side])
+		half := pixel.V(s.Settings.PaddleWidth/2, s.Settings.PaddleHeight/2)
+		fill(c.Sub(half), c.Add(half))
+	}
+	ball := s.Ball.Position()
+	ball.X *= mirror
+	half := pixel.V(s.Settings.BallRadius, s.Settings.BallRadius)
+	fill(ball.Sub(half), ball.Add(half))
+	return obs
+}
+
+func max0(x int) int {
+	if x < 0 {
+		return 0
+	}
+	return x
+}
+
+// RenderSize implements Env.
+func (s *PongState) RenderSize() (float64, float64) {
+	return s.Settings.CourtWidth * s.Settings.Scale, s.Settings.CourtHeight * s.Settings.Scale
+}
+
+// Render implements Env.
+func (s *PongState) Render(target pixel.Target) {
+	center := pixel.V(s.RenderSize()).Scaled(0.5)
+
+	s.imd.Clear()
+	// Clear the screen.
+	s.imd.Color = pixel.RGB(0, 0, 0)
+	s.imd.Push(pixel.V(0, 0), pixel.V(s.RenderSize()))
+	s.imd.Rectangle(0)
+
+	// Draw the center line.
+	s.imd.Color = pixel.RGB(0.4, 0.4, 0.4)
+	s.imd.Push(pixel.V(center.X, 0), pixel.V(center.X, center.Y*2))
+	s.imd.Line(3)
+
+	// Draw the paddles.
+	s.imd.Color = pixel.RGB(1, 1, 1)
+	for side := range s.PaddleY {
+		c := pixel.V(s.PaddleX(side), s.PaddleY[side])
+		half := pixel.V(s.Settings.PaddleWidth/2, s.Settings.PaddleHeight/2)
+		s.imd.Push(c.Sub(half).Scaled(s.Settings.Scale).Add(center), c.Add(half).Scaled(s.Settings.Scale).Add(center))
+		s.imd.Rectangle(0)
+	}
+
+	// Draw the ball.
+	s.imd.Push(s.Ball.Position().Scaled(s.Settings.Scale).Add(center))
+	s.imd.Circle(s.Settings.BallRadius*s.Settings.Scale, 0)
+
+	s.imd.Draw(target)
+}
+
+// PongEnv is a single agent game of pong. The agent controls the left paddle, and the right paddle is controlled by a scripted opponent.
+// The episode terminates when either side wins a point.
+type PongEnv struct {
+	PongState
+}
+
+// NewPongEnv creates a new single agent pong environment with the given settings.
+func NewPongEnv(settings PongSettings) *PongEnv {
+	e := &PongEnv{newPongState(settings)}
+	e.Reset()
+	return e
+}
+
+// Name implements Env.
+func (*PongEnv) Name() string {
+	return "Pong"
+}
+
+// Reset implements Env.
+func (e *PongEnv) Reset() ResetData {
+	e.reset()
+	return ResetData{
+		Observation: e.getObservation(PongLeft),
+		Info:        e.getInfo(),
+	}
+}
+
+// Step implements Env.
+func (e *PongEnv) Step(action []float64) StepData {
+	validateAction(action, e.ActionLength())
+	hitter, winner := e.step([2]float64{action[0], e.scriptedAction(PongRight)})
+	return StepData{
+		Observation: e.getObservation(PongLeft),
+		Reward:      e.reward(PongLeft, hitter, winner),
+		Terminated:  winner != -1,
+		Info:        e.getInfo(),
+	}
+}
+
+// MultiAgentPongEnv is a two agent game of pong. Each side of the court is a slot that can be filled by an agent.
+// Empty slots are controlled by a scripted opponent, so removing an agent hands its paddle over to the computer.
+// The episode terminates when either side wins a point.
+type MultiAgentPongEnv struct {
+	PongState
+	// The agent in each slot, or "" if the slot is controlled by the scripted opponent.
+	Slots [2]AgentID
+}
+
+// NewMultiAgentPongEnv creates a new multi-agent pong environment. The first agent plays on the left, and the second on the right.
+func NewMultiAgentPongEnv(settings PongSettings, agents ...AgentID) *MultiAgentPongEnv {
+	e := &MultiAgentPongEnv{PongState: newPongState(settings)}
+	for _, id := range agents {
+		e.AddAgent(id)
+	}
+	e.Reset()
+	return e
+}
+
+// Name implements MultiAgentEnv.
+func (*MultiAgentPongEnv) Name() string {
+	return "MultiAgentPong"
+}
+
+// Agents implements MultiAgentEnv.
+func (e *MultiAgentPongEnv) Agents() []AgentID {
+	agents := make([]AgentID, 0, 2)
+	for _, id := range e.Slots {
+		if id != "" {
+			agents = append(agents, id)
+		}
+	}
+	return agents
+}
+
+// AddAgent implements MultiAgentEnv. The agent takes the first free slot, and it panics if both slots are taken.
+func (e *MultiAgentPongEnv) AddAgent(id AgentID) {
+	if id == "" {
+		panic("agent id must not be empty")
+	}
+	if e.Slots[PongLeft] == id || e.Slots[PongRight] == id {
+		panic("agent already exists: " + string(id))
+	}
+	for side := range e.Slots {
+		if e.Slots[side] == "" {
+			e.Slots[side] = id
+			return
+		}
+	}
+	panic("both pong paddles are already taken")
+}
+
+// RemoveAgent implements MultiAgentEnv.
+func (e *MultiAgentPongEnv) RemoveAgent(id AgentID) {
+	for side := range e.Slots {
+		if e.Slots[side] == id && id != "" {
+			e.Slots[side] = ""
+			return
+		}
+	}
+	panic("agent does not exist: " + string(id))
+}
+
+// Reset implements MultiAgentEnv.
+func (e *MultiAgentPongEnv) Reset() MultiAgentResetData {
+	e.reset()
+	return MultiAgentResetData{
+		Observations: e.getObservations(),
+		Info:         e.getInfo(),
+	}
+}
+
+// Step implements MultiAgentEnv. There must be an action for every agent in the environment.
+func (e *MultiAgentPongEnv) Step(actions map[AgentID][]float64) MultiAgentStepData {
+	var paddleActions [2]float64
+	for side, id := range e.Slots {
+		if id == "" {
+			paddleActions[side] = e.scriptedAction(side)
+			continue
+		}
+		action, ok := actions[id]
+		if !ok {
+			panic("Invalid action: missing action for agent " + string(id))
+		}
+		validateAction(action, e.ActionLength())
+		paddleActions[side] = action[0]
+	}
+
+	hitter, winner := e.step(paddleActions)
+
+	rewards := make(map[AgentID]float64, 2)
+	terminated := make(map[AgentID]bool, 2)
+	for side, id := range e.Slots {
+		if id != "" {
+			rewards[id] = e.reward(side, hitter, winner)
+			terminated[id] = winner != -1
+		}
+	}
+	return MultiAgentStepData{
+		Observations: e.getObservations(),
+		Rewards:      rewards,
+		Terminated:   terminated,
+		Info:         e.getInfo(),
+	}
+}
+
+func (e *MultiAgentPongEnv) getObservations() map[AgentID][]float64 {
+	obs := make(map[AgentID][]float64, 2)
+	for side, id := range e.Slots {
+		if id != "" {
+			obs[id] = e.getObservation(side)
+		}
+	}
+	return obs
+}
@@ -0,0 +1,364 @@
+package gym
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/imdraw"
+)
+
+var _ Env = &RopeEnv{}
+
+// RopeSettings contains all the settings for the rope environment.
+type RopeSettings struct {
+	// The number of particles in the rope.
+	NumLinks int
+	// The distance between neighbouring particles in the rope.
+	LinkLength float64
+	// The radius of each particle in the rope.
+	LinkRadius float64
+	// The smallest angle that the rope can bend to at each particle. Pi means the rope cannot bend at all.
+	MinBendAngle float64
+	// How strongly the rope resists bending past MinBendAngle, between 0 and 1.
+	BendStiffness float64
+	// Drag on the rope particles. The rope lies on a table, so this acts like friction.
+	Drag float64
+	// Gravity pulling the rope down the screen. This is usually 0, as the rope is seen from above.
+	Gravity float64
+	// The number of constraint relaxations per step.
+	ConstraintIterations int
+
+	// The max speed of the gripper holding the first particle of the rope.
+	GripperSpeed float64
+	// The radius of the circular arena.
+	BoundaryRadius float64
+	// The max curvature of the target shape, in radians per unit length. 0 means the target is always a straight line.
+	MaxTargetCurvature float64
+	// The number of evenly spaced rope particles that are included in the observation.
+	NumObservedPoints int
+
+	// The reward for reducing the shape distance by one unit.
+	ShapeReward float64
+	// The shape distance below which the rope is considered to match the target.
+	SuccessDistance float64
+	// The reward for matching the target.
+	SuccessReward float64
+	// If true, the episode terminates when the rope matches the target.
+	TerminateOnSuccess bool
+
+	Scale     float64
+	DeltaTime float64
+
+	// If true, Step and Reset return a nil Info map, so that no map is allocated on every step.
+	DisableInfo bool
+}
+
+var DefaultRopeSettings = RopeSettings{
+	NumLinks:             16,
+	LinkLength:           1.5,
+	LinkRadius:           0.4,
+	MinBendAngle:         math.Pi / 2,
+	BendStiffness:        0.3,
+	Drag:                 2,
+	Gravity:              0,
+	ConstraintIterations: 10,
+
+	GripperSpeed:       10,
+	BoundaryRadius:     20,
+	MaxTargetCurvature: 0.15,
+	NumObservedPoints:  8,
+
+	ShapeReward:        1,
+	SuccessDistance:    1,
+	SuccessReward:      5,
+	TerminateOnSuccess: true,
+
+	Scale:     20,
+	DeltaTime: 1.0 / 60.0,
+}
+
+// RopeEnv is a deformable object manipulation task.
+// The agent grips the first particle of a rope lying on a table, and must drag the rope so that it lies along a target shape.
+type RopeEnv struct {
+	// The particles of the rope. The first one is held by the gripper.
+	Rope []*WorldParticle
+	// The position that each rope particle should end up at.
+	Target   []pixel.Vec
+	World    *VerletWorld
+	Settings RopeSettings
+
+	lastDistance float64
+	imd          *imdraw.IMDraw
+	rng          envRand
+}
+
+// NewRopeEnv creates a new rope environment with the given settings.
+// It panics if the rope has fewer than two links, or if it is too long to lie straight across the arena.
+func NewRopeEnv(settings RopeSettings) *RopeEnv {
+	if settings.NumLinks < 2 {
+		panic("rope must have at least two links")
+	}
+	if settings.LinkLength*float64(settings.NumLinks-1) > 2*(settings.BoundaryRadius-settings.LinkRadius) {
+		panic("rope is too long to fit inside the arena")
+	}
+	worldSettings := DefaultVerletWorldSettings
+	worldSettings.Gravity = pixel.V(0, -settings.Gravity)
+	worldSettings.LinearDrag = settings.Drag
+	worldSettings.ConstraintIterations = settings.ConstraintIterations
+	worldSettings.DeltaTime = settings.DeltaTime
+	world := NewVerletWorld(worldSettings)
+
+	rope := make([]*WorldParticle, settings.NumLinks)
+	for i := range rope {
+		rope[i] = world.AddParticle(pixel.V(float64(i)*settings.LinkLength, 0), 1, settings.LinkRadius)
+		if i > 0 {
+			world.AddConstraint(&DistanceConstraint{rope[i-1], rope[i], settings.LinkLength, 1})
+		}
+		if i > 1 {
+			world.AddConstraint(&AngleConstraint{rope[i-2], rope[i-1], rope[i], settings.MinBendAngle, math.Pi, settings.BendStiffness})
+		}
+	}
+	rope[0].Pinned = true
+	world.AddObstacle(&BoundaryObstacle{Radius: settings.BoundaryRadius})
+
+	e := &RopeEnv{
+		Rope:     rope,
+		Target:   make([]pixel.Vec, settings.NumLinks),
+		World:    world,
+		Settings: settings,
+		imd:      imdraw.New(nil),
+	}
+	e.Reset()
+	return e
+}
+
+// Seed implements Seedable.
+func (e *RopeEnv) Seed(seed int64) {
+	e.rng.seed(seed)
+}
+
+// Name implements Env.
+func (*RopeEnv) Name() string {
+	return "Rope"
+}
+
+// ActionLength implements Env.
+func (*RopeEnv) ActionLength() int {
+	return 2
+}
+
+// NumCategoricalActions implements Env.
+func (*RopeEnv) NumCategoricalActions() int {
+	return 5
+}
+
+// ConvertCategoricalAction implements Env. The actions are the same as BallPushEnv.
+func (*RopeEnv) ConvertCategoricalAction(a int) []float64 {
+	return convertMoveCategoricalAction(a)
+}
+
+// ObservationLength implements Env.
+func (e *RopeEnv) ObservationLength() int {
+	return e.Settings.NumObservedPoints * 4
+}
+
+// ShapeDistance gets the mean distance between each rope particle and its target position.
+func (e *RopeEnv) ShapeDistance() float64 {
+	total := 0.0
+	for i, p := range e.Rope {
+		total += p.Position().Sub(e.Target[i]).Len()
+	}
+	return total / float64(len(e.Rope))
+}
+
+// Generates a random smooth curve of rope length, made of arcs of constant curvature, that fits inside the arena.
+// The rope can always be dragged into this shape, as it never bends more sharply than the rope is allowed to.
+// If no random curve fits after many tries, the shape is a straight line through the center, which NewRopeEnv makes sure always fits.
+func (e *RopeEnv) randomShape() []pixel.Vec {
+	shape := make([]pixel.Vec, e.Settings.NumLinks)
+	ropeLength := e.Settings.LinkLength * float64(e.Settings.NumLinks-1)
+	maxTurn := math.Pi - e.Settings.MinBendAngle
+	for tries := 0; tries <= 100; tries++ {
+		start := pixel.V(0, e.rng.Float64()*(e.Settings.BoundaryRadius-ropeLength/2)).Rotated(e.rng.Float64() * 2 * math.Pi)
+		heading := e.rng.Float64() * 2 * math.Pi
+		curvature := (e.rng.Float64()*2 - 1) * e.Settings.MaxTargetCurvature
+		turn := math.Max(-maxTurn, math.Min(maxTurn, curvature*e.Settings.LinkLength))
+		shape[0] = start
+		fits := true
+		for i := 1; i < len(shape); i++ {
+			shape[i] = shape[i-1].Add(pixel.V(e.Settings.LinkLength, 0).Rotated(heading))
+			heading += turn
+			if shape[i].Len() > e.Settings.BoundaryRadius-e.Settings.LinkRadius {
+				fits = false
+			}
+		}
+		if fits {
+			return shape
+		}
+	}
+	heading := pixel.V(e.Settings.LinkLength, 0).Rotated(e.rng.Float64() * 2 * math.Pi)
+	for i := range shape {
+		shape[i] = heading.Scaled(float64(i) - float64(len(shape)-1)/2)
+	}
+	return shape
+}
+
+// Reset implements Env.
+// The rope starts along one random shape, and the target is another.
+func (e *RopeEnv) Reset() ResetData {
+	for i, pos := range e.randomShape() {
+		e.Rope[i].SlideToPosition(pos)
+		e.Rope[i].SetVelocity(pixel.ZV)
+	}
+	e.Target = e.randomShape()
+	e.lastDistance = e.ShapeDistance()
+	return ResetData{
+		Observation: e.getObservation(),
+		Info:        e.getInfo(),
+	}
+}
+
+func (e *RopeEnv) getObservation() []float64 {
+	// For each observed point:
+	// 1. Vector from center to the rope particle
+	// 2. Vector from the rope particle to its target
+	obs := make([]float64, 0, e.ObservationLength())
+	for i := 0; i < e.Settings.NumObservedPoints; i++ {
+		idx := 0
+		if e.Settings.NumObservedPoints > 1 {
+			idx = i * (len(e.Rope) - 1) / (e.Settings.NumObservedPoints - 1)
+		}
+		pos := e.Rope[idx].Position()
+		toTarget := e.Target[idx].Sub(pos)
+		obs = append(obs,
+			pos.X/e.Settings.BoundaryRadius,
+			pos.Y/e.Settings.BoundaryRadius,
+			toTarget.X/(2*e.Settings.BoundaryRadius),
+			toTarget.Y/(2*e.Settings.BoundaryRadius),
+		)
+	}
+	return clampAll(obs...)
+}
+
+func (e *RopeEnv) getInfo() map[string]interface{} {
+	if e.Settings.DisableInfo {
+		return nil
+	}
+	return map[string]interface{}{
+		InfoIsSuccess:     e.lastDistance < e.Settings.SuccessDistance,
+		InfoShapeDistance: e.lastDistance,
+	}
+}
+
+// ObservationNames implements ObservationSchema, with the observed points numbered from 0 at the gripper.
+func (e *RopeEnv) ObservationNames() []string {
+	names := make([]string, 0, e.ObservationLength())
+	for i := 0; i < e.Settings.NumObservedPoints; i++ {
+		names = append(names,
+			fmt.Sprintf("point_%d_x", i), fmt.Sprintf("point_%d_y", i),
+			fmt.Sprintf("point_%d_to_target_x", i), fmt.Sprintf("point_%d_to_target_y", i),
+		)
+	}
+	return names
+}
+
+// InfoKeys implements InfoSchema.
+func (*RopeEnv) InfoKeys() []InfoKey {
+	return []InfoKey{
+		{InfoIsSuccess, "bool", "True if the rope is within SuccessDistance of the target shape."},
+		{InfoShapeDistance, "float64", "The mean distance between each rope particle and its target position."},
+	}
+}
+
+// Step implements Env.
+func (e *RopeEnv) Step(action []float64) StepData {
+	validateAction(action, 2)
+
+	// Ensure that going diagonally does not go faster than sideways
+	controlVec := pixel.V(action[0], action[1])
+	if controlVec.Len() > 1 {
+		controlVec = controlVec.Unit()
+	}
+
+	// The gripper is kinematic, so move it directly and keep it inside the arena.
+	gripper := e.Rope[0]
+	gripperPos := gripper.Position().Add(controlVec.Scaled(e.Settings.GripperSpeed * e.Settings.DeltaTime))
+	maxGripperDist := e.Settings.BoundaryRadius - e.Settings.LinkRadius
+	if gripperPos.Len() > maxGripperDist {
+		gripperPos = gripperPos.Unit().Scaled(maxGripperDist)
+	}
+	gripper.SlideToPosition(gripperPos)
+
+	e.World.Step()
+
+	// Reward getting closer to the target shape.
+	distance := e.ShapeDistance()
+	reward := e.Settings.ShapeReward * (e.lastDistance - distance)
+	e.lastDistance = distance
+
+	success := distance < e.Settings.SuccessDistance
+	if success {
+		reward += e.Settings.SuccessReward
+	}
+
+	return StepData{
+		Observation: e.getObservation(),
+		Reward:      reward,
+		Terminated:  success && e.Settings.TerminateOnSuccess,
+		Info:        e.getInfo(),
+	}
+}
+
+// RenderSize implements Env.
+func (e *RopeEnv) RenderSize() (float64, float64) {
+	s := e.Settings.BoundaryRadius * 2 * e.Settings.Scale
+	return s, s
+}
+
+// Render implements Env.
+func (e *RopeEnv) Render(target pixel.Target) {
+	center := pixel.V(e.RenderSize()).Scaled(0.5)
+	toScreen := func(v pixel.Vec) pixel.Vec {
+		return v.Scaled(e.Settings.Scale).Add(center)
+	}
+
+	e.imd.Clear()
+	// Clear the screen.
+	e.imd.Color = pixel.RGB(0, 0, 0)
+	e.imd.Push(pixel.V(0, 0), pixel.V(e.RenderSize()))
+	e.imd.Rectangle(0)
+
+	// Draw the table.
+	e.imd.Color = pixel.RGB(0.1, 0.1, 0.1)
+	e.imd.Push(center)
+	e.imd.Circle(e.Settings.BoundaryRadius*e.Settings.Scale, 0)
+	e.imd.Color = pixel.RGB(0.4, 0.4, 0.4)
+	e.imd.Push(center)
+	e.imd.Circle(e.Settings.BoundaryRadius*e.Settings.Scale, 3)
+
+	// Draw the target shape.
+	e.imd.Color = pixel.RGB(0.0, 0.6, 0.2)
+	for _, t := range e.Target {
+		e.imd.Push(toScreen(t))
+	}
+	e.imd.Line(e.Settings.LinkRadius * e.Settings.Scale)
+
+	// Draw the rope.
+	e.imd.Color = pixel.RGB(0.9, 0.7, 0.3)
+	for _, p := range e.Rope {
+		e.imd.Push(toScreen(p.Position()))
+	}
+	e.imd.Line(e.Settings.LinkRadius * 2 * e.Settings.Scale)
+	for _, p := range e.Rope {
+		e.imd.Push(toScreen(p.Position()))
+		e.imd.Circle(p.Radius*e.Settings.Scale, 0)
+	}
+
+	// Draw the gripper.
+	e.imd.Color = pixel.RGB(0.8, 0.2, 0.0)
+	e.imd.Push(toScreen(e.Rope[0].Position()))
+	e.imd.Circle(e.Settings.LinkRadius*1.5*e.Settings.Scale, 0)
+
+	e.imd.Draw(target)
+}
@@ -0,0 +1,53 @@
+package gym
+
+import "testing"
+
+// TestStepIntoZeroAllocs checks that StepInto does not allocate for CartPoleEnv and BallPushEnv, with both float and fixed point physics, when Info is disabled.
+// Resets are not measured, as they are allowed to allocate.
+func TestStepIntoZeroAllocs(t *testing.T) {
+	cartPole := DefaultCartPoleSettings
+	cartPole.DisableInfo = true
+	deterministicCartPole := cartPole
+	deterministicCartPole.Deterministic = true
+	ballPush := *DefaultBallPushSettings
+	ballPush.DisableInfo = true
+	ballPush.NumBalls = 3
+	ballPush.NumObstacles = 2
+	deterministicBallPush := ballPush
+	deterministicBallPush.Deterministic = true
+
+	cases := []struct {
+		name string
+		env  StepIntoEnv
+	}{
+		{"CartPole", NewCartPoleEnv(cartPole)},
+		{"CartPoleDeterministic", NewCartPoleEnv(deterministicCartPole)},
+		{"BallPush", NewBallPushEnv(&ballPush)},
+		{"BallPushDeterministic", NewBallPushEnv(&deterministicBallPush)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var rng envRand
+			rng.seed(1)
+			actions := make([][]float64, 64)
+			for i := range actions {
+				actions[i] = make([]float64, c.env.ActionLength())
+				for j := range actions[i] {
+					actions[i][j] = rng.Float64()*2 - 1
+				}
+			}
+			c.env.Reset()
+			var data StepData
+			// The first step creates the buffers in data.
+			c.env.StepInto(actions[0], &data)
+			i := 0
+			allocs := testing.AllocsPerRun(1000, func() {
+				c.env.StepInto(actions[i%len(actions)], &data)
+				i++
+			})
+			if allocs != 0 {
+				t.Errorf("%v allocations per step", allocs)
+			}
+		})
+	}
+}
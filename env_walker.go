@@ -2,7 +2,6 @@ package gym
 
 import (
 	"math"
-	"math/rand"
 
 	"github.com/gopxl/pixel"
 	"github.com/gopxl/pixel/imdraw"
@@ -13,6 +12,11 @@ import (
 
 var _ Env = &WalkerEnv{}
 
+// WalkerEnv is a 2D walking robot simulated with box2d.
+// Given the same settings (in particular TimeStep, Substeps, VelocityIterations and PositionIterations), the same seed and the same actions,
+// two runs of the same binary produce identical trajectories, as box2d is single threaded and visits bodies in a fixed order.
+// Unlike the Deterministic modes of CartPoleEnv and BallPushEnv, box2d uses float64 maths, so trajectories are not guaranteed
+// to be identical between builds for different architectures or compiler versions.
 type WalkerEnv struct {
 	world    *b2.B2World
 	player   *Player
@@ -20,6 +24,7 @@ type WalkerEnv struct {
 	settings WalkerSettings
 	rocks    []*Box
 	imd      *imdraw.IMDraw
+	rng      envRand
 }
 
 type WalkerSettings struct {
@@ -33,6 +38,21 @@ type WalkerSettings struct {
 	JointMaxTorque   float64
 
 	StopOnFall bool
+
+	// The simulated time covered by one call to Step.
+	// This and the substep and iteration counts below are replaced with their value in DefaultWalkerSettings if they are 0 or less.
+	TimeStep float64
+	// The number of physics sub-steps that TimeStep is split into.
+	Substeps int
+	// The number of velocity solver iterations per sub-step.
+	VelocityIterations int
+	// The number of position solver iterations per sub-step.
+	PositionIterations int
+	// Downwards acceleration due to gravity. It is used exactly as given, so 0 is weightless and a negative value pulls upwards.
+	Gravity float64
+
+	// If true, Step and Reset return a nil Info map, so that no map is allocated on every step.
+	DisableInfo bool
 }
 
 var DefaultWalkerSettings = WalkerSettings{
@@ -44,10 +64,18 @@ var DefaultWalkerSettings = WalkerSettings{
 	JointMaxVelocity: 5,
 	JointMaxTorque:   15,
 	StopOnFall:       false,
+
+	TimeStep:           1.0 / 60,
+	Substeps:           1,
+	VelocityIterations: 6,
+	PositionIterations: 2,
+	Gravity:            9.81,
+	DisableInfo:        false,
 }
 
 func NewWalkerEnv(settings WalkerSettings) *WalkerEnv {
-	world := b2.MakeB2World(b2.B2Vec2{X: 0, Y: -9.81})
+	settings.applyPhysicsDefaults()
+	world := b2.MakeB2World(b2.B2Vec2{X: 0, Y: -settings.Gravity})
 	player := NewPlayer(
 		&world,
 		settings.PlayerLimbLength, settings.PlayerLimbWidth, settings.PlayerBodyLength, settings.PlayerBodyHeight,
@@ -56,24 +84,52 @@ func NewWalkerEnv(settings WalkerSettings) *WalkerEnv {
 	floor := NewBox(&world, 100, 1, false, 1, 1, colornames.Black)
 	floor.Body.SetTransform(b2.B2Vec2{X: 45}, 0)
 
-	rocks := make([]*Box, 90)
-
-	for i := range rocks {
-		xr := rand.Float64()
-		x := xr*90 + 10
-		r := (rand.Float64()*0.8 + 0.2) * xr
-		rocks[i] = NewBox(&world, r, r, false, 1, .3, colornames.Black)
-		rocks[i].Body.SetTransform(b2.B2Vec2{X: x, Y: 0.5}, rand.Float64()*6)
-	}
-
-	return &WalkerEnv{
+	e := &WalkerEnv{
 		world:    &world,
 		player:   player,
 		floor:    floor,
 		imd:      imdraw.New(nil),
 		settings: settings,
-		rocks:    rocks,
 	}
+	e.placeRocks()
+	return e
+}
+
+// Replaces the time step, substeps and iteration counts with the defaults if they are 0 or less, as they would stop the simulation from working.
+func (s *WalkerSettings) applyPhysicsDefaults() {
+	if s.TimeStep <= 0 {
+		s.TimeStep = DefaultWalkerSettings.TimeStep
+	}
+	if s.Substeps < 1 {
+		s.Substeps = DefaultWalkerSettings.Substeps
+	}
+	if s.VelocityIterations < 1 {
+		s.VelocityIterations = DefaultWalkerSettings.VelocityIterations
+	}
+	if s.PositionIterations < 1 {
+		s.PositionIterations = DefaultWalkerSettings.PositionIterations
+	}
+}
+
+// Scatters rocks randomly along the floor, replacing any existing rocks.
+func (e *WalkerEnv) placeRocks() {
+	for _, r := range e.rocks {
+		e.world.DestroyBody(r.Body)
+	}
+	e.rocks = make([]*Box, 90)
+	for i := range e.rocks {
+		xr := e.rng.Float64()
+		x := xr*90 + 10
+		r := (e.rng.Float64()*0.8 + 0.2) * xr
+		e.rocks[i] = NewBox(e.world, r, r, false, 1, .3, colornames.Black)
+		e.rocks[i].Body.SetTransform(b2.B2Vec2{X: x, Y: 0.5}, e.rng.Float64()*6)
+	}
+}
+
+// Seed implements Seedable. The rocks are regenerated from the new seed.
+func (e *WalkerEnv) Seed(seed int64) {
+	e.rng.seed(seed)
+	e.placeRocks()
 }
 
 // ActionLength implements Env.
@@ -130,23 +186,65 @@ func (e *WalkerEnv) Reset() ResetData {
 	e.player.Teleport(pixel.V(0, 4))
 	return ResetData{
 		Observation: e.getObservation(),
-		Info:        make(map[string]interface{}),
+		Info:        e.getInfo(0),
 	}
 }
 
 // Step implements Env.
 func (e *WalkerEnv) Step(action []float64) StepData {
 	e.player.SetMotorSpeeds(action[0], action[1], action[2], action[3])
-	e.world.Step(1.0/60, 6, 2)
+	subDt := e.settings.TimeStep / float64(e.settings.Substeps)
+	for i := 0; i < e.settings.Substeps; i++ {
+		e.world.Step(subDt, e.settings.VelocityIterations, e.settings.PositionIterations)
+	}
 
 	headVx := e.player.Head.Body.GetLinearVelocity().X
-	headPy := e.player.Head.Body.GetPosition().Y
+	reward := headVx * e.settings.TimeStep
 
 	return StepData{
 		Observation: e.getObservation(),
-		Reward:      headVx * (1.0 / 60),
-		Terminated:  headPy < 1.2 && e.settings.StopOnFall,
-		Info:        make(map[string]interface{}),
+		Reward:      reward,
+		Terminated:  e.hasFallen() && e.settings.StopOnFall,
+		Info:        e.getInfo(reward),
+	}
+}
+
+// The walker has fallen if its head is close to the floor.
+func (e *WalkerEnv) hasFallen() bool {
+	return e.player.Head.Body.GetPosition().Y < 1.2
+}
+
+// Gets the Info map, given the forward reward of the last step.
+func (e *WalkerEnv) getInfo(rewardForward float64) map[string]interface{} {
+	if e.settings.DisableInfo {
+		return nil
+	}
+	return map[string]interface{}{
+		InfoDistance:      e.player.Head.Body.GetPosition().X,
+		InfoHeadHeight:    e.player.Head.Body.GetPosition().Y,
+		InfoHeadVelocity:  e.player.Head.Body.GetLinearVelocity().X,
+		InfoFallen:        e.hasFallen(),
+		InfoRewardForward: rewardForward,
+	}
+}
+
+// ObservationNames implements ObservationSchema.
+func (*WalkerEnv) ObservationNames() []string {
+	return []string{
+		"left_hip_angle", "right_hip_angle", "left_knee_angle", "right_knee_angle",
+		"left_hip_velocity", "right_hip_velocity", "left_knee_velocity", "right_knee_velocity",
+		"body_angle_sin", "body_angle_cos",
+	}
+}
+
+// InfoKeys implements InfoSchema.
+func (*WalkerEnv) InfoKeys() []InfoKey {
+	return []InfoKey{
+		{InfoDistance, "float64", "How far the head is from the start line."},
+		{InfoHeadHeight, "float64", "The height of the head above the floor."},
+		{InfoHeadVelocity, "float64", "The forward velocity of the head."},
+		{InfoFallen, "bool", "True if the head is low enough to count as a fall. This terminates the episode if StopOnFall is set."},
+		{InfoRewardForward, "float64", "The reward for moving the head forwards. This is currently the only reward term, so it equals the reward."},
 	}
 }
 
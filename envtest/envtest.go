@@ -0,0 +1,304 @@
+// Package envtest checks that an implementation of gym.Env keeps the promises of the Env interface, so that custom envs work with every agent and tool in gym.
+//
+// Call Check from a test:
+//
+//	func TestMyEnv(t *testing.T) {
+//		envtest.Check(t, func() gym.Env { return NewMyEnv(DefaultMyEnvSettings) })
+//	}
+package envtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/JoshPattman/gym"
+)
+
+// Settings contains all the settings for CheckWithSettings.
+type Settings struct {
+	// The number of random steps taken while checking observations and rewards.
+	Steps int
+	// Episodes are reset after this many steps, even if the env has not terminated. 0 means they are only reset on termination.
+	MaxEpisodeSteps int
+	// The number of steps compared between two identically seeded envs, for envs that are gym.Seedable.
+	DeterminismSteps int
+	// The seed used for the random actions, and for the env if it is gym.Seedable.
+	Seed int64
+	// If false, observations outside of -1 to 1 are not reported.
+	CheckBounds bool
+	// At most this many problems of each kind are reported, so that a broken env does not flood the output.
+	MaxReports int
+}
+
+var DefaultSettings = Settings{
+	Steps:            2000,
+	MaxEpisodeSteps:  500,
+	DeterminismSteps: 300,
+	Seed:             1,
+	CheckBounds:      true,
+	MaxReports:       5,
+}
+
+// Check runs every check with DefaultSettings, and reports each problem with t.Errorf.
+// The factory must create a new, independent env every time it is called.
+func Check(t testing.TB, factory func() gym.Env) {
+	t.Helper()
+	CheckWithSettings(t, factory, DefaultSettings)
+}
+
+// CheckWithSettings runs every check, and reports each problem with t.Errorf.
+func CheckWithSettings(t testing.TB, factory func() gym.Env, settings Settings) {
+	t.Helper()
+	for _, p := range run(factory, settings) {
+		t.Errorf("%s", p)
+	}
+}
+
+// Validate runs every check, and returns an error listing every problem, or nil if there were none.
+// It is the same as CheckWithSettings, for use outside of tests.
+func Validate(factory func() gym.Env, settings Settings) error {
+	problems := run(factory, settings)
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "\n"))
+}
+
+// The checks, in the order that they run. Each one gets its own env from the factory.
+var checks = []struct {
+	name string
+	run  func(c *checker, env gym.Env)
+}{
+	{"spaces", checkSpaces},
+	{"categorical actions", checkCategorical},
+	{"random actions", checkRandomActions},
+	{"info schema", checkInfoSchema},
+	{"determinism", checkDeterminism},
+}
+
+func run(factory func() gym.Env, settings Settings) []string {
+	c := &checker{settings: settings, factory: factory, counts: make(map[string]int)}
+	for _, check := range checks {
+		c.check, c.where = check.name, ""
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					if c.where != "" {
+						c.report("panic", "panicked in %s: %v\n%s", c.where, r, debug.Stack())
+					} else {
+						c.report("panic", "panicked: %v\n%s", r, debug.Stack())
+					}
+				}
+			}()
+			check.run(c, factory())
+		}()
+	}
+	return c.problems
+}
+
+// checker collects the problems found by the checks.
+type checker struct {
+	settings Settings
+	factory  func() gym.Env
+	// The name of the check that is running, and what it was doing, if it is useful to know in case of a panic.
+	check    string
+	where    string
+	problems []string
+	// The number of problems of each kind, used to limit the reports.
+	counts map[string]int
+}
+
+// Records a problem of the given kind, unless MaxReports of that kind have already been recorded.
+func (c *checker) report(kind, format string, args ...interface{}) {
+	key := c.check + "/" + kind
+	c.counts[key]++
+	if c.settings.MaxReports > 0 && c.counts[key] > c.settings.MaxReports {
+		return
+	}
+	c.problems = append(c.problems, fmt.Sprintf("%s: %s", c.check, fmt.Sprintf(format, args...)))
+}
+
+func (c *checker) randomAction(env gym.Env, rng *rand.Rand) []float64 {
+	action := make([]float64, env.ActionLength())
+	for i := range action {
+		action[i] = rng.Float64()*2 - 1
+	}
+	return action
+}
+
+// Checks an observation from Step or Reset.
+func (c *checker) checkObservation(env gym.Env, obs []float64, where string) {
+	if len(obs) != env.ObservationLength() {
+		c.report("observation length", "%s: observation has length %d, but ObservationLength is %d", where, len(obs), env.ObservationLength())
+	}
+	for i, v := range obs {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			c.report("observation not finite", "%s: observation[%d] (%s) is %v", where, i, gym.ObservationName(env, i), v)
+		} else if c.settings.CheckBounds && (v < -1 || v > 1) {
+			c.report(fmt.Sprintf("observation bounds %d", i), "%s: observation[%d] (%s) is %v, which is outside of -1 to 1", where, i, gym.ObservationName(env, i), v)
+		}
+	}
+}
+
+func checkSpaces(c *checker, env gym.Env) {
+	if env.ActionLength() < 1 {
+		c.report("action length", "ActionLength is %d", env.ActionLength())
+	}
+	if env.ObservationLength() < 1 {
+		c.report("observation length", "ObservationLength is %d", env.ObservationLength())
+	}
+	if env.Name() == "" {
+		c.report("name", "Name is empty")
+	}
+}
+
+// numCategoricalActions gets the number of categorical actions, or 0 if the env does not support them.
+// Envs without categorical actions are allowed to panic in NumCategoricalActions.
+func numCategoricalActions(env gym.Env) (n int) {
+	defer func() {
+		if recover() != nil {
+			n = 0
+		}
+	}()
+	return env.NumCategoricalActions()
+}
+
+func checkCategorical(c *checker, env gym.Env) {
+	n := numCategoricalActions(env)
+	if n < 0 {
+		c.report("count", "NumCategoricalActions is %d", n)
+	}
+	env.Reset()
+	for a := 0; a < n; a++ {
+		action := env.ConvertCategoricalAction(a)
+		if len(action) != env.ActionLength() {
+			c.report("action length", "ConvertCategoricalAction(%d) has length %d, but ActionLength is %d", a, len(action), env.ActionLength())
+			continue
+		}
+		valid := true
+		for i, v := range action {
+			if !(v >= -1 && v <= 1) {
+				c.report("action bounds", "ConvertCategoricalAction(%d)[%d] is %v, which is outside of -1 to 1", a, i, v)
+				valid = false
+			}
+		}
+		if valid && env.Step(action).Terminated {
+			env.Reset()
+		}
+	}
+}
+
+func checkRandomActions(c *checker, env gym.Env) {
+	rng := rand.New(rand.NewSource(c.settings.Seed))
+	if seedable, ok := env.(gym.Seedable); ok {
+		seedable.Seed(c.settings.Seed)
+	}
+	c.checkObservation(env, env.Reset().Observation, "first Reset")
+	episodeSteps := 0
+	for step := 0; step < c.settings.Steps; step++ {
+		action := c.randomAction(env, rng)
+		c.where = fmt.Sprintf("Step %d with action %v", step, action)
+		data := env.Step(action)
+		where := fmt.Sprintf("Step %d", step)
+		c.checkObservation(env, data.Observation, where)
+		if math.IsNaN(data.Reward) || math.IsInf(data.Reward, 0) {
+			c.report("reward", "%s: reward is %v", where, data.Reward)
+		}
+		episodeSteps++
+		if data.Terminated || (c.settings.MaxEpisodeSteps > 0 && episodeSteps >= c.settings.MaxEpisodeSteps) {
+			c.where = fmt.Sprintf("Reset after %s", where)
+			c.checkObservation(env, env.Reset().Observation, c.where)
+			episodeSteps = 0
+		}
+	}
+}
+
+// The names of the types used in InfoKey.Type.
+var infoTypes = map[string]reflect.Type{
+	"float64": reflect.TypeOf(float64(0)),
+	"int":     reflect.TypeOf(0),
+	"bool":    reflect.TypeOf(false),
+}
+
+func checkInfoSchema(c *checker, env gym.Env) {
+	schema, ok := env.(gym.InfoSchema)
+	if !ok {
+		return
+	}
+	keys := schema.InfoKeys()
+	check := func(info map[string]interface{}, where string) {
+		// Info may be disabled, in which case it is nil.
+		if info == nil {
+			return
+		}
+		for _, k := range keys {
+			v, ok := info[k.Name]
+			if !ok {
+				c.report("missing key "+k.Name, "%s: Info has no %q, which is in InfoKeys", where, k.Name)
+			} else if reflect.TypeOf(v) != infoTypes[k.Type] {
+				c.report("key type "+k.Name, "%s: Info[%q] is a %T, but InfoKeys says it is a %s", where, k.Name, v, k.Type)
+			}
+		}
+	}
+	rng := rand.New(rand.NewSource(c.settings.Seed))
+	check(env.Reset().Info, "Reset")
+	for step := 0; step < 10; step++ {
+		data := env.Step(c.randomAction(env, rng))
+		check(data.Info, fmt.Sprintf("Step %d", step))
+		if data.Terminated {
+			check(env.Reset().Info, "Reset")
+		}
+	}
+}
+
+func checkDeterminism(c *checker, env gym.Env) {
+	if _, ok := env.(gym.Seedable); !ok {
+		return
+	}
+	other := c.factory()
+	envs := []gym.Env{env, other}
+	for _, e := range envs {
+		e.(gym.Seedable).Seed(c.settings.Seed)
+	}
+	rng := rand.New(rand.NewSource(c.settings.Seed))
+	same := func(a, b []float64) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if math.Float64bits(a[i]) != math.Float64bits(b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	obsA, obsB := env.Reset().Observation, other.Reset().Observation
+	if !same(obsA, obsB) {
+		c.report("reset", "two envs seeded with %d reset to different observations %v and %v", c.settings.Seed, obsA, obsB)
+		return
+	}
+	episodeSteps := 0
+	for step := 0; step < c.settings.DeterminismSteps; step++ {
+		action := c.randomAction(env, rng)
+		a, b := env.Step(action), other.Step(action)
+		if !same(a.Observation, b.Observation) || a.Reward != b.Reward || a.Terminated != b.Terminated {
+			c.report("step", "two envs seeded with %d diverged at Step %d", c.settings.Seed, step)
+			return
+		}
+		episodeSteps++
+		if a.Terminated || (c.settings.MaxEpisodeSteps > 0 && episodeSteps >= c.settings.MaxEpisodeSteps) {
+			if !same(env.Reset().Observation, other.Reset().Observation) {
+				c.report("reset", "two envs seeded with %d reset to different observations after Step %d", c.settings.Seed, step)
+				return
+			}
+			episodeSteps = 0
+		}
+	}
+}
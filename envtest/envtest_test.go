@@ -0,0 +1,72 @@
+package envtest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JoshPattman/gym"
+	"github.com/JoshPattman/gym/envtest"
+)
+
+func TestCartPole(t *testing.T) {
+	envtest.Check(t, func() gym.Env { return gym.NewCartPoleEnv(gym.DefaultCartPoleSettings) })
+}
+
+func TestBallPush(t *testing.T) {
+	envtest.Check(t, func() gym.Env {
+		settings := *gym.DefaultBallPushSettings
+		settings.NumBalls = 3
+		settings.NumObstacles = 2
+		return gym.NewBallPushEnv(&settings)
+	})
+}
+
+func TestWalker(t *testing.T) {
+	// The joint angles and velocities of the walker can overshoot their limits, so they are not always between -1 and 1.
+	settings := envtest.DefaultSettings
+	settings.CheckBounds = false
+	envtest.CheckWithSettings(t, func() gym.Env { return gym.NewWalkerEnv(gym.DefaultWalkerSettings) }, settings)
+}
+
+func TestPong(t *testing.T) {
+	envtest.Check(t, func() gym.Env { return gym.NewPongEnv(gym.DefaultPongSettings) })
+}
+
+func TestPongPixels(t *testing.T) {
+	envtest.Check(t, func() gym.Env {
+		settings := gym.DefaultPongSettings
+		settings.PixelObservations = true
+		return gym.NewPongEnv(settings)
+	})
+}
+
+func TestRope(t *testing.T) {
+	envtest.Check(t, func() gym.Env { return gym.NewRopeEnv(gym.DefaultRopeSettings) })
+}
+
+// brokenEnv is a CartPole whose observations are one value too long and out of range, and whose categorical actions are invalid.
+type brokenEnv struct {
+	*gym.CartPoleEnv
+}
+
+func (e brokenEnv) Step(action []float64) gym.StepData {
+	data := e.CartPoleEnv.Step(action)
+	data.Observation = append(data.Observation, 2)
+	return data
+}
+
+func (brokenEnv) ConvertCategoricalAction(int) []float64 {
+	return []float64{3}
+}
+
+func TestValidateFindsProblems(t *testing.T) {
+	err := envtest.Validate(func() gym.Env { return brokenEnv{gym.NewCartPoleEnv(gym.DefaultCartPoleSettings)} }, envtest.DefaultSettings)
+	if err == nil {
+		t.Fatal("expected problems with a broken env")
+	}
+	for _, want := range []string{"observation has length 5", "outside of -1 to 1", "ConvertCategoricalAction(0)[0] is 3"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a problem containing %q, got:\n%v", want, err)
+		}
+	}
+}
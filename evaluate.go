@@ -0,0 +1,220 @@
+package gym
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// EvaluateSettings contains all the settings for Evaluate.
+type EvaluateSettings struct {
+	// Episodes are truncated after this many steps. 0 means episodes only end when the env terminates.
+	MaxEpisodeSteps int
+	// If not nil, this is called after every reset and step, for example to render the env to a window.
+	OnStep func(env Env, episode, step int)
+	// If not empty, every episode is recorded as an animated GIF in this directory, named episode_000.gif and so on.
+	VideoDir string
+	// The size of the recorded videos in pixels.
+	VideoWidth  int
+	VideoHeight int
+	// One out of every this many steps is recorded. Less than 1 is treated as 1.
+	VideoFrameSkip int
+	// If not nil, the scalar Info values of every step are logged as "info/<key>", and the return, length and success of every episode
+	// as "episode/return", "episode/length" and "episode/success". The step counts every step of the evaluation, across all episodes.
+	Logger Logger
+}
+
+var DefaultEvaluateSettings = EvaluateSettings{
+	MaxEpisodeSteps: 1000,
+	OnStep:          nil,
+	VideoDir:        "",
+	VideoWidth:      300,
+	VideoHeight:     300,
+	VideoFrameSkip:  2,
+	Logger:          nil,
+}
+
+// EpisodeResult is the outcome of a single evaluation episode.
+type EpisodeResult struct {
+	// The total reward of the episode.
+	Return float64
+	// The number of steps in the episode.
+	Length int
+	// True if the env terminated, and false if the episode was truncated at MaxEpisodeSteps.
+	Terminated bool
+	// True if the env reported "is_success" as true on the last step.
+	Success bool
+}
+
+// EvaluationResult summarises the outcome of every episode of an evaluation.
+type EvaluationResult struct {
+	Episodes []EpisodeResult
+
+	MeanReturn float64
+	StdReturn  float64
+	MinReturn  float64
+	MaxReturn  float64
+
+	MeanLength float64
+	MinLength  int
+	MaxLength  int
+
+	// True if the env reports "is_success" in its Info. If false, SuccessRate is always 0.
+	HasSuccess bool
+	// The fraction of episodes that were successful.
+	SuccessRate float64
+}
+
+// Evaluate runs the policy in the env for the given number of episodes using DefaultEvaluateSettings, and summarises the results.
+// If the env is Seedable, it is seeded first, so the same seed and policy give the same results.
+func Evaluate(env Env, policy Policy, episodes int, seed int64) (EvaluationResult, error) {
+	return EvaluateWithSettings(env, policy, episodes, seed, DefaultEvaluateSettings)
+}
+
+// EvaluateWithSettings is the same as Evaluate, but with the given settings.
+// An error is returned if the video directory could not be created, a video could not be saved, or settings.Logger returned an error.
+func EvaluateWithSettings(env Env, policy Policy, episodes int, seed int64, settings EvaluateSettings) (EvaluationResult, error) {
+	if episodes < 1 {
+		panic("must evaluate at least one episode")
+	}
+	if seedable, ok := env.(Seedable); ok {
+		seedable.Seed(seed)
+	}
+	var recorder *GIFRecorder
+	if settings.VideoDir != "" {
+		// Create the directory up front, so that a bad path fails before the evaluation rather than after it.
+		if err := os.MkdirAll(settings.VideoDir, 0o755); err != nil {
+			return EvaluationResult{}, err
+		}
+		recorder = NewGIFRecorder(settings.VideoWidth, settings.VideoHeight)
+	}
+	frameSkip := settings.VideoFrameSkip
+	if frameSkip < 1 {
+		frameSkip = 1
+	}
+
+	result := EvaluationResult{}
+	totalSteps := 0
+	var logValues map[string]float64
+	if settings.Logger != nil {
+		logValues = make(map[string]float64)
+	}
+	for episode := 0; episode < episodes; episode++ {
+		obs := env.Reset().Observation
+		if settings.OnStep != nil {
+			settings.OnStep(env, episode, 0)
+		}
+		if recorder != nil {
+			recorder.Reset()
+			recorder.Capture(env)
+		}
+
+		ep := EpisodeResult{}
+		for settings.MaxEpisodeSteps <= 0 || ep.Length < settings.MaxEpisodeSteps {
+			stepData := env.Step(policy.Act(obs))
+			obs = stepData.Observation
+			ep.Return += stepData.Reward
+			ep.Length++
+			totalSteps++
+			if success, ok := InfoBool(stepData.Info, InfoIsSuccess); ok {
+				result.HasSuccess = true
+				ep.Success = success
+			}
+			if settings.Logger != nil && len(stepData.Info) > 0 {
+				clear(logValues)
+				InfoScalars(stepData.Info, "info/", logValues)
+				if err := settings.Logger.Log(totalSteps, logValues); err != nil {
+					return result, err
+				}
+			}
+			if settings.OnStep != nil {
+				settings.OnStep(env, episode, ep.Length)
+			}
+			if recorder != nil && ep.Length%frameSkip == 0 {
+				recorder.Capture(env)
+			}
+			if stepData.Terminated {
+				ep.Terminated = true
+				break
+			}
+		}
+		result.Episodes = append(result.Episodes, ep)
+		if settings.Logger != nil {
+			clear(logValues)
+			logValues["episode/return"] = ep.Return
+			logValues["episode/length"] = float64(ep.Length)
+			if result.HasSuccess {
+				logValues["episode/success"] = boolToFloat(ep.Success)
+			}
+			if err := settings.Logger.Log(totalSteps, logValues); err != nil {
+				return result, err
+			}
+		}
+
+		if recorder != nil {
+			path := filepath.Join(settings.VideoDir, fmt.Sprintf("episode_%03d.gif", episode))
+			if err := recorder.SaveFile(path); err != nil {
+				return result, err
+			}
+		}
+	}
+	result.summarise()
+	return result, nil
+}
+
+// Computes the summary statistics from the episode results.
+func (r *EvaluationResult) summarise() {
+	r.MinReturn, r.MaxReturn = math.Inf(1), math.Inf(-1)
+	r.MinLength, r.MaxLength = math.MaxInt, 0
+	successes := 0
+	for _, ep := range r.Episodes {
+		r.MeanReturn += ep.Return
+		r.MeanLength += float64(ep.Length)
+		r.MinReturn = math.Min(r.MinReturn, ep.Return)
+		r.MaxReturn = math.Max(r.MaxReturn, ep.Return)
+		r.MinLength = min(r.MinLength, ep.Length)
+		r.MaxLength = max(r.MaxLength, ep.Length)
+		if ep.Success {
+			successes++
+		}
+	}
+	n := float64(len(r.Episodes))
+	r.MeanReturn /= n
+	r.MeanLength /= n
+	r.SuccessRate = float64(successes) / n
+	for _, ep := range r.Episodes {
+		r.StdReturn += (ep.Return - r.MeanReturn) * (ep.Return - r.MeanReturn)
+	}
+	r.StdReturn = math.Sqrt(r.StdReturn / n)
+}
+
+// String gets a one line summary of the results.
+func (r EvaluationResult) String() string {
+	s := fmt.Sprintf("episodes=%d return=%.3f±%.3f [%.3f, %.3f] length=%.1f [%d, %d]",
+		len(r.Episodes), r.MeanReturn, r.StdReturn, r.MinReturn, r.MaxReturn, r.MeanLength, r.MinLength, r.MaxLength)
+	if r.HasSuccess {
+		s += fmt.Sprintf(" success=%.1f%%", r.SuccessRate*100)
+	}
+	return s
+}
+
+// Table gets a table with one row per episode, followed by the summary.
+func (r EvaluationResult) Table() string {
+	sb := &strings.Builder{}
+	w := tabwriter.NewWriter(sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "episode\treturn\tlength\tterminated\tsuccess\t")
+	for i, ep := range r.Episodes {
+		success := "-"
+		if r.HasSuccess {
+			success = fmt.Sprint(ep.Success)
+		}
+		fmt.Fprintf(w, "%d\t%.3f\t%d\t%v\t%s\t\n", i, ep.Return, ep.Length, ep.Terminated, success)
+	}
+	w.Flush()
+	sb.WriteString(r.String())
+	sb.WriteString("\n")
+	return sb.String()
+}
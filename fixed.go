@@ -0,0 +1,224 @@
+package gym
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/gopxl/pixel"
+)
+
+// Fixed is a signed Q32.32 fixed-point number, stored as an int64 counting units of 2^-32.
+// Every operation on Fixed uses only integer arithmetic, so results are bit-for-bit identical on every platform and compiler.
+// This is not true of float64, where the Go compiler is allowed to fuse multiply-adds on some architectures (such as arm64).
+type Fixed int64
+
+const fixedFracBits = 32
+
+// FixedOne is the Fixed representation of 1.
+const FixedOne Fixed = 1 << fixedFracBits
+
+var (
+	fixedPi     = FixedFromFloat(math.Pi)
+	fixedHalfPi = FixedFromFloat(math.Pi / 2)
+	fixedTwoPi  = FixedFromFloat(math.Pi * 2)
+)
+
+// FixedFromFloat converts a float64 to the nearest Fixed. This conversion is exact and deterministic.
+func FixedFromFloat(f float64) Fixed {
+	return Fixed(math.Round(f * (1 << fixedFracBits)))
+}
+
+// FixedFromInt converts an int to a Fixed.
+func FixedFromInt(i int) Fixed {
+	return Fixed(i) << fixedFracBits
+}
+
+// Float converts a Fixed to the nearest float64.
+func (a Fixed) Float() float64 {
+	return float64(a) / (1 << fixedFracBits)
+}
+
+// Abs gets the absolute value of a.
+func (a Fixed) Abs() Fixed {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Mul multiplies two Fixed numbers, rounding to the nearest representable value.
+func (a Fixed) Mul(b Fixed) Fixed {
+	hi, lo := bits.Mul64(fixedAbsBits(a), fixedAbsBits(b))
+	lo, carry := bits.Add64(lo, 1<<(fixedFracBits-1), 0)
+	hi += carry
+	r := Fixed(hi<<(64-fixedFracBits) | lo>>fixedFracBits)
+	if (a < 0) != (b < 0) {
+		return -r
+	}
+	return r
+}
+
+// Div divides a by b, rounding to the nearest representable value. It saturates if the result is too large, and panics if b is 0.
+func (a Fixed) Div(b Fixed) Fixed {
+	if b == 0 {
+		panic("fixed point division by zero")
+	}
+	ua, ub := fixedAbsBits(a), fixedAbsBits(b)
+	hi, lo := ua>>(64-fixedFracBits), ua<<fixedFracBits
+	var r Fixed
+	if hi >= ub {
+		r = math.MaxInt64
+	} else {
+		q, rem := bits.Div64(hi, lo, ub)
+		if rem >= ub-rem {
+			q++
+		}
+		r = Fixed(q)
+	}
+	if (a < 0) != (b < 0) {
+		return -r
+	}
+	return r
+}
+
+// Sqrt gets the square root of a, which must not be negative.
+func (a Fixed) Sqrt() Fixed {
+	if a < 0 {
+		panic("fixed point square root of negative number")
+	}
+	if a == 0 {
+		return 0
+	}
+	// Start from a power of two within a factor of two of the answer, then use Newton's method.
+	x := Fixed(1) << ((bits.Len64(uint64(a)) + fixedFracBits) / 2)
+	for i := 0; i < 64; i++ {
+		next := (x + a.Div(x)) / 2
+		if next == x || next == x+1 || next == x-1 {
+			x = next
+			break
+		}
+		x = next
+	}
+	return x
+}
+
+// Sin gets the sine of a, in radians.
+func (a Fixed) Sin() Fixed {
+	// Reduce the angle to between -Pi and Pi, then to between -Pi/2 and Pi/2.
+	x := a % fixedTwoPi
+	if x > fixedPi {
+		x -= fixedTwoPi
+	} else if x < -fixedPi {
+		x += fixedTwoPi
+	}
+	if x > fixedHalfPi {
+		x = fixedPi - x
+	} else if x < -fixedHalfPi {
+		x = -fixedPi - x
+	}
+	// Taylor series to x^17, evaluated with Horner's method. The error is below the precision of Fixed.
+	x2 := x.Mul(x)
+	r := FixedOne
+	for n := 16; n >= 2; n -= 2 {
+		r = FixedOne - x2.Mul(r)/Fixed(n*(n+1))
+	}
+	return x.Mul(r)
+}
+
+// Cos gets the cosine of a, in radians.
+func (a Fixed) Cos() Fixed {
+	return (a + fixedHalfPi).Sin()
+}
+
+// Clamp restricts a to be between min and max.
+func (a Fixed) Clamp(min, max Fixed) Fixed {
+	if a < min {
+		return min
+	}
+	if a > max {
+		return max
+	}
+	return a
+}
+
+// Gets the magnitude of a as an unsigned integer.
+func fixedAbsBits(a Fixed) uint64 {
+	if a < 0 {
+		return uint64(-a)
+	}
+	return uint64(a)
+}
+
+// fixedVec is a 2D vector of Fixed numbers, with the same operations as pixel.Vec.
+type fixedVec struct {
+	X, Y Fixed
+}
+
+func fixedVecFrom(v pixel.Vec) fixedVec {
+	return fixedVec{FixedFromFloat(v.X), FixedFromFloat(v.Y)}
+}
+
+func (v fixedVec) Vec() pixel.Vec {
+	return pixel.V(v.X.Float(), v.Y.Float())
+}
+
+func (v fixedVec) Add(u fixedVec) fixedVec {
+	return fixedVec{v.X + u.X, v.Y + u.Y}
+}
+
+func (v fixedVec) Sub(u fixedVec) fixedVec {
+	return fixedVec{v.X - u.X, v.Y - u.Y}
+}
+
+func (v fixedVec) Scaled(s Fixed) fixedVec {
+	return fixedVec{v.X.Mul(s), v.Y.Mul(s)}
+}
+
+func (v fixedVec) Dot(u fixedVec) Fixed {
+	return v.X.Mul(u.X) + v.Y.Mul(u.Y)
+}
+
+func (v fixedVec) Len() Fixed {
+	scaled, shift := v.normalized()
+	l := scaled.Dot(scaled).Sqrt()
+	if shift > 0 {
+		return l >> shift
+	}
+	return l << -shift
+}
+
+// Unit gets the vector with length 1 in the same direction. Like pixel.Vec, the zero vector becomes (1, 0).
+func (v fixedVec) Unit() fixedVec {
+	if v.X == 0 && v.Y == 0 {
+		return fixedVec{FixedOne, 0}
+	}
+	scaled, _ := v.normalized()
+	l := scaled.Dot(scaled).Sqrt()
+	return fixedVec{scaled.X.Div(l), scaled.Y.Div(l)}
+}
+
+// Scales the vector by a power of two so that its largest component is around 256.
+// This keeps precision when squaring very short vectors, and avoids overflow when squaring long ones.
+// Returns the scaled vector and the power of two it was scaled up by.
+func (v fixedVec) normalized() (fixedVec, int) {
+	m := fixedAbsBits(v.X)
+	if y := fixedAbsBits(v.Y); y > m {
+		m = y
+	}
+	if m == 0 {
+		return v, 0
+	}
+	shift := fixedFracBits + 8 - bits.Len64(m)
+	if shift > 0 {
+		return fixedVec{v.X << shift, v.Y << shift}, shift
+	}
+	return fixedVec{v.X >> -shift, v.Y >> -shift}, shift
+}
+
+func (v fixedVec) Rotated(angle Fixed) fixedVec {
+	sin, cos := angle.Sin(), angle.Cos()
+	return fixedVec{
+		v.X.Mul(cos) - v.Y.Mul(sin),
+		v.X.Mul(sin) + v.Y.Mul(cos),
+	}
+}
@@ -0,0 +1,101 @@
+package gym
+
+// InfoKey documents one key of the Info map returned by an env's Step and Reset.
+type InfoKey struct {
+	Name string
+	// The Go type of the value: "float64", "int" or "bool".
+	Type        string
+	Description string
+}
+
+// InfoSchema is implemented by envs that document their Info map.
+// Every key is present after every Step and Reset, unless Info has been disabled in the env's settings, in which case Info is nil.
+type InfoSchema interface {
+	InfoKeys() []InfoKey
+}
+
+var _ InfoSchema = &CartPoleEnv{}
+var _ InfoSchema = &BallPushEnv{}
+var _ InfoSchema = &WalkerEnv{}
+var _ InfoSchema = &PongEnv{}
+var _ InfoSchema = &RopeEnv{}
+
+// Keys shared by several envs.
+const (
+	// True if the episode counts as a success. Evaluate uses this to compute the success rate.
+	InfoIsSuccess = "is_success"
+)
+
+// Keys of CartPoleEnv.
+const (
+	InfoCartX               = "cart_x"
+	InfoCartVelocity        = "cart_velocity"
+	InfoPoleAngleRad        = "pole_angle_rad"
+	InfoPoleAngularVelocity = "pole_angular_velocity"
+	InfoOutOfBounds         = "out_of_bounds"
+	InfoPoleFallen          = "pole_fallen"
+)
+
+// Keys of BallPushEnv.
+const (
+	InfoTouchedBall   = "touched_ball"
+	InfoBallInCenter  = "ball_in_center"
+	InfoBallDist      = "ball_dist"
+	InfoAgentBallDist = "agent_ball_dist"
+	InfoStepsInTarget = "steps_in_target"
+)
+
+// Keys of WalkerEnv.
+const (
+	InfoDistance      = "distance"
+	InfoHeadHeight    = "head_height"
+	InfoHeadVelocity  = "head_velocity"
+	InfoFallen        = "fallen"
+	InfoRewardForward = "reward_forward"
+)
+
+// Keys of PongEnv.
+const (
+	InfoScoreLeft  = "score_left"
+	InfoScoreRight = "score_right"
+	InfoBallX      = "ball_x"
+	InfoBallY      = "ball_y"
+)
+
+// Keys of RopeEnv.
+const (
+	InfoShapeDistance = "shape_distance"
+)
+
+// InfoFloat gets a number from an Info map. Ints are converted to float64.
+// The second return value is false if the key is missing or is not a number.
+func InfoFloat(info map[string]interface{}, key string) (float64, bool) {
+	switch v := info[key].(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// InfoInt gets an int from an Info map. The second return value is false if the key is missing or is not an int.
+func InfoInt(info map[string]interface{}, key string) (int, bool) {
+	switch v := info[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// InfoBool gets a bool from an Info map. The second return value is false if the key is missing or is not a bool.
+func InfoBool(info map[string]interface{}, key string) (bool, bool) {
+	v, ok := info[key].(bool)
+	return v, ok
+}
@@ -0,0 +1,165 @@
+package gym
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Logger records named scalar values, such as episode returns or training losses, against a step number.
+// Loggers are not safe to use from several goroutines at once.
+type Logger interface {
+	// Log records the values at the given step.
+	Log(step int, values map[string]float64) error
+	// Close flushes any buffered values and closes the underlying file, if the logger owns one.
+	Close() error
+}
+
+var _ Logger = &CSVLogger{}
+var _ Logger = &JSONLLogger{}
+var _ Logger = MultiLogger{}
+
+// InfoScalars gets every value in an Info map that can be logged as a scalar: numbers, and bools as 0 or 1.
+// Each key is prefixed with prefix.
+func InfoScalars(info map[string]interface{}, prefix string, values map[string]float64) {
+	for k := range info {
+		if f, ok := InfoFloat(info, k); ok {
+			values[prefix+k] = f
+		} else if b, ok := InfoBool(info, k); ok {
+			values[prefix+k] = boolToFloat(b)
+		}
+	}
+}
+
+// CSVLogger writes one row per Log call, with a step column followed by one column per value.
+// The columns are fixed when the logger is created, or by the keys of the first Log call if none are given.
+// Columns that are missing from a Log call are left empty, and values that do not have a column are ignored.
+type CSVLogger struct {
+	w       *csv.Writer
+	closer  io.Closer
+	columns []string
+	row     []string
+}
+
+// NewCSVLogger creates a CSVLogger that writes to w. If w is an io.Closer, it is closed by Close.
+func NewCSVLogger(w io.Writer, columns ...string) *CSVLogger {
+	l := &CSVLogger{w: csv.NewWriter(w)}
+	if closer, ok := w.(io.Closer); ok {
+		l.closer = closer
+	}
+	if len(columns) > 0 {
+		l.setColumns(columns)
+	}
+	return l
+}
+
+func (l *CSVLogger) setColumns(columns []string) {
+	l.columns = append([]string{}, columns...)
+	l.w.Write(append([]string{"step"}, l.columns...))
+}
+
+// Log implements Logger. The row is flushed straight away, so the file can be read while it is being written.
+func (l *CSVLogger) Log(step int, values map[string]float64) error {
+	if l.columns == nil {
+		l.setColumns(sortedValueKeys(values))
+	}
+	l.row = append(l.row[:0], strconv.Itoa(step))
+	for _, c := range l.columns {
+		if v, ok := values[c]; ok {
+			l.row = append(l.row, strconv.FormatFloat(v, 'g', -1, 64))
+		} else {
+			l.row = append(l.row, "")
+		}
+	}
+	l.w.Write(l.row)
+	l.w.Flush()
+	return l.w.Error()
+}
+
+// Close implements Logger.
+func (l *CSVLogger) Close() error {
+	l.w.Flush()
+	err := l.w.Error()
+	if l.closer != nil {
+		err = errors.Join(err, l.closer.Close())
+	}
+	return err
+}
+
+// JSONLLogger writes one JSON object per line for each Log call, holding the step and every value.
+// Values that are NaN or infinite are written as null, as JSON cannot represent them.
+type JSONLLogger struct {
+	w      io.Writer
+	closer io.Closer
+	record map[string]interface{}
+}
+
+// NewJSONLLogger creates a JSONLLogger that writes to w. If w is an io.Closer, it is closed by Close.
+func NewJSONLLogger(w io.Writer) *JSONLLogger {
+	l := &JSONLLogger{w: w, record: make(map[string]interface{})}
+	if closer, ok := w.(io.Closer); ok {
+		l.closer = closer
+	}
+	return l
+}
+
+// Log implements Logger.
+func (l *JSONLLogger) Log(step int, values map[string]float64) error {
+	clear(l.record)
+	for k, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			l.record[k] = nil
+		} else {
+			l.record[k] = v
+		}
+	}
+	l.record["step"] = step
+	data, err := json.Marshal(l.record)
+	if err != nil {
+		return err
+	}
+	_, err = l.w.Write(append(data, '\n'))
+	return err
+}
+
+// Close implements Logger.
+func (l *JSONLLogger) Close() error {
+	if l.closer != nil {
+		return l.closer.Close()
+	}
+	return nil
+}
+
+// MultiLogger sends every Log call to each of its loggers.
+type MultiLogger []Logger
+
+// Log implements Logger. Every logger is called even if one fails, and all of their errors are returned.
+func (m MultiLogger) Log(step int, values map[string]float64) error {
+	var errs []error
+	for _, l := range m {
+		errs = append(errs, l.Log(step, values))
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements Logger.
+func (m MultiLogger) Close() error {
+	var errs []error
+	for _, l := range m {
+		errs = append(errs, l.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func sortedValueKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
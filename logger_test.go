@@ -0,0 +1,67 @@
+package gym
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// The same values are logged to every logger in the tests, with a missing value, an extra value and a NaN after the first row.
+var loggerTestValues = []struct {
+	step   int
+	values map[string]float64
+}{
+	{0, map[string]float64{"return": 1.5, "length": 10}},
+	{5, map[string]float64{"return": -2, "loss": 0.25}},
+	{10, map[string]float64{"return": math.NaN(), "length": 200, "loss": math.Inf(1)}},
+}
+
+func logTestValues(t *testing.T, l Logger) {
+	t.Helper()
+	for _, v := range loggerTestValues {
+		if err := l.Log(v.step, v.values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCSVLogger(t *testing.T) {
+	cases := []struct {
+		name    string
+		columns []string
+		golden  string
+	}{
+		// The columns come from the first row, so loss is never written and the missing length is left empty.
+		{"ColumnsFromFirstLog", nil, "step,length,return\n" +
+			"0,10,1.5\n" +
+			"5,,-2\n" +
+			"10,200,NaN\n"},
+		{"GivenColumns", []string{"return", "loss", "missing"}, "step,return,loss,missing\n" +
+			"0,1.5,,\n" +
+			"5,-2,0.25,\n" +
+			"10,NaN,+Inf,\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logTestValues(t, NewCSVLogger(&buf, c.columns...))
+			if buf.String() != c.golden {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), c.golden)
+			}
+		})
+	}
+}
+
+func TestJSONLLogger(t *testing.T) {
+	golden := `{"length":10,"return":1.5,"step":0}` + "\n" +
+		`{"loss":0.25,"return":-2,"step":5}` + "\n" +
+		`{"length":200,"loss":null,"return":null,"step":10}` + "\n"
+	var buf bytes.Buffer
+	logTestValues(t, NewJSONLLogger(&buf))
+	if buf.String() != golden {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), golden)
+	}
+}
@@ -0,0 +1,125 @@
+package gym
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ Logger = &TensorBoardLogger{}
+
+// TensorBoardLogger writes values as scalar summaries in TensorBoard's tfevents format, so runs can be viewed with TensorBoard
+// or any other tool that reads event files. Each Log call writes one event holding every value, tagged by its key.
+type TensorBoardLogger struct {
+	w      io.Writer
+	closer io.Closer
+	buf    []byte
+	record []byte
+}
+
+// NewTensorBoardLogger creates a TensorBoardLogger that writes an event file to w. If w is an io.Closer, it is closed by Close.
+// TensorBoard only finds event files whose names start with "events.out.tfevents.", so NewTensorBoardLoggerDir is usually easier to use.
+func NewTensorBoardLogger(w io.Writer) (*TensorBoardLogger, error) {
+	l := &TensorBoardLogger{w: w}
+	if closer, ok := w.(io.Closer); ok {
+		l.closer = closer
+	}
+	// Every event file starts with an event holding the version of the format.
+	l.buf = appendProtoDouble(l.buf[:0], 1, float64(time.Now().UnixNano())/1e9)
+	l.buf = appendProtoBytes(l.buf, 3, []byte("brain.Event:2"))
+	if err := l.writeRecord(l.buf); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// NewTensorBoardLoggerDir creates the directory if needed, and a TensorBoardLogger that writes to a new event file in it.
+func NewTensorBoardLoggerDir(dir string) (*TensorBoardLogger, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	name := fmt.Sprintf("events.out.tfevents.%d.%s", time.Now().Unix(), host)
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	l, err := NewTensorBoardLogger(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Log implements Logger.
+func (l *TensorBoardLogger) Log(step int, values map[string]float64) error {
+	// Event{wall_time: 1, step: 2, summary: 5}, where Summary{value: 1} and Value{tag: 1, simple_value: 2}.
+	var summary []byte
+	for _, k := range sortedValueKeys(values) {
+		var value []byte
+		value = appendProtoBytes(value, 1, []byte(k))
+		value = appendProtoFloat(value, 2, float32(values[k]))
+		summary = appendProtoBytes(summary, 1, value)
+	}
+	l.buf = appendProtoDouble(l.buf[:0], 1, float64(time.Now().UnixNano())/1e9)
+	l.buf = appendProtoVarint(l.buf, 2, uint64(step))
+	l.buf = appendProtoBytes(l.buf, 5, summary)
+	return l.writeRecord(l.buf)
+}
+
+// Close implements Logger.
+func (l *TensorBoardLogger) Close() error {
+	if l.closer != nil {
+		return l.closer.Close()
+	}
+	return nil
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// Writes a TFRecord: the length, a checksum of the length, the data, and a checksum of the data.
+func (l *TensorBoardLogger) writeRecord(data []byte) error {
+	l.record = binary.LittleEndian.AppendUint64(l.record[:0], uint64(len(data)))
+	l.record = binary.LittleEndian.AppendUint32(l.record, maskedCRC(l.record))
+	l.record = append(l.record, data...)
+	l.record = binary.LittleEndian.AppendUint32(l.record, maskedCRC(data))
+	_, err := l.w.Write(l.record)
+	return err
+}
+
+func maskedCRC(data []byte) uint32 {
+	crc := crc32.Checksum(data, crc32c)
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// The protobuf wire format, for the few field types that events need.
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoDouble(b []byte, field int, v float64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|1)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+func appendProtoFloat(b []byte, field int, v float32) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|5)
+	return binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,149 @@
+package gym
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+// protoField is a single field of a decoded protobuf message. Varints are in varint, fixed width values in fixed, and length delimited values in bytes.
+type protoField struct {
+	num      int
+	wireType int
+	varint   uint64
+	fixed    uint64
+	bytes    []byte
+}
+
+// Decodes the fields of a protobuf message, for the wire types that the TensorBoardLogger writes.
+func decodeProto(t *testing.T, b []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad field key")
+		}
+		b = b[n:]
+		f := protoField{num: int(key >> 3), wireType: int(key & 7)}
+		switch f.wireType {
+		case 0:
+			f.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("bad varint in field %d", f.num)
+			}
+			b = b[n:]
+		case 1:
+			f.fixed, b = binary.LittleEndian.Uint64(b), b[8:]
+		case 5:
+			f.fixed, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case 2:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				t.Fatalf("bad length in field %d", f.num)
+			}
+			f.bytes, b = b[n:n+int(length)], b[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type %d in field %d", f.wireType, f.num)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// Splits an event file into its records, checking the length and both checksums of each.
+func decodeTFRecords(t *testing.T, b []byte) [][]byte {
+	t.Helper()
+	table := crc32.MakeTable(crc32.Castagnoli)
+	mask := func(crc uint32) uint32 { return ((crc >> 15) | (crc << 17)) + 0xa282ead8 }
+	var records [][]byte
+	for len(b) > 0 {
+		if len(b) < 12 {
+			t.Fatalf("truncated record header")
+		}
+		length := binary.LittleEndian.Uint64(b)
+		if got, want := binary.LittleEndian.Uint32(b[8:]), mask(crc32.Checksum(b[:8], table)); got != want {
+			t.Fatalf("length checksum is %x, want %x", got, want)
+		}
+		b = b[12:]
+		if uint64(len(b)) < length+4 {
+			t.Fatalf("record of length %d is truncated", length)
+		}
+		data := b[:length]
+		if got, want := binary.LittleEndian.Uint32(b[length:]), mask(crc32.Checksum(data, table)); got != want {
+			t.Fatalf("data checksum is %x, want %x", got, want)
+		}
+		records = append(records, data)
+		b = b[length+4:]
+	}
+	return records
+}
+
+func TestTensorBoardLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewTensorBoardLogger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logTestValues(t, l)
+	records := decodeTFRecords(t, buf.Bytes())
+	if len(records) != 1+len(loggerTestValues) {
+		t.Fatalf("got %d records, want %d", len(records), 1+len(loggerTestValues))
+	}
+
+	// The first event holds the file version in field 3.
+	version := decodeProto(t, records[0])
+	if len(version) != 2 || version[0].num != 1 || version[0].wireType != 1 || version[1].num != 3 || string(version[1].bytes) != "brain.Event:2" {
+		t.Errorf("first event is %+v, want a wall time and file_version brain.Event:2", version)
+	}
+
+	for i, want := range loggerTestValues {
+		event := decodeProto(t, records[i+1])
+		values := map[string]float32{}
+		var step uint64
+		var hasWallTime bool
+		for _, f := range event {
+			switch {
+			case f.num == 1 && f.wireType == 1:
+				hasWallTime = math.Float64frombits(f.fixed) > 0
+			case f.num == 2 && f.wireType == 0:
+				step = f.varint
+			case f.num == 5 && f.wireType == 2:
+				for _, v := range decodeProto(t, f.bytes) {
+					var tag string
+					var simpleValue float32
+					for _, vf := range decodeProto(t, v.bytes) {
+						switch {
+						case vf.num == 1 && vf.wireType == 2:
+							tag = string(vf.bytes)
+						case vf.num == 2 && vf.wireType == 5:
+							simpleValue = math.Float32frombits(uint32(vf.fixed))
+						default:
+							t.Errorf("unexpected field %d in Summary.Value", vf.num)
+						}
+					}
+					values[tag] = simpleValue
+				}
+			default:
+				t.Errorf("unexpected field %d in event %d", f.num, i)
+			}
+		}
+		if !hasWallTime {
+			t.Errorf("event %d has no wall time", i)
+		}
+		if step != uint64(want.step) {
+			t.Errorf("event %d has step %d, want %d", i, step, want.step)
+		}
+		if len(values) != len(want.values) {
+			t.Errorf("event %d has values %v, want %v", i, values, want.values)
+		}
+		for k, v := range want.values {
+			got, ok := values[k]
+			if !ok || !(got == float32(v) || (math.IsNaN(v) && math.IsNaN(float64(got)))) {
+				t.Errorf("event %d has %s = %v, want %v", i, k, got, v)
+			}
+		}
+	}
+}
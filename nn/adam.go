@@ -0,0 +1,74 @@
+package nn
+
+import "math"
+
+// AdamSettings contains all the settings for the Adam optimiser.
+type AdamSettings struct {
+	LearningRate float64
+	// The decay rates of the running averages of the gradient and the squared gradient.
+	Beta1 float64
+	Beta2 float64
+	// Added to the denominator to avoid dividing by zero.
+	Epsilon float64
+}
+
+var DefaultAdamSettings = AdamSettings{
+	LearningRate: 0.001,
+	Beta1:        0.9,
+	Beta2:        0.999,
+	Epsilon:      1e-8,
+}
+
+// Adam is the Adam gradient descent optimiser, for a parameter vector of fixed length.
+// Its fields are all exported, so it can be saved and loaded with encoding/gob.
+type Adam struct {
+	Settings AdamSettings
+	// The running averages of the gradient and the squared gradient.
+	M []float64
+	V []float64
+	// The number of steps taken so far.
+	T int
+}
+
+// NewAdam creates an Adam optimiser for a parameter vector of the given length.
+func NewAdam(numParams int, settings AdamSettings) *Adam {
+	return &Adam{
+		Settings: settings,
+		M:        make([]float64, numParams),
+		V:        make([]float64, numParams),
+	}
+}
+
+// Step moves params one step against the gradient, to minimise the loss.
+func (a *Adam) Step(params, grads []float64) {
+	if len(params) != len(a.M) || len(grads) != len(a.M) {
+		panic("params and grads must have the length adam was created with")
+	}
+	a.T++
+	s := a.Settings
+	correction1 := 1 - math.Pow(s.Beta1, float64(a.T))
+	correction2 := 1 - math.Pow(s.Beta2, float64(a.T))
+	for i, g := range grads {
+		a.M[i] = s.Beta1*a.M[i] + (1-s.Beta1)*g
+		a.V[i] = s.Beta2*a.V[i] + (1-s.Beta2)*g*g
+		mHat := a.M[i] / correction1
+		vHat := a.V[i] / correction2
+		params[i] -= s.LearningRate * mHat / (math.Sqrt(vHat) + s.Epsilon)
+	}
+}
+
+// ClipGradNorm scales grads down so that their L2 norm is at most maxNorm, and returns the norm before clipping.
+func ClipGradNorm(grads []float64, maxNorm float64) float64 {
+	norm := 0.0
+	for _, g := range grads {
+		norm += g * g
+	}
+	norm = math.Sqrt(norm)
+	if norm > maxNorm && norm > 0 {
+		scale := maxNorm / norm
+		for i := range grads {
+			grads[i] *= scale
+		}
+	}
+	return norm
+}
@@ -0,0 +1,119 @@
+package nn
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Softmax writes the probabilities of a categorical distribution with the given logits into probs.
+func Softmax(logits, probs []float64) {
+	if len(probs) != len(logits) {
+		panic("probs must have the same length as logits")
+	}
+	maxLogit := math.Inf(-1)
+	for _, l := range logits {
+		maxLogit = math.Max(maxLogit, l)
+	}
+	sum := 0.0
+	for i, l := range logits {
+		probs[i] = math.Exp(l - maxLogit)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+}
+
+// Gets the log of the sum of the exponentials of the logits, without overflowing.
+func logSumExp(logits []float64) float64 {
+	maxLogit := math.Inf(-1)
+	for _, l := range logits {
+		maxLogit = math.Max(maxLogit, l)
+	}
+	sum := 0.0
+	for _, l := range logits {
+		sum += math.Exp(l - maxLogit)
+	}
+	return maxLogit + math.Log(sum)
+}
+
+// SampleCategorical samples an index from the categorical distribution with the given logits.
+func SampleCategorical(logits []float64, rng *rand.Rand) int {
+	lse := logSumExp(logits)
+	u := rng.Float64()
+	for i, l := range logits {
+		u -= math.Exp(l - lse)
+		if u < 0 {
+			return i
+		}
+	}
+	return len(logits) - 1
+}
+
+// CategoricalLogProb gets the log probability of the action under the categorical distribution with the given logits.
+func CategoricalLogProb(logits []float64, action int) float64 {
+	return logits[action] - logSumExp(logits)
+}
+
+// CategoricalEntropy gets the entropy of the categorical distribution with the given logits.
+func CategoricalEntropy(logits []float64) float64 {
+	lse := logSumExp(logits)
+	entropy := 0.0
+	for _, l := range logits {
+		logP := l - lse
+		entropy -= math.Exp(logP) * logP
+	}
+	return entropy
+}
+
+// CategoricalGrad adds the gradient of scale*logProb(action) + entropyScale*entropy, with respect to the logits, to grad.
+func CategoricalGrad(logits []float64, action int, scale, entropyScale float64, grad []float64) {
+	lse := logSumExp(logits)
+	entropy := CategoricalEntropy(logits)
+	for i, l := range logits {
+		logP := l - lse
+		p := math.Exp(logP)
+		if i == action {
+			grad[i] += scale
+		}
+		grad[i] -= scale * p
+		// The derivative of the entropy with respect to logit i is -p_i (log p_i + H).
+		grad[i] -= entropyScale * p * (logP + entropy)
+	}
+}
+
+// SampleGaussian samples an action from the diagonal Gaussian distribution with the given mean and log standard deviation, writing it into action.
+func SampleGaussian(mean, logStd []float64, rng *rand.Rand, action []float64) {
+	for i := range action {
+		action[i] = mean[i] + math.Exp(logStd[i])*rng.NormFloat64()
+	}
+}
+
+// GaussianLogProb gets the log probability density of the action under the diagonal Gaussian distribution with the given mean and log standard deviation.
+func GaussianLogProb(mean, logStd, action []float64) float64 {
+	logProb := 0.0
+	for i, a := range action {
+		z := (a - mean[i]) / math.Exp(logStd[i])
+		logProb += -0.5*z*z - logStd[i] - 0.5*math.Log(2*math.Pi)
+	}
+	return logProb
+}
+
+// GaussianEntropy gets the entropy of the diagonal Gaussian distribution with the given log standard deviation.
+func GaussianEntropy(logStd []float64) float64 {
+	entropy := 0.0
+	for _, s := range logStd {
+		entropy += s + 0.5*math.Log(2*math.Pi*math.E)
+	}
+	return entropy
+}
+
+// GaussianGrad adds the gradient of scale*logProb(action) + entropyScale*entropy, with respect to the mean and log standard deviation, to meanGrad and logStdGrad.
+func GaussianGrad(mean, logStd, action []float64, scale, entropyScale float64, meanGrad, logStdGrad []float64) {
+	for i, a := range action {
+		variance := math.Exp(2 * logStd[i])
+		d := a - mean[i]
+		meanGrad[i] += scale * d / variance
+		logStdGrad[i] += scale*(d*d/variance-1) + entropyScale
+	}
+}
@@ -0,0 +1,229 @@
+// Package nn provides a small dense neural network, written in pure Go, that is fast enough to train simple policies on a CPU.
+package nn
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Activation is an element-wise function applied to the output of a layer.
+type Activation int
+
+const (
+	Linear Activation = iota
+	Tanh
+	ReLU
+)
+
+// Derivative gets the derivative of the activation, given its output y.
+func (a Activation) Derivative(y float64) float64 {
+	switch a {
+	case Linear:
+		return 1
+	case Tanh:
+		return 1 - y*y
+	case ReLU:
+		if y > 0 {
+			return 1
+		}
+		return 0
+	default:
+		panic("unknown activation")
+	}
+}
+
+// Apply applies the activation to x.
+func (a Activation) Apply(x float64) float64 {
+	switch a {
+	case Linear:
+		return x
+	case Tanh:
+		return math.Tanh(x)
+	case ReLU:
+		return max(0, x)
+	default:
+		panic("unknown activation")
+	}
+}
+
+// MLP is a fully connected feed-forward network. All of its weights and biases are stored in a single flat slice, so that it can be optimised as a plain parameter vector.
+// It keeps the activations of its last forward pass, so a single MLP must not be used from more than one goroutine at once. Use Clone to get a copy for each goroutine.
+type MLP struct {
+	// The number of neurons in each layer, starting with the input layer.
+	Sizes []int
+	// The activation of every hidden layer.
+	Hidden Activation
+	// The activation of the output layer.
+	Output Activation
+	// The weights and biases of each layer in turn. The weights of a layer are stored by output then input, and are followed by its biases.
+	Params []float64
+
+	// The outputs of each layer, after the activation, from the last forward pass. The first is the input.
+	activations [][]float64
+	// The gradient of the loss with respect to the outputs of each layer, used by Backward.
+	deltas [][]float64
+}
+
+// NewMLP creates an MLP with the given layer sizes, which must include the input and output layers. All parameters are zero until Init is called.
+func NewMLP(sizes []int, hidden, output Activation) *MLP {
+	if len(sizes) < 2 {
+		panic("mlp must have at least an input and an output layer")
+	}
+	n := 0
+	for l := 1; l < len(sizes); l++ {
+		if sizes[l-1] < 1 || sizes[l] < 1 {
+			panic("every layer must have at least one neuron")
+		}
+		n += sizes[l]*sizes[l-1] + sizes[l]
+	}
+	m := &MLP{
+		Sizes:  append([]int{}, sizes...),
+		Hidden: hidden,
+		Output: output,
+		Params: make([]float64, n),
+	}
+	m.allocate()
+	return m
+}
+
+func (m *MLP) allocate() {
+	m.activations = make([][]float64, len(m.Sizes))
+	m.deltas = make([][]float64, len(m.Sizes))
+	for l, s := range m.Sizes {
+		m.activations[l] = make([]float64, s)
+		m.deltas[l] = make([]float64, s)
+	}
+}
+
+// NumParams gets the total number of weights and biases.
+func (m *MLP) NumParams() int {
+	return len(m.Params)
+}
+
+// NumInputs gets the size of the input layer.
+func (m *MLP) NumInputs() int {
+	return m.Sizes[0]
+}
+
+// NumOutputs gets the size of the output layer.
+func (m *MLP) NumOutputs() int {
+	return m.Sizes[len(m.Sizes)-1]
+}
+
+// Init sets every weight to a random value scaled by the size of its layer, and every bias to zero.
+func (m *MLP) Init(rng *rand.Rand) {
+	offset := 0
+	for l := 1; l < len(m.Sizes); l++ {
+		in, out := m.Sizes[l-1], m.Sizes[l]
+		scale := math.Sqrt(1 / float64(in))
+		if m.activation(l) == ReLU {
+			scale = math.Sqrt(2 / float64(in))
+		}
+		for i := 0; i < in*out; i++ {
+			m.Params[offset+i] = rng.NormFloat64() * scale
+		}
+		for i := 0; i < out; i++ {
+			m.Params[offset+in*out+i] = 0
+		}
+		offset += in*out + out
+	}
+}
+
+// Gets the activation of layer l.
+func (m *MLP) activation(l int) Activation {
+	if l == len(m.Sizes)-1 {
+		return m.Output
+	}
+	return m.Hidden
+}
+
+// Forward runs the network on the input. The returned slice is reused, so it is only valid until the next call to Forward.
+func (m *MLP) Forward(input []float64) []float64 {
+	if len(input) != m.Sizes[0] {
+		panic("input has wrong length for mlp")
+	}
+	if m.activations == nil {
+		// The network was created without NewMLP, for example by decoding it with encoding/gob.
+		m.allocate()
+	}
+	copy(m.activations[0], input)
+	offset := 0
+	for l := 1; l < len(m.Sizes); l++ {
+		in, out := m.activations[l-1], m.activations[l]
+		weights := m.Params[offset : offset+len(in)*len(out)]
+		biases := m.Params[offset+len(in)*len(out) : offset+len(in)*len(out)+len(out)]
+		act := m.activation(l)
+		for o := range out {
+			sum := biases[o]
+			row := weights[o*len(in) : (o+1)*len(in)]
+			for i, x := range in {
+				sum += row[i] * x
+			}
+			out[o] = act.Apply(sum)
+		}
+		offset += len(in)*len(out) + len(out)
+	}
+	return m.activations[len(m.Sizes)-1]
+}
+
+// Backward backpropagates the gradient of a loss through the last forward pass.
+// outputGrad is the gradient of the loss with respect to the output of Forward. The gradient with respect to every parameter is added to paramGrads,
+// so the gradients of several samples can be accumulated before taking a step. The returned gradient with respect to the input is reused, so it is only valid until the next call to Backward.
+func (m *MLP) Backward(outputGrad, paramGrads []float64) []float64 {
+	if len(outputGrad) != m.NumOutputs() {
+		panic("output grad has wrong length for mlp")
+	}
+	if len(paramGrads) != len(m.Params) {
+		panic("param grads has wrong length for mlp")
+	}
+	if m.activations == nil {
+		panic("must call Forward before Backward")
+	}
+	last := len(m.Sizes) - 1
+	copy(m.deltas[last], outputGrad)
+	offset := len(m.Params)
+	for l := last; l >= 1; l-- {
+		in, out := m.activations[l-1], m.activations[l]
+		offset -= len(in)*len(out) + len(out)
+		weights := m.Params[offset : offset+len(in)*len(out)]
+		weightGrads := paramGrads[offset : offset+len(in)*len(out)]
+		biasGrads := paramGrads[offset+len(in)*len(out) : offset+len(in)*len(out)+len(out)]
+		act := m.activation(l)
+		inDelta := m.deltas[l-1]
+		for i := range inDelta {
+			inDelta[i] = 0
+		}
+		for o, y := range out {
+			// Turn the gradient with respect to the output into the gradient with respect to the sum before the activation.
+			d := m.deltas[l][o] * act.Derivative(y)
+			biasGrads[o] += d
+			row := weights[o*len(in) : (o+1)*len(in)]
+			gradRow := weightGrads[o*len(in) : (o+1)*len(in)]
+			for i, x := range in {
+				gradRow[i] += d * x
+				inDelta[i] += d * row[i]
+			}
+		}
+	}
+	return m.deltas[0]
+}
+
+// Clone creates a copy of the network which shares nothing with the original.
+func (m *MLP) Clone() *MLP {
+	c := &MLP{
+		Sizes:  append([]int{}, m.Sizes...),
+		Hidden: m.Hidden,
+		Output: m.Output,
+		Params: append([]float64{}, m.Params...),
+	}
+	c.allocate()
+	return c
+}
+
+// SetParams copies params into the network.
+func (m *MLP) SetParams(params []float64) {
+	if len(params) != len(m.Params) {
+		panic("params has wrong length for mlp")
+	}
+	copy(m.Params, params)
+}
@@ -0,0 +1,54 @@
+package nn
+
+import "math"
+
+// Normaliser tracks the running mean and variance of each element of its inputs, and scales inputs to have a mean of 0 and a standard deviation of 1.
+// Until it has seen any inputs, it leaves them unchanged.
+type Normaliser struct {
+	Count float64
+	Mean  []float64
+	// The sum of squared differences from the mean, from which the variance is computed.
+	M2 []float64
+	// Normalised values are clipped to between -Clip and Clip. 0 means no clipping.
+	Clip float64
+}
+
+// NewNormaliser creates a normaliser for inputs of the given length, which clips normalised values to 5 standard deviations.
+func NewNormaliser(length int) *Normaliser {
+	return &Normaliser{
+		Mean: make([]float64, length),
+		M2:   make([]float64, length),
+		Clip: 5,
+	}
+}
+
+// Update adds an input to the running statistics.
+func (n *Normaliser) Update(x []float64) {
+	if len(x) != len(n.Mean) {
+		panic("input has wrong length for normaliser")
+	}
+	n.Count++
+	for i, v := range x {
+		d := v - n.Mean[i]
+		n.Mean[i] += d / n.Count
+		n.M2[i] += d * (v - n.Mean[i])
+	}
+}
+
+// Normalise writes the normalised input into out, which may be the same slice as x.
+func (n *Normaliser) Normalise(x, out []float64) {
+	if len(x) != len(n.Mean) || len(out) != len(n.Mean) {
+		panic("input has wrong length for normaliser")
+	}
+	if n.Count == 0 {
+		copy(out, x)
+		return
+	}
+	for i, v := range x {
+		y := (v - n.Mean[i]) / math.Sqrt(n.M2[i]/n.Count+1e-8)
+		if n.Clip > 0 {
+			y = math.Max(-n.Clip, math.Min(n.Clip, y))
+		}
+		out[i] = y
+	}
+}
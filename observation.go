@@ -0,0 +1,36 @@
+package gym
+
+import "fmt"
+
+// ObservationSchema is implemented by envs that name each value in their observation, such as "agent_x".
+// ObservationNames has the same length as the observation, and may change if the env's settings change.
+type ObservationSchema interface {
+	ObservationNames() []string
+}
+
+var _ ObservationSchema = &CartPoleEnv{}
+var _ ObservationSchema = &BallPushEnv{}
+var _ ObservationSchema = &WalkerEnv{}
+var _ ObservationSchema = &PongEnv{}
+var _ ObservationSchema = &RopeEnv{}
+
+// ObservationName gets the name of value i of the env's observation, or "obs[i]" if the env is not an ObservationSchema.
+func ObservationName(env Env, i int) string {
+	if schema, ok := env.(ObservationSchema); ok {
+		if names := schema.ObservationNames(); i >= 0 && i < len(names) {
+			return names[i]
+		}
+	}
+	return fmt.Sprintf("obs[%d]", i)
+}
+
+// Names each pixel of a grayscale image that is laid out row by row from the top, such as "pixel_3_7" for row 3 column 7.
+func pixelObservationNames(width, height int) []string {
+	names := make([]string, 0, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			names = append(names, fmt.Sprintf("pixel_%d_%d", y, x))
+		}
+	}
+	return names
+}
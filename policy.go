@@ -0,0 +1,40 @@
+package gym
+
+// Policy chooses a continuous action for each observation.
+type Policy interface {
+	// Act returns the action to take, which must be valid for the env, given the current observation.
+	Act(observation []float64) []float64
+}
+
+// CategoricalPolicy chooses one of an env's categorical actions for each observation.
+type CategoricalPolicy interface {
+	// ActCategorical returns the categorical action to take, between 0 and NumCategoricalActions-1, given the current observation.
+	ActCategorical(observation []float64) int
+}
+
+var _ Policy = PolicyFunc(nil)
+
+// PolicyFunc allows a function to be used as a Policy.
+type PolicyFunc func(observation []float64) []float64
+
+// Act implements Policy.
+func (f PolicyFunc) Act(observation []float64) []float64 {
+	return f(observation)
+}
+
+var _ CategoricalPolicy = CategoricalPolicyFunc(nil)
+
+// CategoricalPolicyFunc allows a function to be used as a CategoricalPolicy.
+type CategoricalPolicyFunc func(observation []float64) int
+
+// ActCategorical implements CategoricalPolicy.
+func (f CategoricalPolicyFunc) ActCategorical(observation []float64) int {
+	return f(observation)
+}
+
+// FromCategorical converts a CategoricalPolicy into a Policy, using the env's ConvertCategoricalAction.
+func FromCategorical(env Env, policy CategoricalPolicy) Policy {
+	return PolicyFunc(func(observation []float64) []float64 {
+		return env.ConvertCategoricalAction(policy.ActCategorical(observation))
+	})
+}
@@ -0,0 +1,155 @@
+package gym
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/gopxl/pixel"
+)
+
+var _ pixel.BasicTarget = &HeadlessCanvas{}
+
+// HeadlessCanvas is a pixel.Target that rasterizes triangles in software, without a window or OpenGL.
+// Any env can Render onto it, which allows rendering on machines without a display, such as CI.
+// Only triangle positions and colours are supported. Pictures (e.g. sprites and text) are ignored.
+//
+// When building without a display, use the 'headless' build tag to also leave out BeginRenderLoop and its OpenGL dependencies.
+type HeadlessCanvas struct {
+	width, height int
+	pixels        []pixel.RGBA
+	matrix        pixel.Matrix
+	mask          pixel.RGBA
+}
+
+// NewHeadlessCanvas creates a new transparent canvas with the given size in pixels.
+func NewHeadlessCanvas(width, height int) *HeadlessCanvas {
+	return &HeadlessCanvas{
+		width:  width,
+		height: height,
+		pixels: make([]pixel.RGBA, width*height),
+		matrix: pixel.IM,
+		mask:   pixel.Alpha(1),
+	}
+}
+
+// Size gets the width and height of the canvas in pixels.
+func (c *HeadlessCanvas) Size() (int, int) {
+	return c.width, c.height
+}
+
+// SetMatrix sets a Matrix that every point will be projected by.
+func (c *HeadlessCanvas) SetMatrix(m pixel.Matrix) {
+	c.matrix = m
+}
+
+// SetColorMask sets a color that will be multiplied with the color of everything drawn.
+func (c *HeadlessCanvas) SetColorMask(col color.Color) {
+	if col == nil {
+		c.mask = pixel.Alpha(1)
+		return
+	}
+	c.mask = pixel.ToRGBA(col)
+}
+
+// Clear fills the whole canvas with the given colour.
+func (c *HeadlessCanvas) Clear(col color.Color) {
+	rgba := pixel.ToRGBA(col)
+	for i := range c.pixels {
+		c.pixels[i] = rgba
+	}
+}
+
+// At gets the colour of the pixel at (x, y). Like image.Image, (0, 0) is the top left pixel.
+func (c *HeadlessCanvas) At(x, y int) pixel.RGBA {
+	return c.pixels[y*c.width+x]
+}
+
+// Image copies the contents of the canvas into a new image.
+func (c *HeadlessCanvas) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	for i, p := range c.pixels {
+		img.Pix[i*4+0] = uint8(math.Round(math.Min(1, p.R) * 255))
+		img.Pix[i*4+1] = uint8(math.Round(math.Min(1, p.G) * 255))
+		img.Pix[i*4+2] = uint8(math.Round(math.Min(1, p.B) * 255))
+		img.Pix[i*4+3] = uint8(math.Round(math.Min(1, p.A) * 255))
+	}
+	return img
+}
+
+// MakeTriangles implements pixel.Target.
+func (c *HeadlessCanvas) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	tri := pixel.MakeTrianglesData(t.Len())
+	tri.Update(t)
+	return &headlessTriangles{TrianglesData: tri, dst: c}
+}
+
+// MakePicture implements pixel.Target. Pictures are not supported, so drawing them has no effect.
+func (c *HeadlessCanvas) MakePicture(p pixel.Picture) pixel.TargetPicture {
+	return &headlessPicture{p}
+}
+
+// Rasterizes a single triangle, blending it over the existing contents of the canvas.
+// A pixel is covered if its center is inside the triangle, and the colour is interpolated between the vertices.
+func (c *HeadlessCanvas) fillTriangle(a, b, d pixel.Vec, ca, cb, cd pixel.RGBA) {
+	// Convert to image coordinates, where y points down.
+	a, b, d = c.toImage(c.matrix.Project(a)), c.toImage(c.matrix.Project(b)), c.toImage(c.matrix.Project(d))
+	area := edge(a, b, d)
+	if area == 0 {
+		return
+	}
+	minX := int(math.Max(0, math.Floor(math.Min(a.X, math.Min(b.X, d.X)))))
+	maxX := int(math.Min(float64(c.width-1), math.Ceil(math.Max(a.X, math.Max(b.X, d.X)))))
+	minY := int(math.Max(0, math.Floor(math.Min(a.Y, math.Min(b.Y, d.Y)))))
+	maxY := int(math.Min(float64(c.height-1), math.Ceil(math.Max(a.Y, math.Max(b.Y, d.Y)))))
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			p := pixel.V(float64(x)+0.5, float64(y)+0.5)
+			wa, wb, wd := edge(b, d, p)/area, edge(d, a, p)/area, edge(a, b, p)/area
+			if wa < 0 || wb < 0 || wd < 0 {
+				continue
+			}
+			src := ca.Scaled(wa).Add(cb.Scaled(wb)).Add(cd.Scaled(wd)).Mul(c.mask)
+			i := y*c.width + x
+			// Colours are alpha-premultiplied, so this is the standard 'over' operator.
+			c.pixels[i] = src.Add(c.pixels[i].Scaled(1 - src.A))
+		}
+	}
+}
+
+// Converts a point from pixel coordinates (y up) to image coordinates (y down).
+func (c *HeadlessCanvas) toImage(v pixel.Vec) pixel.Vec {
+	return pixel.V(v.X, float64(c.height)-v.Y)
+}
+
+// Twice the signed area of the triangle abc.
+func edge(a, b, c pixel.Vec) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// headlessTriangles are triangles that draw onto a HeadlessCanvas.
+type headlessTriangles struct {
+	*pixel.TrianglesData
+	dst *HeadlessCanvas
+}
+
+// Draw implements pixel.TargetTriangles.
+func (t *headlessTriangles) Draw() {
+	td := *t.TrianglesData
+	for i := 0; i+2 < len(td); i += 3 {
+		t.dst.fillTriangle(
+			td[i].Position, td[i+1].Position, td[i+2].Position,
+			td[i].Color, td[i+1].Color, td[i+2].Color,
+		)
+	}
+}
+
+// headlessPicture is a picture that a HeadlessCanvas cannot draw.
+type headlessPicture struct {
+	pixel.Picture
+}
+
+// Draw implements pixel.TargetPicture. The picture is ignored, and only the triangles are drawn.
+func (p *headlessPicture) Draw(t pixel.TargetTriangles) {
+	t.Draw()
+}
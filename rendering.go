@@ -1,3 +1,5 @@
+//go:build !headless
+
 package gym
 
 import (
@@ -5,20 +7,20 @@ import (
 	"github.com/gopxl/pixel/pixelgl"
 )
 
-// Create a window, and start rendering an environment in a loop.
+// Create a window, and start rendering an environment (either an Env or a MultiAgentEnv) in a loop.
 // This function will not update the environment, you should do this inside stepFunc.
 //
 // Each frame, the stepFunc is called (uses vsync so will proobably be 60/s).
 // If stepFunc returns true, the render loop will exit.
 // stepFunc also takes a refrence to the window as a parameter, to allow for keypress checking.
-func BeginRenderLoop(e Env, stepFunc func(win *pixelgl.Window) bool) {
+func BeginRenderLoop(e Renderable, stepFunc func(win *pixelgl.Window) bool) {
 	pixelgl.Run(func() {
 		renderLoop(e, stepFunc)
 	})
 }
 
 // Helper function for BeginRenderLoop.
-func renderLoop(e Env, stepFunc func(win *pixelgl.Window) bool) {
+func renderLoop(e Renderable, stepFunc func(win *pixelgl.Window) bool) {
 	dx, dy := e.RenderSize()
 	cfg := pixelgl.WindowConfig{
 		Title:  "Gym: " + e.Name(),
@@ -0,0 +1,204 @@
+package gym
+
+// RolloutPolicy is a stochastic policy with a value function, as used by actor-critic methods such as PPO and A2C.
+type RolloutPolicy interface {
+	// SampleAction samples an action for the observation, and returns it along with its log probability and the estimated value of the observation.
+	SampleAction(observation []float64) (action []float64, logProb, value float64)
+	// Value estimates the value of an observation. It is used to bootstrap returns when a rollout or episode is cut short.
+	Value(observation []float64) float64
+}
+
+// Rollout collects fixed length batches of experience from one or more envs, which are stepped in lockstep.
+// Every slice is indexed by step then env: index t*len(Envs)+i holds step t of env i.
+// Episodes carry on from one call of Collect to the next, so the envs are only reset when an episode ends.
+type Rollout struct {
+	Envs []Env
+	// The number of steps taken in each env per call to Collect.
+	Steps int
+	// If above 0, episodes are truncated after this many steps. Unlike termination, the value of the final observation is still bootstrapped from.
+	MaxEpisodeSteps int
+
+	Observations [][]float64
+	Actions      [][]float64
+	Rewards      []float64
+	LogProbs     []float64
+	// The estimated value of each observation.
+	Values []float64
+	// The estimated value of the observation after each step. This is 0 if the env terminated.
+	NextValues []float64
+	// True if the env terminated after the step.
+	Terminated []bool
+	// True if the episode was truncated after the step, because it reached MaxEpisodeSteps.
+	Truncated []bool
+
+	// These are filled in by ComputeGAE or ComputeReturns.
+	Advantages []float64
+	Returns    []float64
+
+	// The total reward and length of each episode that finished during the last call to Collect.
+	EpisodeReturns []float64
+	EpisodeLengths []int
+
+	observations   [][]float64
+	episodeReturns []float64
+	episodeLengths []int
+}
+
+// NewRollout creates a rollout that collects the given number of steps from each env. The envs are reset.
+func NewRollout(steps int, envs ...Env) *Rollout {
+	if len(envs) == 0 {
+		panic("rollout needs at least one env")
+	}
+	n := steps * len(envs)
+	r := &Rollout{
+		Envs:           envs,
+		Steps:          steps,
+		Observations:   make([][]float64, n),
+		Actions:        make([][]float64, n),
+		Rewards:        make([]float64, n),
+		LogProbs:       make([]float64, n),
+		Values:         make([]float64, n),
+		NextValues:     make([]float64, n),
+		Terminated:     make([]bool, n),
+		Truncated:      make([]bool, n),
+		Advantages:     make([]float64, n),
+		Returns:        make([]float64, n),
+		observations:   make([][]float64, len(envs)),
+		episodeReturns: make([]float64, len(envs)),
+		episodeLengths: make([]int, len(envs)),
+	}
+	for i, env := range envs {
+		r.observations[i] = env.Reset().Observation
+	}
+	return r
+}
+
+// Len gets the total number of steps stored, across all envs.
+func (r *Rollout) Len() int {
+	return len(r.Rewards)
+}
+
+// Collect steps every env Steps times using the policy, overwriting the previously collected data.
+func (r *Rollout) Collect(policy RolloutPolicy) {
+	r.EpisodeReturns = r.EpisodeReturns[:0]
+	r.EpisodeLengths = r.EpisodeLengths[:0]
+	numEnvs := len(r.Envs)
+	for t := 0; t < r.Steps; t++ {
+		for i, env := range r.Envs {
+			idx := t*numEnvs + i
+			obs := r.observations[i]
+			action, logProb, value := policy.SampleAction(obs)
+			stepData := env.Step(action)
+
+			r.Observations[idx] = append(r.Observations[idx][:0], obs...)
+			r.Actions[idx] = append(r.Actions[idx][:0], action...)
+			r.Rewards[idx] = stepData.Reward
+			r.LogProbs[idx] = logProb
+			r.Values[idx] = value
+			r.Terminated[idx] = stepData.Terminated
+
+			r.episodeReturns[i] += stepData.Reward
+			r.episodeLengths[i]++
+			r.Truncated[idx] = !stepData.Terminated && r.MaxEpisodeSteps > 0 && r.episodeLengths[i] >= r.MaxEpisodeSteps
+
+			// The next value is filled in from the next step's value, unless the episode or rollout ends here.
+			switch {
+			case stepData.Terminated:
+				r.NextValues[idx] = 0
+			case r.Truncated[idx] || t == r.Steps-1:
+				r.NextValues[idx] = policy.Value(stepData.Observation)
+			}
+			if t > 0 && !r.Terminated[idx-numEnvs] && !r.Truncated[idx-numEnvs] {
+				r.NextValues[idx-numEnvs] = value
+			}
+
+			if stepData.Terminated || r.Truncated[idx] {
+				r.EpisodeReturns = append(r.EpisodeReturns, r.episodeReturns[i])
+				r.EpisodeLengths = append(r.EpisodeLengths, r.episodeLengths[i])
+				r.episodeReturns[i] = 0
+				r.episodeLengths[i] = 0
+				r.observations[i] = env.Reset().Observation
+			} else {
+				r.observations[i] = stepData.Observation
+			}
+		}
+	}
+}
+
+// ComputeGAE fills in Advantages with generalised advantage estimates (Schulman et al. 2016), and Returns with the advantages plus the values.
+// Gamma is the discount factor, and lambda trades off bias (0) against variance (1).
+func (r *Rollout) ComputeGAE(gamma, lambda float64) {
+	r.forEachEnv(func(rewards, values, nextValues []float64, episodeEnds []bool, out []float64) {
+		copy(out, GAE(rewards, values, nextValues, episodeEnds, gamma, lambda))
+	}, r.Advantages)
+	for i := range r.Returns {
+		r.Returns[i] = r.Advantages[i] + r.Values[i]
+	}
+}
+
+// ComputeReturns fills in Returns with the discounted return from each step, bootstrapping from NextValues where an episode is cut short.
+// Advantages are set to the returns minus the values.
+func (r *Rollout) ComputeReturns(gamma float64) {
+	r.forEachEnv(func(rewards, values, nextValues []float64, episodeEnds []bool, out []float64) {
+		copy(out, DiscountedReturns(rewards, nextValues, episodeEnds, gamma))
+	}, r.Returns)
+	for i := range r.Advantages {
+		r.Advantages[i] = r.Returns[i] - r.Values[i]
+	}
+}
+
+// Splits the data into a separate trajectory for each env, calls f on each one, and scatters what f writes to its out slice back into out.
+func (r *Rollout) forEachEnv(f func(rewards, values, nextValues []float64, episodeEnds []bool, out []float64), out []float64) {
+	numEnvs := len(r.Envs)
+	rewards := make([]float64, r.Steps)
+	values := make([]float64, r.Steps)
+	nextValues := make([]float64, r.Steps)
+	episodeEnds := make([]bool, r.Steps)
+	envOut := make([]float64, r.Steps)
+	for i := range r.Envs {
+		for t := 0; t < r.Steps; t++ {
+			idx := t*numEnvs + i
+			rewards[t] = r.Rewards[idx]
+			values[t] = r.Values[idx]
+			nextValues[t] = r.NextValues[idx]
+			episodeEnds[t] = r.Terminated[idx] || r.Truncated[idx]
+		}
+		f(rewards, values, nextValues, episodeEnds, envOut)
+		for t := 0; t < r.Steps; t++ {
+			out[t*numEnvs+i] = envOut[t]
+		}
+	}
+}
+
+// DiscountedReturns computes the discounted return from each step of a single env's trajectory.
+// nextValues[t] is the estimated value of the observation after step t, which should be 0 if the env terminated.
+// episodeEnds[t] is true if the episode ended after step t, whether it terminated or was truncated.
+// The return is bootstrapped from nextValues at the end of each episode and at the end of the trajectory.
+func DiscountedReturns(rewards, nextValues []float64, episodeEnds []bool, gamma float64) []float64 {
+	returns := make([]float64, len(rewards))
+	next := 0.0
+	for t := len(rewards) - 1; t >= 0; t-- {
+		if episodeEnds[t] || t == len(rewards)-1 {
+			next = nextValues[t]
+		}
+		returns[t] = rewards[t] + gamma*next
+		next = returns[t]
+	}
+	return returns
+}
+
+// GAE computes generalised advantage estimates for each step of a single env's trajectory.
+// values[t] is the estimated value of the observation before step t, and the other arguments are the same as DiscountedReturns.
+func GAE(rewards, values, nextValues []float64, episodeEnds []bool, gamma, lambda float64) []float64 {
+	advantages := make([]float64, len(rewards))
+	next := 0.0
+	for t := len(rewards) - 1; t >= 0; t-- {
+		if episodeEnds[t] || t == len(rewards)-1 {
+			next = 0
+		}
+		delta := rewards[t] + gamma*nextValues[t] - values[t]
+		advantages[t] = delta + gamma*lambda*next
+		next = advantages[t]
+	}
+	return advantages
+}
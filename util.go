@@ -27,16 +27,33 @@ func decay(x float64) float64 {
 
 func clampAll(vals ...float64) []float64 {
 	clampedVals := make([]float64, len(vals))
+	copy(clampedVals, vals)
+	clampInPlace(clampedVals)
+	return clampedVals
+}
+
+// Clamps every value to between -1 and 1, without allocating.
+func clampInPlace(vals []float64) {
 	for i := range vals {
-		clampedVals[i] = vals[i]
-		if clampedVals[i] < -1 {
-			clampedVals[i] = -1
+		if vals[i] < -1 {
+			vals[i] = -1
 		}
-		if clampedVals[i] > 1 {
-			clampedVals[i] = 1
+		if vals[i] > 1 {
+			vals[i] = 1
 		}
 	}
-	return clampedVals
+}
+
+// Gets the map to write Info into: nil if Info is disabled, or the old map emptied, so that it can be reused without allocating.
+func reuseInfo(info map[string]interface{}, disabled bool, size int) map[string]interface{} {
+	if disabled {
+		return nil
+	}
+	if info == nil {
+		return make(map[string]interface{}, size)
+	}
+	clear(info)
+	return info
 }
 
 func normInt(std float64) int {
@@ -46,3 +63,34 @@ func normInt(std float64) int {
 	}
 	return int(math.Round(rawRand))
 }
+
+// envRand is the source of randomness for an environment.
+// Until it is seeded, it uses the global math/rand functions, so environments behave the same as they always have.
+type envRand struct {
+	r *rand.Rand
+}
+
+func (e *envRand) seed(seed int64) {
+	e.r = rand.New(rand.NewSource(seed))
+}
+
+func (e *envRand) Float64() float64 {
+	if e.r == nil {
+		return rand.Float64()
+	}
+	return e.r.Float64()
+}
+
+func (e *envRand) Intn(n int) int {
+	if e.r == nil {
+		return rand.Intn(n)
+	}
+	return e.r.Intn(n)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -39,6 +39,12 @@ func (p *VerletParticle) Velocity() pixel.Vec {
 	return p.recentVelocity
 }
 
+// Get the velocity implied by the current and previous positions.
+// Unlike Velocity, this immediately reflects calls to SetVelocity.
+func (p *VerletParticle) implicitVelocity() pixel.Vec {
+	return p.currentPosition.Sub(p.previousPosition).Scaled(1 / p.dt)
+}
+
 // Get the acceleration of the particle.
 func (p *VerletParticle) Acceleration() pixel.Vec {
 	return p.recentAcceleration
@@ -81,3 +87,127 @@ func (p *VerletParticle) StepParticle() {
 	p.currentForce = pixel.ZV
 	p.currentImpulse = pixel.ZV
 }
+
+// Pushes a particle of the given radius back inside a circular boundary centered on the origin.
+// Returns true if the particle was touching the boundary.
+func containParticle(p *VerletParticle, radius, boundaryRadius float64) bool {
+	overlap := p.Position().Len() + radius - boundaryRadius
+	if overlap > 0 {
+		p.SlideToPosition(p.Position().Unit().Scaled(boundaryRadius - radius))
+		return true
+	}
+	return false
+}
+
+// Pushes a particle of the given radius out of a solid circle.
+// Returns true if the particle was touching the circle.
+func excludeParticle(p *VerletParticle, radius float64, center pixel.Vec, circleRadius float64) bool {
+	offset := p.Position().Sub(center)
+	if offset.Len() >= circleRadius+radius {
+		return false
+	}
+	if offset.Len() == 0 {
+		offset = pixel.V(1, 0)
+	}
+	p.SlideToPosition(center.Add(offset.Unit().Scaled(circleRadius + radius)))
+	return true
+}
+
+// Separates two overlapping circular particles by moving each of them half of the overlap.
+// Returns true if the particles were touching.
+func collideParticles(a, b *VerletParticle, radiusA, radiusB float64) bool {
+	overlap := (radiusA + radiusB) - a.Position().Sub(b.Position()).Len()
+	if overlap > 0 {
+		correctionVec := a.Position().Sub(b.Position()).Unit().Scaled(overlap / 2)
+		a.SlideToPosition(a.Position().Add(correctionVec))
+		b.SlideToPosition(b.Position().Sub(correctionVec))
+		return true
+	}
+	return false
+}
+
+// fixedParticle is a copy of a VerletParticle's state in fixed point, used by the deterministic physics modes.
+// Changes are made to the copy, then written back to the particle with store.
+type fixedParticle struct {
+	p        *VerletParticle
+	current  fixedVec
+	previous fixedVec
+	velocity fixedVec
+	force    fixedVec
+	mass     Fixed
+	dt       Fixed
+}
+
+func loadFixedParticle(p *VerletParticle) *fixedParticle {
+	f := &fixedParticle{}
+	f.load(p)
+	return f
+}
+
+// Overwrites f with a copy of the particle's state, so that f can be reused without allocating.
+func (f *fixedParticle) load(p *VerletParticle) {
+	dt := FixedFromFloat(p.dt)
+	impulse := fixedVecFrom(p.currentImpulse)
+	*f = fixedParticle{
+		p:        p,
+		current:  fixedVecFrom(p.currentPosition),
+		previous: fixedVecFrom(p.previousPosition),
+		velocity: fixedVecFrom(p.recentVelocity),
+		force:    fixedVecFrom(p.currentForce).Add(fixedVec{impulse.X.Div(dt), impulse.Y.Div(dt)}),
+		mass:     FixedFromFloat(p.mass),
+		dt:       dt,
+	}
+}
+
+// Writes the fixed point state back to the particle. Every value is exactly representable as a float64, so no rounding happens.
+func (f *fixedParticle) store() {
+	f.p.currentPosition = f.current.Vec()
+	f.p.previousPosition = f.previous.Vec()
+	f.p.recentVelocity = f.velocity.Vec()
+	f.p.currentForce = f.force.Vec()
+	f.p.currentImpulse = pixel.ZV
+}
+
+// The same as VerletParticle.StepParticle, in fixed point.
+func (f *fixedParticle) step() {
+	acceleration := fixedVec{f.force.X.Div(f.mass), f.force.Y.Div(f.mass)}
+	next := f.current.Add(f.current).Sub(f.previous).Add(acceleration.Scaled(f.dt.Mul(f.dt)))
+	f.velocity = next.Sub(f.previous)
+	f.velocity = fixedVec{f.velocity.X.Div(2 * f.dt), f.velocity.Y.Div(2 * f.dt)}
+	f.p.recentAcceleration = acceleration.Vec()
+	f.previous = f.current
+	f.current = next
+	f.force = fixedVec{}
+}
+
+// The same as containParticle, in fixed point.
+func containFixedParticle(p *fixedParticle, radius, boundaryRadius Fixed) bool {
+	if p.current.Len()+radius-boundaryRadius > 0 {
+		p.current = p.current.Unit().Scaled(boundaryRadius - radius)
+		return true
+	}
+	return false
+}
+
+// The same as excludeParticle, in fixed point.
+func excludeFixedParticle(p *fixedParticle, radius Fixed, center fixedVec, circleRadius Fixed) bool {
+	offset := p.current.Sub(center)
+	if offset.Len() >= circleRadius+radius {
+		return false
+	}
+	p.current = center.Add(offset.Unit().Scaled(circleRadius + radius))
+	return true
+}
+
+// The same as collideParticles, in fixed point.
+func collideFixedParticles(a, b *fixedParticle, radiusA, radiusB Fixed) bool {
+	offset := a.current.Sub(b.current)
+	overlap := radiusA + radiusB - offset.Len()
+	if overlap > 0 {
+		correction := offset.Unit().Scaled(overlap / 2)
+		a.current = a.current.Add(correction)
+		b.current = b.current.Sub(correction)
+		return true
+	}
+	return false
+}
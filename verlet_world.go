@@ -0,0 +1,358 @@
+package gym
+
+import (
+	"math"
+
+	"github.com/gopxl/pixel"
+)
+
+// VerletWorldSettings contains all the settings for a VerletWorld.
+type VerletWorldSettings struct {
+	// Acceleration due to gravity, applied to every particle.
+	Gravity pixel.Vec
+	// Drag force per unit of velocity, applied against the direction of motion.
+	LinearDrag float64
+	// Drag force per unit of velocity squared, applied against the direction of motion.
+	QuadraticDrag float64
+	// The number of times constraints and collisions are relaxed per step. More iterations give stiffer constraints.
+	ConstraintIterations int
+	// The size of the cells used for finding colliding particles. If 0, it is chosen from the largest particle radius.
+	CellSize float64
+	// The delta time between steps.
+	DeltaTime float64
+}
+
+var DefaultVerletWorldSettings = VerletWorldSettings{
+	Gravity:              pixel.ZV,
+	LinearDrag:           0,
+	QuadraticDrag:        0,
+	ConstraintIterations: 8,
+	CellSize:             0,
+	DeltaTime:            1.0 / 60.0,
+}
+
+// WorldParticle is a circular VerletParticle that lives in a VerletWorld.
+type WorldParticle struct {
+	*VerletParticle
+	// The radius of the particle, used for collisions.
+	Radius float64
+	// If true, the particle is not moved by forces, constraints or collisions. It can still be moved with SlideToPosition.
+	Pinned bool
+	// If true, the particle passes through other particles and obstacles.
+	NoCollide bool
+}
+
+// Gets the inverse mass of the particle, which is 0 for pinned particles.
+func (p *WorldParticle) invMass() float64 {
+	if p.Pinned {
+		return 0
+	}
+	return 1 / p.Mass()
+}
+
+// VerletConstraint is a constraint between particles in a VerletWorld.
+// Relax should move the particles part of the way towards satisfying the constraint. It is called many times per step.
+type VerletConstraint interface {
+	Relax()
+}
+
+// VerletObstacle is a static shape that particles in a VerletWorld collide with.
+// Resolve should push the particle out of the obstacle, and return true if they were touching.
+type VerletObstacle interface {
+	Resolve(p *WorldParticle) bool
+}
+
+// VerletWorld owns a set of particles, the constraints between them, and static obstacles, and steps them all forwards together.
+type VerletWorld struct {
+	Particles   []*WorldParticle
+	Constraints []VerletConstraint
+	Obstacles   []VerletObstacle
+	Settings    VerletWorldSettings
+
+	cells map[[2]int][]int
+}
+
+// NewVerletWorld creates a new empty world with the given settings.
+func NewVerletWorld(settings VerletWorldSettings) *VerletWorld {
+	return &VerletWorld{
+		Settings: settings,
+		cells:    make(map[[2]int][]int),
+	}
+}
+
+// AddParticle creates a new particle at the given position, adds it to the world, and returns it.
+func (w *VerletWorld) AddParticle(position pixel.Vec, mass, radius float64) *WorldParticle {
+	p := &WorldParticle{
+		VerletParticle: NewVerletParticle(position, mass, w.Settings.DeltaTime),
+		Radius:         radius,
+	}
+	w.Particles = append(w.Particles, p)
+	return p
+}
+
+// AddConstraint adds a constraint to the world.
+func (w *VerletWorld) AddConstraint(c VerletConstraint) {
+	w.Constraints = append(w.Constraints, c)
+}
+
+// AddObstacle adds a static obstacle to the world.
+func (w *VerletWorld) AddObstacle(o VerletObstacle) {
+	w.Obstacles = append(w.Obstacles, o)
+}
+
+// Step steps the world forward in time by one time step.
+// Particles are integrated first, then constraints and collisions are relaxed ConstraintIterations times.
+func (w *VerletWorld) Step() {
+	for _, p := range w.Particles {
+		if p.Pinned {
+			// Pinned particles keep whatever position they were given, but forget any velocity.
+			p.SetVelocity(pixel.ZV)
+			continue
+		}
+		vel := p.Velocity()
+		dragForce := vel.Scaled(-w.Settings.LinearDrag).Sub(vel.Scaled(w.Settings.QuadraticDrag * vel.Len()))
+		p.ApplyForce(w.Settings.Gravity.Scaled(p.Mass()).Add(dragForce))
+		p.StepParticle()
+	}
+
+	for i := 0; i < w.Settings.ConstraintIterations; i++ {
+		for _, c := range w.Constraints {
+			c.Relax()
+		}
+		// The constraints may have moved particles into other cells, so the grid must be rebuilt before every collision pass.
+		w.rebuildCells()
+		w.collideParticles()
+		for _, p := range w.Particles {
+			if p.NoCollide || p.Pinned {
+				continue
+			}
+			for _, o := range w.Obstacles {
+				o.Resolve(p)
+			}
+		}
+	}
+}
+
+// Gets the cell size to use for the spatial hash.
+func (w *VerletWorld) cellSize() float64 {
+	if w.Settings.CellSize > 0 {
+		return w.Settings.CellSize
+	}
+	maxRadius := 0.0
+	for _, p := range w.Particles {
+		maxRadius = math.Max(maxRadius, p.Radius)
+	}
+	if maxRadius == 0 {
+		return 1
+	}
+	return maxRadius * 2
+}
+
+func (w *VerletWorld) cellOf(pos pixel.Vec, size float64) [2]int {
+	return [2]int{int(math.Floor(pos.X / size)), int(math.Floor(pos.Y / size))}
+}
+
+// Sorts the particles into a grid so that only particles in neighbouring cells need to be checked for collisions.
+// Cells that were used last time keep their slices, as this is called many times per step.
+func (w *VerletWorld) rebuildCells() {
+	for k, v := range w.cells {
+		if len(v) == 0 {
+			delete(w.cells, k)
+		} else {
+			w.cells[k] = v[:0]
+		}
+	}
+	size := w.cellSize()
+	for i, p := range w.Particles {
+		if p.NoCollide {
+			continue
+		}
+		cell := w.cellOf(p.Position(), size)
+		w.cells[cell] = append(w.cells[cell], i)
+	}
+}
+
+// Separates every pair of overlapping particles, splitting the correction by inverse mass.
+func (w *VerletWorld) collideParticles() {
+	size := w.cellSize()
+	for i, a := range w.Particles {
+		if a.NoCollide {
+			continue
+		}
+		cell := w.cellOf(a.Position(), size)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range w.cells[[2]int{cell[0] + dx, cell[1] + dy}] {
+					if j <= i {
+						continue
+					}
+					minDist := a.Radius + w.Particles[j].Radius
+					moveApart(a, w.Particles[j], minDist, 1, func(current float64) bool { return current < minDist })
+				}
+			}
+		}
+	}
+}
+
+// Moves two particles along the line between them by stiffness times the difference between their distance and the target distance.
+// The particles are only moved if shouldMove returns true for their current distance.
+func moveApart(a, b *WorldParticle, dist, stiffness float64, shouldMove func(float64) bool) bool {
+	offset := a.Position().Sub(b.Position())
+	current := offset.Len()
+	if !shouldMove(current) {
+		return false
+	}
+	invA, invB := a.invMass(), b.invMass()
+	if invA+invB == 0 {
+		return false
+	}
+	dir := offset.Unit()
+	if current == 0 {
+		dir = pixel.V(1, 0)
+	}
+	correction := dir.Scaled((dist - current) * stiffness / (invA + invB))
+	a.SlideToPosition(a.Position().Add(correction.Scaled(invA)))
+	b.SlideToPosition(b.Position().Sub(correction.Scaled(invB)))
+	return true
+}
+
+var _ VerletConstraint = &DistanceConstraint{}
+
+// DistanceConstraint keeps two particles a fixed distance apart, like a rigid rod.
+type DistanceConstraint struct {
+	A, B   *WorldParticle
+	Length float64
+	// How much of the error is corrected each relaxation, between 0 and 1. Lower values make the rod springy.
+	Stiffness float64
+}
+
+// NewDistanceConstraint creates a fully stiff distance constraint with the current distance between the particles as its length.
+func NewDistanceConstraint(a, b *WorldParticle) *DistanceConstraint {
+	return &DistanceConstraint{a, b, a.Position().Sub(b.Position()).Len(), 1}
+}
+
+// Relax implements VerletConstraint.
+func (c *DistanceConstraint) Relax() {
+	moveApart(c.A, c.B, c.Length, c.Stiffness, func(float64) bool { return true })
+}
+
+var _ VerletConstraint = &RopeConstraint{}
+
+// RopeConstraint stops two particles from getting further apart than a maximum length, like a piece of rope.
+// Unlike DistanceConstraint, the particles are free to move closer together.
+type RopeConstraint struct {
+	A, B      *WorldParticle
+	MaxLength float64
+}
+
+// Relax implements VerletConstraint.
+func (c *RopeConstraint) Relax() {
+	moveApart(c.A, c.B, c.MaxLength, 1, func(current float64) bool { return current > c.MaxLength })
+}
+
+var _ VerletConstraint = &AngleConstraint{}
+
+// AngleConstraint keeps the angle ABC (the angle at B between A and C) within a range, which stops chains of particles from bending too much.
+// Angles are unsigned, so a straight line is Pi and a fully folded chain is 0.
+type AngleConstraint struct {
+	A, B, C  *WorldParticle
+	MinAngle float64
+	MaxAngle float64
+	// How much of the error is corrected each relaxation, between 0 and 1.
+	Stiffness float64
+}
+
+// Relax implements VerletConstraint.
+// The error is corrected by rotating A and C around B, splitting the rotation by inverse mass.
+func (c *AngleConstraint) Relax() {
+	ba := c.A.Position().Sub(c.B.Position())
+	bc := c.C.Position().Sub(c.B.Position())
+	cross := ba.Cross(bc)
+	angle := math.Atan2(math.Abs(cross), ba.Dot(bc))
+	var diff float64
+	if angle < c.MinAngle {
+		diff = c.MinAngle - angle
+	} else if angle > c.MaxAngle {
+		diff = c.MaxAngle - angle
+	} else {
+		return
+	}
+	invA, invC := c.A.invMass(), c.C.invMass()
+	if invA+invC == 0 {
+		return
+	}
+	// If C is anticlockwise of A, rotating A clockwise and C anticlockwise opens the angle.
+	sign := 1.0
+	if cross < 0 {
+		sign = -1.0
+	}
+	diff *= c.Stiffness * sign / (invA + invC)
+	c.A.SlideToPosition(c.B.Position().Add(ba.Rotated(-diff * invA)))
+	c.C.SlideToPosition(c.B.Position().Add(bc.Rotated(diff * invC)))
+}
+
+var _ VerletObstacle = &CircleObstacle{}
+
+// CircleObstacle is a solid circle that particles cannot enter.
+type CircleObstacle struct {
+	Center pixel.Vec
+	Radius float64
+}
+
+// Resolve implements VerletObstacle.
+func (o *CircleObstacle) Resolve(p *WorldParticle) bool {
+	return excludeParticle(p.VerletParticle, p.Radius, o.Center, o.Radius)
+}
+
+var _ VerletObstacle = &BoundaryObstacle{}
+
+// BoundaryObstacle is a circular arena that particles cannot leave.
+type BoundaryObstacle struct {
+	Center pixel.Vec
+	Radius float64
+}
+
+// Resolve implements VerletObstacle.
+func (o *BoundaryObstacle) Resolve(p *WorldParticle) bool {
+	offset := p.Position().Sub(o.Center)
+	if offset.Len()+p.Radius <= o.Radius {
+		return false
+	}
+	p.SlideToPosition(o.Center.Add(offset.Unit().Scaled(o.Radius - p.Radius)))
+	return true
+}
+
+var _ VerletObstacle = &SegmentObstacle{}
+
+// SegmentObstacle is a solid line segment with a thickness, such as a wall or the floor.
+type SegmentObstacle struct {
+	A, B      pixel.Vec
+	Thickness float64
+}
+
+// Resolve implements VerletObstacle.
+func (o *SegmentObstacle) Resolve(p *WorldParticle) bool {
+	closest := closestPointOnSegment(o.A, o.B, p.Position())
+	offset := p.Position().Sub(closest)
+	minDist := o.Thickness/2 + p.Radius
+	if offset.Len() >= minDist {
+		return false
+	}
+	if offset.Len() == 0 {
+		// The particle is exactly on the line, so push it out along the normal.
+		offset = o.B.Sub(o.A).Normal()
+	}
+	p.SlideToPosition(closest.Add(offset.Unit().Scaled(minDist)))
+	return true
+}
+
+// Finds the point on the line segment ab that is closest to p.
+func closestPointOnSegment(a, b, p pixel.Vec) pixel.Vec {
+	ab := b.Sub(a)
+	lenSq := ab.Dot(ab)
+	if lenSq == 0 {
+		return a
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/lenSq))
+	return a.Add(ab.Scaled(t))
+}
@@ -0,0 +1,162 @@
+package gym
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gopxl/pixel"
+)
+
+// Creates a world with no gravity or drag, so that particles only move because of constraints, obstacles and collisions.
+func newTestVerletWorld(iterations int) *VerletWorld {
+	settings := DefaultVerletWorldSettings
+	settings.ConstraintIterations = iterations
+	return NewVerletWorld(settings)
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestDistanceConstraint(t *testing.T) {
+	w := newTestVerletWorld(1)
+	a := w.AddParticle(pixel.V(0, 0), 1, 0.1)
+	b := w.AddParticle(pixel.V(3, 0), 1, 0.1)
+	w.AddConstraint(&DistanceConstraint{a, b, 1, 1})
+	w.Step()
+	if d := b.Position().Sub(a.Position()).Len(); !approxEqual(d, 1) {
+		t.Errorf("distance is %v, want 1", d)
+	}
+	// The particles have equal mass, so they should have moved by the same amount.
+	if !approxEqual(a.Position().X, 1) || !approxEqual(b.Position().X, 2) {
+		t.Errorf("particles are at %v and %v, want (1, 0) and (2, 0)", a.Position(), b.Position())
+	}
+}
+
+func TestDistanceConstraintPinned(t *testing.T) {
+	w := newTestVerletWorld(1)
+	a := w.AddParticle(pixel.V(0, 0), 1, 0.1)
+	a.Pinned = true
+	b := w.AddParticle(pixel.V(0, 3), 1, 0.1)
+	w.AddConstraint(&DistanceConstraint{a, b, 1, 1})
+	w.Step()
+	if a.Position() != pixel.ZV {
+		t.Errorf("pinned particle moved to %v", a.Position())
+	}
+	if !approxEqual(b.Position().Y, 1) {
+		t.Errorf("free particle is at %v, want (0, 1)", b.Position())
+	}
+}
+
+func TestRopeConstraint(t *testing.T) {
+	cases := []struct {
+		name      string
+		start     float64
+		wantFinal float64
+	}{
+		{"Slack", 1, 1},
+		{"Stretched", 4, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := newTestVerletWorld(1)
+			a := w.AddParticle(pixel.V(0, 0), 1, 0.1)
+			b := w.AddParticle(pixel.V(c.start, 0), 1, 0.1)
+			w.AddConstraint(&RopeConstraint{a, b, 2})
+			w.Step()
+			if d := b.Position().Sub(a.Position()).Len(); !approxEqual(d, c.wantFinal) {
+				t.Errorf("distance is %v, want %v", d, c.wantFinal)
+			}
+		})
+	}
+}
+
+func TestAngleConstraint(t *testing.T) {
+	w := newTestVerletWorld(20)
+	a := w.AddParticle(pixel.V(1, 0.1), 1, 0.1)
+	b := w.AddParticle(pixel.V(0, 0), 1, 0.1)
+	b.Pinned = true
+	c := w.AddParticle(pixel.V(1, -0.1), 1, 0.1)
+	w.AddConstraint(&AngleConstraint{a, b, c, math.Pi / 2, math.Pi, 1})
+	w.Step()
+	ba, bc := a.Position().Sub(b.Position()), c.Position().Sub(b.Position())
+	angle := math.Atan2(math.Abs(ba.Cross(bc)), ba.Dot(bc))
+	if angle < math.Pi/2-1e-6 {
+		t.Errorf("angle is %v, want at least %v", angle, math.Pi/2)
+	}
+	// Rotating around B must not change the distances from it.
+	if !approxEqual(ba.Len(), math.Hypot(1, 0.1)) || !approxEqual(bc.Len(), math.Hypot(1, 0.1)) {
+		t.Errorf("distances from B changed to %v and %v", ba.Len(), bc.Len())
+	}
+}
+
+func TestObstacles(t *testing.T) {
+	cases := []struct {
+		name     string
+		obstacle VerletObstacle
+		start    pixel.Vec
+		want     pixel.Vec
+	}{
+		{"CircleInside", &CircleObstacle{Center: pixel.ZV, Radius: 2}, pixel.V(1, 0), pixel.V(2.5, 0)},
+		{"CircleOutside", &CircleObstacle{Center: pixel.ZV, Radius: 2}, pixel.V(0, 3), pixel.V(0, 3)},
+		{"BoundaryOutside", &BoundaryObstacle{Center: pixel.ZV, Radius: 5}, pixel.V(0, 7), pixel.V(0, 4.5)},
+		{"BoundaryInside", &BoundaryObstacle{Center: pixel.ZV, Radius: 5}, pixel.V(1, 1), pixel.V(1, 1)},
+		{"SegmentAbove", &SegmentObstacle{A: pixel.V(-5, 0), B: pixel.V(5, 0), Thickness: 1}, pixel.V(2, 0.25), pixel.V(2, 1)},
+		{"SegmentEnd", &SegmentObstacle{A: pixel.V(-5, 0), B: pixel.V(5, 0), Thickness: 1}, pixel.V(5.5, 0), pixel.V(6, 0)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := newTestVerletWorld(1)
+			p := w.AddParticle(c.start, 1, 0.5)
+			w.AddObstacle(c.obstacle)
+			w.Step()
+			if !approxEqual(p.Position().X, c.want.X) || !approxEqual(p.Position().Y, c.want.Y) {
+				t.Errorf("particle is at %v, want %v", p.Position(), c.want)
+			}
+		})
+	}
+}
+
+func TestObstacleIgnoresNoCollide(t *testing.T) {
+	w := newTestVerletWorld(1)
+	p := w.AddParticle(pixel.V(1, 0), 1, 0.5)
+	p.NoCollide = true
+	w.AddObstacle(&CircleObstacle{Center: pixel.ZV, Radius: 2})
+	w.Step()
+	if p.Position() != pixel.V(1, 0) {
+		t.Errorf("NoCollide particle moved to %v", p.Position())
+	}
+}
+
+func TestParticleCollisions(t *testing.T) {
+	w := newTestVerletWorld(1)
+	a := w.AddParticle(pixel.V(0, 0), 1, 0.5)
+	b := w.AddParticle(pixel.V(0.4, 0), 1, 0.5)
+	ghost := w.AddParticle(pixel.V(0.2, 0), 1, 0.5)
+	ghost.NoCollide = true
+	w.Step()
+	if d := b.Position().Sub(a.Position()).Len(); !approxEqual(d, 1) {
+		t.Errorf("distance is %v, want 1", d)
+	}
+	if ghost.Position() != pixel.V(0.2, 0) {
+		t.Errorf("NoCollide particle moved to %v", ghost.Position())
+	}
+}
+
+// A particle that a constraint pulls into another cell during the step must still collide with the particles in that cell.
+func TestParticleCollisionsAfterConstraintMove(t *testing.T) {
+	settings := DefaultVerletWorldSettings
+	settings.ConstraintIterations = 1
+	settings.CellSize = 1
+	w := NewVerletWorld(settings)
+	// The order matters: the particle that is already in place comes first, so it is the one that looks for neighbours.
+	resting := w.AddParticle(pixel.V(8.2, 0), 1, 0.5)
+	moving := w.AddParticle(pixel.V(0, 0), 1, 0.5)
+	anchor := w.AddParticle(pixel.V(10, 0), 1, 0.5)
+	anchor.Pinned = true
+	w.AddConstraint(&RopeConstraint{moving, anchor, 2})
+	w.Step()
+	if d := resting.Position().Sub(moving.Position()).Len(); d < 1-1e-9 {
+		t.Errorf("particles overlap, distance is %v, want at least 1", d)
+	}
+}
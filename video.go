@@ -0,0 +1,75 @@
+package gym
+
+import (
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+
+	"github.com/gopxl/pixel"
+)
+
+// GIFRecorder captures frames of an env, rendered with a HeadlessCanvas, and saves them as an animated GIF.
+// It does not need a window, so it works on machines without a display.
+type GIFRecorder struct {
+	// The delay between frames, in hundredths of a second.
+	Delay  int
+	canvas *HeadlessCanvas
+	frames []*image.Paletted
+}
+
+// NewGIFRecorder creates a recorder that captures frames at the given size in pixels.
+func NewGIFRecorder(width, height int) *GIFRecorder {
+	return &GIFRecorder{
+		Delay:  2,
+		canvas: NewHeadlessCanvas(width, height),
+	}
+}
+
+// Capture renders the env, scaled to fit the recorder, and adds it as a frame.
+func (r *GIFRecorder) Capture(env Renderable) {
+	rsx, rsy := env.RenderSize()
+	w, h := r.canvas.Size()
+	r.canvas.Clear(pixel.RGB(0, 0, 0))
+	r.canvas.SetMatrix(pixel.IM.ScaledXY(pixel.ZV, pixel.V(float64(w)/rsx, float64(h)/rsy)))
+	env.Render(r.canvas)
+
+	src := r.canvas.Image()
+	frame := image.NewPaletted(src.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(frame, frame.Bounds(), src, image.Point{})
+	r.frames = append(r.frames, frame)
+}
+
+// NumFrames gets the number of frames captured so far.
+func (r *GIFRecorder) NumFrames() int {
+	return len(r.frames)
+}
+
+// Reset removes every captured frame.
+func (r *GIFRecorder) Reset() {
+	r.frames = r.frames[:0]
+}
+
+// Save writes the captured frames to w as an animated GIF.
+func (r *GIFRecorder) Save(w io.Writer) error {
+	delays := make([]int, len(r.frames))
+	for i := range delays {
+		delays[i] = r.Delay
+	}
+	return gif.EncodeAll(w, &gif.GIF{Image: r.frames, Delay: delays})
+}
+
+// SaveFile writes the captured frames to a GIF file, overwriting it if it exists.
+func (r *GIFRecorder) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
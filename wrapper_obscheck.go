@@ -0,0 +1,202 @@
+package gym
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+var _ StepIntoEnv = &ObservationCheckEnv{}
+
+// ObservationCheckSettings contains all the settings for the ObservationCheckEnv wrapper.
+type ObservationCheckSettings struct {
+	// If true, out of range values are clamped to between -1 and 1 after they have been recorded, so the agent never sees them.
+	// NaN is replaced with 0.
+	Clamp bool
+	// If true, the first out of range value causes a panic, which is useful for finding the exact step that produced it.
+	PanicOnViolation bool
+	// At most this many violations are kept in Violations. Later ones are still counted in the stats. 0 means no limit.
+	MaxViolations int
+}
+
+var DefaultObservationCheckSettings = ObservationCheckSettings{
+	Clamp:            false,
+	PanicOnViolation: false,
+	MaxViolations:    1000,
+}
+
+// ObservationViolation is a single observation value that was outside of -1 to 1, or was NaN.
+type ObservationViolation struct {
+	// The episode that it happened in, counting from 0 at the first Reset.
+	Episode int
+	// The step of the episode that it happened on, or -1 if it was the observation from Reset.
+	Step  int
+	Index int
+	// The name of the value, from ObservationName.
+	Name  string
+	Value float64
+}
+
+func (v ObservationViolation) String() string {
+	where := fmt.Sprintf("step %d", v.Step)
+	if v.Step < 0 {
+		where = "reset"
+	}
+	return fmt.Sprintf("episode %d %s: observation[%d] (%s) is %v", v.Episode, where, v.Index, v.Name, v.Value)
+}
+
+// ObservationStats are statistics of a single value of the observation, over every observation seen by an ObservationCheckEnv.
+type ObservationStats struct {
+	Name  string
+	Count int
+	Min   float64
+	Max   float64
+	Mean  float64
+	// The number of values that were outside of -1 to 1, or were NaN. NaN is not included in any of the other stats.
+	OutOfRange int
+
+	// The sum of squared differences from the mean, updated with Welford's algorithm.
+	m2 float64
+}
+
+// Std gets the standard deviation of the value.
+func (s ObservationStats) Std() float64 {
+	if s.Count < 2 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.Count))
+}
+
+func (s *ObservationStats) add(v float64) {
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count++
+	delta := v - s.Mean
+	s.Mean += delta / float64(s.Count)
+	s.m2 += delta * (v - s.Mean)
+}
+
+// ObservationCheckEnv wraps any Env and checks that every observation it produces is between -1 and 1, as the Env interface promises.
+// Every value outside of that range is recorded with its index and name, and statistics of each value are collected,
+// so that the normalisation of an env can be fixed. Optionally, out of range values can be clamped instead.
+type ObservationCheckEnv struct {
+	Env
+	Settings ObservationCheckSettings
+	// The recorded violations, in the order that they happened.
+	Violations []ObservationViolation
+	// The statistics of each value of the observation, indexed by value.
+	Stats []ObservationStats
+
+	episode, step int
+}
+
+// NewObservationCheckEnv wraps the given env so that its observations are checked.
+func NewObservationCheckEnv(env Env, settings ObservationCheckSettings) *ObservationCheckEnv {
+	return &ObservationCheckEnv{
+		Env:      env,
+		Settings: settings,
+		episode:  -1,
+	}
+}
+
+// Step implements Env.
+func (e *ObservationCheckEnv) Step(action []float64) StepData {
+	stepData := e.Env.Step(action)
+	e.check(stepData.Observation)
+	e.step++
+	return stepData
+}
+
+// StepInto implements StepIntoEnv. It only avoids allocating if the wrapped env is a StepIntoEnv.
+func (e *ObservationCheckEnv) StepInto(action []float64, data *StepData) {
+	StepInto(e.Env, action, data)
+	e.check(data.Observation)
+	e.step++
+}
+
+// Reset implements Env.
+func (e *ObservationCheckEnv) Reset() ResetData {
+	resetData := e.Env.Reset()
+	e.episode++
+	e.step = -1
+	e.check(resetData.Observation)
+	e.step = 0
+	return resetData
+}
+
+// NumViolations gets the total number of out of range values seen, including those that were not kept because of MaxViolations.
+func (e *ObservationCheckEnv) NumViolations() int {
+	n := 0
+	for _, s := range e.Stats {
+		n += s.OutOfRange
+	}
+	return n
+}
+
+// ClearStats forgets all of the violations and statistics collected so far.
+func (e *ObservationCheckEnv) ClearStats() {
+	e.Violations = nil
+	e.Stats = nil
+}
+
+// Records the observation, then clamps it if enabled.
+func (e *ObservationCheckEnv) check(obs []float64) {
+	if len(e.Stats) != len(obs) {
+		// The observation can change length if the wrapped env's settings change, in which case the old stats no longer make sense.
+		e.Stats = make([]ObservationStats, len(obs))
+		var names []string
+		if schema, ok := e.Env.(ObservationSchema); ok {
+			names = schema.ObservationNames()
+		}
+		for i := range e.Stats {
+			if i < len(names) {
+				e.Stats[i].Name = names[i]
+			} else {
+				e.Stats[i].Name = fmt.Sprintf("obs[%d]", i)
+			}
+		}
+	}
+	for i, v := range obs {
+		stats := &e.Stats[i]
+		if !math.IsNaN(v) {
+			stats.add(v)
+		}
+		if v >= -1 && v <= 1 {
+			continue
+		}
+		stats.OutOfRange++
+		violation := ObservationViolation{Episode: e.episode, Step: e.step, Index: i, Name: stats.Name, Value: v}
+		if e.Settings.PanicOnViolation {
+			panic(fmt.Sprintf("%s: %v", e.Env.Name(), violation))
+		}
+		if e.Settings.MaxViolations <= 0 || len(e.Violations) < e.Settings.MaxViolations {
+			e.Violations = append(e.Violations, violation)
+		}
+		if e.Settings.Clamp {
+			if math.IsNaN(v) {
+				obs[i] = 0
+			} else {
+				obs[i] = math.Max(-1, math.Min(1, v))
+			}
+		}
+	}
+}
+
+// WriteReport writes the statistics of every value of the observation as an aligned table, marking the values that went out of range.
+func (e *ObservationCheckEnv) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "index\tname\tmin\tmax\tmean\tstd\tout of range\t")
+	for i, s := range e.Stats {
+		mark := ""
+		if s.OutOfRange > 0 {
+			mark = fmt.Sprint(s.OutOfRange)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%.4f\t%.4f\t%.4f\t%.4f\t%s\t\n", i, s.Name, s.Min, s.Max, s.Mean, s.Std(), mark)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,165 @@
+package gym
+
+import (
+	"math"
+	"testing"
+)
+
+// scriptedObsEnv is a CartPole whose observations are replaced by a fixed script, and which only names its first two values.
+// The CartPole is embedded as an Env so that its StepInto is not promoted, and the script is used for every step.
+type scriptedObsEnv struct {
+	Env
+	obs  [][]float64
+	next int
+}
+
+func (e *scriptedObsEnv) nextObs() []float64 {
+	obs := append([]float64(nil), e.obs[e.next]...)
+	e.next++
+	return obs
+}
+
+func (e *scriptedObsEnv) Reset() ResetData {
+	data := e.Env.Reset()
+	data.Observation = e.nextObs()
+	return data
+}
+
+func (e *scriptedObsEnv) Step(action []float64) StepData {
+	data := e.Env.Step(action)
+	data.Observation = e.nextObs()
+	return data
+}
+
+func (*scriptedObsEnv) ObservationNames() []string {
+	return []string{"a", "b"}
+}
+
+// The script is two episodes, the first with a reset and two steps, and the second with only a reset.
+var obsCheckScript = [][]float64{
+	{0, 0.5, 2},
+	{math.NaN(), -3, 0.5},
+	{1, 1, 1},
+	{-1, 0, 5},
+}
+
+// Runs the script through an ObservationCheckEnv, returning the observations that it produced.
+func runObsCheckScript(settings ObservationCheckSettings) (*ObservationCheckEnv, [][]float64) {
+	e := NewObservationCheckEnv(&scriptedObsEnv{Env: NewCartPoleEnv(DefaultCartPoleSettings), obs: obsCheckScript}, settings)
+	action := []float64{0}
+	var seen [][]float64
+	seen = append(seen, e.Reset().Observation)
+	seen = append(seen, e.Step(action).Observation)
+	var data StepData
+	e.StepInto(action, &data)
+	seen = append(seen, data.Observation)
+	seen = append(seen, e.Reset().Observation)
+	return e, seen
+}
+
+func TestObservationCheckViolations(t *testing.T) {
+	settings := DefaultObservationCheckSettings
+	settings.MaxViolations = 0
+	e, _ := runObsCheckScript(settings)
+	want := []ObservationViolation{
+		{Episode: 0, Step: -1, Index: 2, Name: "obs[2]", Value: 2},
+		{Episode: 0, Step: 0, Index: 0, Name: "a", Value: math.NaN()},
+		{Episode: 0, Step: 0, Index: 1, Name: "b", Value: -3},
+		{Episode: 1, Step: -1, Index: 2, Name: "obs[2]", Value: 5},
+	}
+	if len(e.Violations) != len(want) {
+		t.Fatalf("got %d violations, want %d: %v", len(e.Violations), len(want), e.Violations)
+	}
+	for i, got := range e.Violations {
+		w := want[i]
+		sameValue := got.Value == w.Value || (math.IsNaN(got.Value) && math.IsNaN(w.Value))
+		if got.Episode != w.Episode || got.Step != w.Step || got.Index != w.Index || got.Name != w.Name || !sameValue {
+			t.Errorf("violation %d is %v, want %v", i, got, w)
+		}
+	}
+	if e.NumViolations() != 4 {
+		t.Errorf("NumViolations is %d, want 4", e.NumViolations())
+	}
+}
+
+func TestObservationCheckMaxViolations(t *testing.T) {
+	settings := DefaultObservationCheckSettings
+	settings.MaxViolations = 2
+	e, _ := runObsCheckScript(settings)
+	if len(e.Violations) != 2 {
+		t.Errorf("kept %d violations, want 2", len(e.Violations))
+	}
+	if e.NumViolations() != 4 {
+		t.Errorf("NumViolations is %d, want 4", e.NumViolations())
+	}
+	for i, want := range []int{1, 1, 2} {
+		if e.Stats[i].OutOfRange != want {
+			t.Errorf("value %d has OutOfRange %d, want %d", i, e.Stats[i].OutOfRange, want)
+		}
+	}
+}
+
+func TestObservationCheckClamp(t *testing.T) {
+	settings := DefaultObservationCheckSettings
+	settings.Clamp = true
+	_, seen := runObsCheckScript(settings)
+	want := [][]float64{
+		{0, 0.5, 1},
+		{0, -1, 0.5},
+		{1, 1, 1},
+		{-1, 0, 1},
+	}
+	for i := range want {
+		for j := range want[i] {
+			if seen[i][j] != want[i][j] {
+				t.Errorf("observation %d is %v, want %v", i, seen[i], want[i])
+				break
+			}
+		}
+	}
+
+	// Without Clamp, the observations must be passed through untouched.
+	_, seen = runObsCheckScript(DefaultObservationCheckSettings)
+	if !math.IsNaN(seen[1][0]) || seen[1][1] != -3 || seen[3][2] != 5 {
+		t.Errorf("observations were changed without Clamp: %v", seen)
+	}
+}
+
+func TestObservationCheckStats(t *testing.T) {
+	e, _ := runObsCheckScript(DefaultObservationCheckSettings)
+	for i, stats := range e.Stats {
+		// Work out the stats directly with two passes, skipping NaN.
+		var values []float64
+		for _, obs := range obsCheckScript {
+			if !math.IsNaN(obs[i]) {
+				values = append(values, obs[i])
+			}
+		}
+		mean, min, max := 0.0, math.Inf(1), math.Inf(-1)
+		for _, v := range values {
+			mean += v / float64(len(values))
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean) / float64(len(values))
+		}
+		if stats.Count != len(values) || stats.Min != min || stats.Max != max {
+			t.Errorf("value %d has count %d min %v max %v, want %d %v %v", i, stats.Count, stats.Min, stats.Max, len(values), min, max)
+		}
+		if math.Abs(stats.Mean-mean) > 1e-12 || math.Abs(stats.Std()-math.Sqrt(variance)) > 1e-12 {
+			t.Errorf("value %d has mean %v std %v, want %v %v", i, stats.Mean, stats.Std(), mean, math.Sqrt(variance))
+		}
+	}
+}
+
+func TestObservationCheckPanic(t *testing.T) {
+	settings := DefaultObservationCheckSettings
+	settings.PanicOnViolation = true
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic on the first violation")
+		}
+	}()
+	runObsCheckScript(settings)
+}
@@ -0,0 +1,160 @@
+package gym
+
+import (
+	"github.com/gopxl/pixel"
+)
+
+var _ Env = &PixelObservationEnv{}
+
+// ChannelOrder specifies how the channels of an image are laid out in a flattened observation.
+type ChannelOrder int
+
+const (
+	// ChannelsLast lays out images as [height][width][channel], which is the common layout for image libraries.
+	ChannelsLast ChannelOrder = iota
+	// ChannelsFirst lays out images as [channel][height][width], which is the common layout for convolutional networks.
+	ChannelsFirst
+)
+
+// PixelObservationSettings contains all the settings for the PixelObservationEnv wrapper.
+type PixelObservationSettings struct {
+	// The resolution of the observed image in pixels.
+	Width  int
+	Height int
+	// If true, the image has a single luminance channel. Otherwise it has three channels: red, green and blue.
+	Grayscale bool
+	// The layout of the flattened observation.
+	ChannelOrder ChannelOrder
+	// The image is rendered at this many times the resolution in each direction, then averaged down. This smooths out thin lines.
+	Supersample int
+}
+
+var DefaultPixelObservationSettings = PixelObservationSettings{
+	Width:        84,
+	Height:       84,
+	Grayscale:    true,
+	ChannelOrder: ChannelsLast,
+	Supersample:  2,
+}
+
+// ImageTensor is an image stored as a flat list of floats between -1 and 1, along with its shape.
+type ImageTensor struct {
+	Data     []float64
+	Width    int
+	Height   int
+	Channels int
+	Order    ChannelOrder
+}
+
+// Index gets the index into Data of channel c of the pixel at (x, y). (0, 0) is the top left pixel.
+func (t ImageTensor) Index(x, y, c int) int {
+	if t.Order == ChannelsFirst {
+		return (c*t.Height+y)*t.Width + x
+	}
+	return (y*t.Width+x)*t.Channels + c
+}
+
+// At gets channel c of the pixel at (x, y). (0, 0) is the top left pixel.
+func (t ImageTensor) At(x, y, c int) float64 {
+	return t.Data[t.Index(x, y, c)]
+}
+
+// PixelObservationEnv wraps any Env, replacing its observation with an image of what the Env renders.
+// Rendering is done with a HeadlessCanvas, so no window or display is needed.
+// The observation is the flattened image, with every channel mapped from [0, 1] to [-1, 1].
+type PixelObservationEnv struct {
+	Env
+	Settings PixelObservationSettings
+
+	canvas *HeadlessCanvas
+}
+
+// NewPixelObservationEnv wraps the given env so that it produces pixel observations.
+func NewPixelObservationEnv(env Env, settings PixelObservationSettings) *PixelObservationEnv {
+	if settings.Supersample < 1 {
+		settings.Supersample = 1
+	}
+	return &PixelObservationEnv{
+		Env:      env,
+		Settings: settings,
+		canvas:   NewHeadlessCanvas(settings.Width*settings.Supersample, settings.Height*settings.Supersample),
+	}
+}
+
+// Step implements Env.
+func (e *PixelObservationEnv) Step(action []float64) StepData {
+	stepData := e.Env.Step(action)
+	stepData.Observation = e.getObservation()
+	return stepData
+}
+
+// Reset implements Env.
+func (e *PixelObservationEnv) Reset() ResetData {
+	resetData := e.Env.Reset()
+	resetData.Observation = e.getObservation()
+	return resetData
+}
+
+// ObservationLength implements Env.
+func (e *PixelObservationEnv) ObservationLength() int {
+	return e.Settings.Width * e.Settings.Height * e.channels()
+}
+
+// Tensor wraps an observation produced by this env with its image shape.
+func (e *PixelObservationEnv) Tensor(observation []float64) ImageTensor {
+	return ImageTensor{
+		Data:     observation,
+		Width:    e.Settings.Width,
+		Height:   e.Settings.Height,
+		Channels: e.channels(),
+		Order:    e.Settings.ChannelOrder,
+	}
+}
+
+// Canvas gets the full resolution canvas that the wrapped env was last rendered onto.
+func (e *PixelObservationEnv) Canvas() *HeadlessCanvas {
+	return e.canvas
+}
+
+func (e *PixelObservationEnv) channels() int {
+	if e.Settings.Grayscale {
+		return 1
+	}
+	return 3
+}
+
+func (e *PixelObservationEnv) getObservation() []float64 {
+	// Render the env, scaled to fit the canvas.
+	rsx, rsy := e.Env.RenderSize()
+	cw, ch := e.canvas.Size()
+	e.canvas.Clear(pixel.RGB(0, 0, 0))
+	e.canvas.SetMatrix(pixel.IM.ScaledXY(pixel.ZV, pixel.V(float64(cw)/rsx, float64(ch)/rsy)))
+	e.Env.Render(e.canvas)
+
+	// Average each block of supersampled pixels down into one observed pixel.
+	ss := e.Settings.Supersample
+	tensor := e.Tensor(make([]float64, e.ObservationLength()))
+	for y := 0; y < tensor.Height; y++ {
+		for x := 0; x < tensor.Width; x++ {
+			sum := pixel.Alpha(0)
+			for sy := 0; sy < ss; sy++ {
+				for sx := 0; sx < ss; sx++ {
+					sum = sum.Add(e.canvas.At(x*ss+sx, y*ss+sy))
+				}
+			}
+			avg := sum.Scaled(1 / float64(ss*ss))
+			if e.Settings.Grayscale {
+				tensor.Data[tensor.Index(x, y, 0)] = 0.299*avg.R + 0.587*avg.G + 0.114*avg.B
+			} else {
+				tensor.Data[tensor.Index(x, y, 0)] = avg.R
+				tensor.Data[tensor.Index(x, y, 1)] = avg.G
+				tensor.Data[tensor.Index(x, y, 2)] = avg.B
+			}
+		}
+	}
+	for i, v := range tensor.Data {
+		tensor.Data[i] = v*2 - 1
+	}
+	clampInPlace(tensor.Data)
+	return tensor.Data
+}